@@ -206,6 +206,28 @@ func (vc VClock) Subtract(vc1 VClock) (subVC VClock) {
 	return subVC
 }
 
+// Merge sets every entry of the clock to the maximum of its value and the value in other
+func (vc VClock) Merge(other VClock) {
+	values := other.GetMap()
+	vc.Lock()
+	for id, ticks := range values {
+		if ticks > vc.m[id] {
+			vc.m[id] = ticks
+		}
+	}
+	vc.Unlock()
+}
+
+// Descends tells if the clock has seen all the ticks of other
+func (vc VClock) Descends(other VClock) bool {
+	for id, ticks := range other.GetMap() {
+		if vc.FindTicks(id) < ticks {
+			return false
+		}
+	}
+	return true
+}
+
 // Sums all of the ticks of a vector clock
 func (vc VClock) Sum() uint64 {
 	vc.Lock()
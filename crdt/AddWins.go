@@ -14,6 +14,7 @@ type AddWins struct {
 	N_Ops         uint64
 	S_Ops         uint64
 	StabilizeLock *sync.RWMutex
+	views         *views //versions and stable state of the reads
 }
 
 // effect
@@ -21,6 +22,7 @@ func (c *AddWins) Effect(op communication.Operation) {
 	c.StabilizeLock.Lock()
 	defer c.StabilizeLock.Unlock()
 
+	c.views.effect(op)
 	switch op.Type {
 	case "Add":
 		c.state[op.Value] = op.Version
@@ -38,6 +40,8 @@ func (c *AddWins) Effect(op communication.Operation) {
 func (c *AddWins) Stabilize(op communication.Operation) {
 	c.StabilizeLock.Lock()
 	defer c.StabilizeLock.Unlock()
+
+	c.views.stabilize(op)
 	for i, v := range c.state {
 		if i == op.Value && v.Equal(op.Version) {
 			//removes the timestamp
@@ -47,12 +51,20 @@ func (c *AddWins) Stabilize(op communication.Operation) {
 	c.S_Ops++
 }
 
-func (c *AddWins) Query() (any, any) {
+func (c *AddWins) Read(level replica.Level) (any, communication.VClock) {
+	c.StabilizeLock.Lock()
+	defer c.StabilizeLock.Unlock()
+
+	return c.views.read(level, func() any { return c.elements() })
+}
+
+// set of the elements in the state
+func (c *AddWins) elements() mapset.Set[any] {
 	set := mapset.NewSet[any]()
 	for i, _ := range c.state {
 		set.Add(i)
 	}
-	return set, nil
+	return set
 }
 
 func (c *AddWins) NumOps() uint64 {
@@ -63,11 +75,20 @@ func (c *AddWins) NumSOps() uint64 {
 	return c.S_Ops
 }
 
-// initialize counter replica
-func NewAddWinsBaseReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
+// initialize counter replica, options turns on the reads that need more than the state
+func NewAddWinsBaseReplica(id string, channels map[string]chan any, delay int, options ...ReadOption) *replica.Replica {
+
+	c := newAddWins(id)
+	c.views = newMixedViews(readOptions(options), func() replica.CrdtI { return newAddWins(id) }, func() func() any {
+		set := c.elements()
+		return func() any { return set.Clone() }
+	})
+	return replica.NewReplica(id, c, channels, delay)
+}
 
-	c := AddWins{id, map[any]communication.VClock{}, 0, 0, new(sync.RWMutex)}
-	return replica.NewReplica(id, &c, channels, delay)
+// initialize an add wins set without a stable state for the reads
+func newAddWins(id string) *AddWins {
+	return &AddWins{id, map[any]communication.VClock{}, 0, 0, new(sync.RWMutex), newViews(nil)}
 }
 
 func (c *AddWins) PrintOpsEffect() {
@@ -0,0 +1,214 @@
+package crdt
+
+import (
+	"library/packages/communication"
+	"library/packages/replica"
+	"log"
+	"sync"
+)
+
+// MapOpValue is the value of an operation on an ORMap
+// an update applies the operation of type Type and value Value to the CRDT of Key, a remove resets Key
+// and a replace resets Key and then applies the operation to its new CRDT
+type MapOpValue struct {
+	Key   string
+	Type  string
+	Value any
+}
+
+// type and value of an update of the CRDT of key with an operation of type opType, to be prepared on a replica
+func MapUpdate(key string, opType string, value any) (string, any) {
+	return "Update", MapOpValue{Key: key, Type: opType, Value: value}
+}
+
+// type and value of a remove of key followed by an update of its new CRDT, to be prepared on a replica
+// concurrent replaces of a key are all kept, like concurrent updates
+func MapReplace(key string, opType string, value any) (string, any) {
+	return "Replace", MapOpValue{Key: key, Type: opType, Value: value}
+}
+
+// type and value of a remove of key, to be prepared on a replica
+func MapRemove(key string) (string, any) {
+	return "Remove", MapOpValue{Key: key}
+}
+
+// CRDT of a key and the unstable operations it was built from
+type mapEntry struct {
+	crdt replica.CrdtI
+	ops  []communication.Operation // delivered operations of the key that are not removed nor stable, in delivery order
+}
+
+// ORMap is an observed-remove map whose values are CRDTs created on the first update of their key
+// a remove drops the operations of the key it observed and the CRDT is rebuilt from the concurrent ones, so keys are add-wins.
+// every remove delivered after an operation became stable observes it, so stable operations are never replayed and their metadata is dropped
+type ORMap struct {
+	newValue func(key string) replica.CrdtI // creates the CRDT of a key, called again to rebuild it after a remove
+	entries  map[string]*mapEntry
+	N_Ops    uint64
+	S_Ops    uint64
+	lock     *sync.RWMutex
+	views    *views //versions and stable state of the reads
+}
+
+// effect
+func (m *ORMap) Effect(op communication.Operation) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.views.effect(op)
+	m.N_Ops++
+
+	value, ok := op.Value.(MapOpValue)
+	if !ok {
+		log.Println("[ ORMAP ] OPERATION WITHOUT KEY", op)
+		return
+	}
+	switch op.Type {
+	case "Update":
+		m.update(value, op)
+	case "Remove":
+		m.remove(value.Key, op.Version)
+	case "Replace":
+		m.remove(value.Key, op.Version)
+		m.update(value, op)
+	}
+}
+
+// applies the nested operation of value to the CRDT of its key, creating it on the first update
+func (m *ORMap) update(value MapOpValue, op communication.Operation) {
+	entry := m.entries[value.Key]
+	if entry == nil {
+		entry = &mapEntry{crdt: m.newValue(value.Key)}
+		m.entries[value.Key] = entry
+	}
+	nested := communication.Operation{Type: value.Type, Value: value.Value, Version: op.Version, OriginID: op.OriginID}
+	entry.ops = append(entry.ops, nested)
+	entry.crdt.Effect(nested)
+}
+
+// drops the operations of key observed by version and rebuilds its CRDT from the concurrent ones
+func (m *ORMap) remove(key string, version communication.VClock) {
+	entry := m.entries[key]
+	if entry == nil {
+		return
+	}
+	// stable operations were all observed by the remove, only the concurrent unstable ones are kept
+	kept := []communication.Operation{}
+	for _, nested := range entry.ops {
+		if !version.Descends(nested.Version) {
+			kept = append(kept, nested)
+		}
+	}
+	if len(kept) == 0 {
+		delete(m.entries, key)
+		return
+	}
+	entry.crdt, entry.ops = m.newValue(key), kept
+	for _, nested := range kept {
+		entry.crdt.Effect(nested)
+	}
+}
+
+func (m *ORMap) Stabilize(op communication.Operation) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.views.stabilize(op)
+	m.S_Ops++
+
+	value, ok := op.Value.(MapOpValue)
+	if !ok || op.Type == "Remove" {
+		return
+	}
+	// the operation is not found if a remove dropped it
+	entry := m.entries[value.Key]
+	if entry == nil {
+		return
+	}
+	for i, nested := range entry.ops {
+		if nested.OriginID == op.OriginID && nested.Version.Equal(op.Version) {
+			entry.ops = append(entry.ops[:i], entry.ops[i+1:]...)
+			entry.crdt.Stabilize(nested)
+			return
+		}
+	}
+}
+
+// reads of the CRDTs of the keys in the map
+func (m *ORMap) Read(level replica.Level) (any, communication.VClock) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.views.read(level, func() any { return m.reads() })
+}
+
+// optimistic reads of the CRDTs of the keys
+func (m *ORMap) reads() map[string]any {
+	reads := make(map[string]any, len(m.entries))
+	for key, entry := range m.entries {
+		reads[key], _ = entry.crdt.Read(replica.Optimistic)
+	}
+	return reads
+}
+
+// keys in the map
+func (m *ORMap) Keys() []string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	keys := make([]string, 0, len(m.entries))
+	for key := range m.entries {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// number of operations kept to rebuild the CRDTs of the keys after a remove, the delivered ones that are not removed nor stable
+func (m *ORMap) NumUnstable() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	n := 0
+	for _, entry := range m.entries {
+		n += len(entry.ops)
+	}
+	return n
+}
+
+func (m *ORMap) NumOps() uint64 {
+	return m.N_Ops
+}
+
+func (m *ORMap) NumSOps() uint64 {
+	return m.S_Ops
+}
+
+// returns an empty map, newValue creates the CRDT of a key on its first update
+// options turns on the reads that need more than the state
+func NewORMap(newValue func(key string) replica.CrdtI, options ...ReadOption) *ORMap {
+	m := newORMap(newValue)
+	m.views = newMixedViews(readOptions(options), func() replica.CrdtI { return newORMap(newValue) }, func() func() any {
+		reads := m.reads()
+		return func() any {
+			settled := make(map[string]any, len(reads))
+			for key, read := range reads {
+				settled[key] = read
+			}
+			return settled
+		}
+	})
+	return m
+}
+
+// initialize an empty map without a stable state for the reads
+func newORMap(newValue func(key string) replica.CrdtI) *ORMap {
+	return &ORMap{newValue: newValue, entries: map[string]*mapEntry{}, lock: new(sync.RWMutex), views: newViews(nil)}
+}
+
+// initialize map replica
+func NewORMapReplica(id string, newValue func(key string) replica.CrdtI, channels map[string]chan any, delay int, options ...ReadOption) *replica.Replica {
+
+	m := NewORMap(newValue, options...)
+
+	return replica.NewReplica(id, m, channels, delay)
+}
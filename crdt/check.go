@@ -0,0 +1,18 @@
+package crdt
+
+import "library/packages/communication"
+
+// Data interfaces that bound the operations of clients (e.g. by rights held by the replica)
+// can implement CheckDataI so engines reject them at Prepare, see replica.CheckI
+type CheckDataI interface {
+	// Check returns an error if `op`, prepared on `state` by its origin, must not be applied
+	Check(state any, op communication.Operation) error
+}
+
+// returns the error of the check of op by data on state, nil if data does not check operations
+func check(data any, state any, op communication.Operation) error {
+	if c, ok := data.(CheckDataI); ok {
+		return c.Check(state, op)
+	}
+	return nil
+}
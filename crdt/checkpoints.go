@@ -0,0 +1,99 @@
+package crdt
+
+import (
+	"library/packages/communication"
+)
+
+// default number of sorted operations between two checkpoints
+const DefaultCheckpointInterval = 32
+
+// data interfaces that can apply operations to a state
+type applier interface {
+	Apply(state any, operations []communication.Operation) any
+}
+
+// Checkpoints keeps intermediate states along the sorted unstable operations of an engine,
+// so when an operation is inserted in the middle of the order only the operations after the nearest checkpoint are replayed
+type Checkpoints struct {
+	interval int   // number of operations between checkpoints, 0 replays always from the first state
+	states   []any // states[i] is the state after applying the first i*interval sorted operations
+	diverged bool  // the engine state has operations that are not in the sorted operations
+}
+
+// returns checkpoints every interval operations starting at state
+func NewCheckpoints(interval int, state any) *Checkpoints {
+	return &Checkpoints{interval: interval, states: []any{state}}
+}
+
+// number of operations between checkpoints
+func (c *Checkpoints) Interval() int {
+	return c.interval
+}
+
+// number of checkpoints kept, including the first state
+func (c *Checkpoints) Len() int {
+	return len(c.states)
+}
+
+// drops all checkpoints, state is the new state before the first sorted operation
+func (c *Checkpoints) Reset(state any) {
+	c.states = []any{state}
+}
+
+// drops the checkpoints after the first n sorted operations
+func (c *Checkpoints) Truncate(n int) {
+	if c.interval > 0 && n/c.interval+1 < len(c.states) {
+		c.states = c.states[:n/c.interval+1]
+	}
+}
+
+// the engine state applied operations that are not in the sorted operations,
+// states are not recorded until the next replay
+func (c *Checkpoints) Diverge() {
+	c.diverged = true
+}
+
+// records state as the state after applying the first n sorted operations
+// only kept if n is a multiple of the interval and all checkpoints before it exist
+func (c *Checkpoints) Add(n int, state any) {
+	if !c.diverged && c.interval > 0 && n%c.interval == 0 && n/c.interval == len(c.states) {
+		c.states = append(c.states, state)
+	}
+}
+
+// returns the state after applying all operations, knowing that only operations from index from changed
+// checkpoints after from are dropped and recorded again while replaying
+func (c *Checkpoints) Replay(data applier, operations []communication.Operation, from int) any {
+	c.diverged = false
+	if c.interval == 0 {
+		return data.Apply(c.states[0], operations)
+	}
+
+	k := from / c.interval
+	if k > len(c.states)-1 {
+		k = len(c.states) - 1
+	}
+	c.states = c.states[:k+1]
+
+	st := c.states[k]
+	for i := k * c.interval; i < len(operations); i += c.interval {
+		end := i + c.interval
+		if end > len(operations) {
+			end = len(operations)
+		}
+		st = data.Apply(st, operations[i:end])
+		c.Add(end, st)
+	}
+	return st
+}
+
+// index of the first operation that differs between two orders of operations
+func firstDifference(ops1 []communication.Operation, ops2 []communication.Operation) int {
+	i := 0
+	for ; i < len(ops1) && i < len(ops2); i++ {
+		if !ops1[i].Equals(ops2[i]) {
+			break
+		}
+	}
+	return i
+}
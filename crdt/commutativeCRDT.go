@@ -2,6 +2,8 @@ package crdt
 
 import (
 	"library/packages/communication"
+	"library/packages/replica"
+	"sync"
 )
 
 type CommutativeDataI interface {
@@ -15,20 +17,67 @@ type CommutativeCRDT struct {
 	Stable_st any
 	N_Ops     uint64
 	S_Ops     uint64
+	Options   ReadOption // reads that need more than the state, see ReadOption
+
+	views *views //versions and stable state of the reads, created on first use from Stable_st
+	once  sync.Once
 }
 
 // effect
 func (c *CommutativeCRDT) Effect(op communication.Operation) {
+	c.reads().effect(op)
 	c.Stable_st = c.Data.Apply(c.Stable_st, []communication.Operation{op})
 	c.N_Ops++
 }
 
 func (c *CommutativeCRDT) Stabilize(op communication.Operation) {
-	//ignore
+	//the state does not change, only the stable reads
+	c.reads().stabilize(op)
+}
+
+// rejects the operations of clients refused by the data interface
+func (c *CommutativeCRDT) Check(op communication.Operation) error {
+	return check(c.Data, c.Stable_st, op)
+}
+
+func (c *CommutativeCRDT) Read(level replica.Level) (any, communication.VClock) {
+	return c.reads().read(level, func() any {
+		return query(c.Data, c.Stable_st)
+	})
+}
+
+// state with all delivered operations before Query
+func (c *CommutativeCRDT) OptimisticState() any {
+	return c.Stable_st
+}
+
+// views of the reads, created with the reads turned on by Options
+func (c *CommutativeCRDT) reads() *views {
+	c.once.Do(func() {
+		if c.views == nil {
+			fork := func(state any) replica.CrdtI {
+				return &CommutativeCRDT{Data: c.Data, Stable_st: state, views: newViews(nil)}
+			}
+			c.views = newMixedViews(c.Options, func() replica.CrdtI { return fork(c.Stable_st) }, func() func() any {
+				st := c.Stable_st
+				return func() any { return query(c.Data, st) }
+			})
+			if c.Options&HistoryReads != 0 {
+				c.views.history = newHistory(c, c.Stable_st, fork, func(engine replica.CrdtI) any { return engine.(*CommutativeCRDT).Stable_st })
+			}
+		}
+	})
+	return c.views
+}
+
+// state with the operations dominated by version, returns a CompactedError if they were compacted away
+func (c *CommutativeCRDT) QueryAt(version communication.VClock) (any, error) {
+	return c.reads().queryAt(version)
 }
 
-func (c *CommutativeCRDT) Query() (any, any) {
-	return c.Stable_st, nil
+// change between the versions from and to, returns a CompactedError if the operations after from were compacted away
+func (c *CommutativeCRDT) Diff(from communication.VClock, to communication.VClock) (Diff, error) {
+	return c.reads().diff(from, to, c.Data)
 }
 
 func (c *CommutativeCRDT) NumOps() uint64 {
@@ -2,6 +2,8 @@ package crdt
 
 import (
 	"library/packages/communication"
+	"library/packages/replica"
+	"sync"
 )
 
 type CommutativeStableDataI interface {
@@ -15,26 +17,90 @@ type CommutativeStableDataI interface {
 	Query(state any) any
 }
 
+// Data interfaces whose stable states keep metadata until other operations are stable can implement StableVersionDataI,
+// the engine then stabilizes the operations through it
+type StableVersionDataI interface {
+	// StabilizeAt is Stabilize where `stable` is the merged version of the stable operations, `op` included.
+	// Every operation concurrent with a stable operation was already applied.
+	StabilizeAt(state any, op communication.Operation, stable communication.VClock) any
+}
+
 type CommutativeStableCRDT struct {
 	Data      CommutativeStableDataI
 	Stable_st any
 	N_Ops     uint64
 	S_Ops     uint64
+
+	Options       ReadOption           // reads that need more than the state, see ReadOption
+	stableVersion communication.VClock // merged versions of the stable operations, created on first use
+	views         *views               //versions and stable state of the reads, created on first use from Stable_st
+	once          sync.Once
 }
 
 // effect
 func (c *CommutativeStableCRDT) Effect(op communication.Operation) {
+	c.reads().effect(op)
 	c.Stable_st = c.Data.Apply(c.Stable_st, []communication.Operation{op})
 	c.N_Ops++
 }
 
 func (c *CommutativeStableCRDT) Stabilize(op communication.Operation) {
-	c.Stable_st = c.Data.Stabilize(c.Stable_st, op)
+	if d, ok := c.Data.(StableVersionDataI); ok {
+		if c.stableVersion.RWMutex == nil {
+			c.stableVersion = communication.NewVClock()
+		}
+		c.stableVersion.Merge(op.Version)
+		c.Stable_st = d.StabilizeAt(c.Stable_st, op, c.stableVersion)
+	} else {
+		c.Stable_st = c.Data.Stabilize(c.Stable_st, op)
+	}
 	c.S_Ops++
+	c.reads().stabilize(op)
+}
+
+// rejects the operations of clients refused by the data interface
+func (c *CommutativeStableCRDT) Check(op communication.Operation) error {
+	return check(c.Data, c.Stable_st, op)
+}
+
+func (c *CommutativeStableCRDT) Read(level replica.Level) (any, communication.VClock) {
+	return c.reads().read(level, func() any {
+		return c.Data.Query(c.Stable_st)
+	})
+}
+
+// state with all delivered operations before Query
+func (c *CommutativeStableCRDT) OptimisticState() any {
+	return c.Stable_st
+}
+
+// views of the reads, created with the reads turned on by Options
+func (c *CommutativeStableCRDT) reads() *views {
+	c.once.Do(func() {
+		if c.views == nil {
+			fork := func(state any) replica.CrdtI {
+				return &CommutativeStableCRDT{Data: c.Data, Stable_st: state, views: newViews(nil)}
+			}
+			c.views = newMixedViews(c.Options, func() replica.CrdtI { return fork(c.Stable_st) }, func() func() any {
+				st := c.Stable_st
+				return func() any { return c.Data.Query(st) }
+			})
+			if c.Options&HistoryReads != 0 {
+				c.views.history = newHistory(c, c.Stable_st, fork, func(engine replica.CrdtI) any { return engine.(*CommutativeStableCRDT).Stable_st })
+			}
+		}
+	})
+	return c.views
+}
+
+// state with the operations dominated by version, returns a CompactedError if they were compacted away
+func (c *CommutativeStableCRDT) QueryAt(version communication.VClock) (any, error) {
+	return c.reads().queryAt(version)
 }
 
-func (c *CommutativeStableCRDT) Query() (any, any) {
-	return c.Data.Query(c.Stable_st), nil
+// change between the versions from and to, returns a CompactedError if the operations after from were compacted away
+func (c *CommutativeStableCRDT) Diff(from communication.VClock, to communication.VClock) (Diff, error) {
+	return c.reads().diff(from, to, c.Data)
 }
 
 func (c *CommutativeStableCRDT) NumOps() uint64 {
@@ -0,0 +1,49 @@
+package crdt
+
+import (
+	"library/packages/communication"
+)
+
+// Data interfaces can implement DeltaDataI to describe the change between two states in their own terms,
+// e.g. the elements added and removed from a set
+type DeltaDataI interface {
+	// Delta returns the change from state `from` to state `to`, both as returned to clients
+	Delta(from any, to any) any
+}
+
+// Diff is the change of an engine between two versions
+type Diff struct {
+	From       communication.VClock
+	To         communication.VClock
+	Operations []communication.Operation // operations dominated by To and not by From, in delivery order
+	Delta      any                       // change of the state given by the data interface, nil if it does not implement DeltaDataI
+}
+
+// change between the states at from and to, data gives the delta of the states
+func (h *history) diff(from communication.VClock, to communication.VClock, data any) (Diff, error) {
+	if !from.Descends(h.version) {
+		return Diff{}, &CompactedError{Version: from.Copy(), Snapshot: h.version.Copy()}
+	}
+
+	d := Diff{From: from.Copy(), To: to.Copy(), Operations: []communication.Operation{}}
+	for _, op := range h.log {
+		if to.Descends(op.Version) && !from.Descends(op.Version) {
+			d.Operations = append(d.Operations, op)
+		}
+	}
+
+	delta, ok := data.(DeltaDataI)
+	if !ok {
+		return d, nil
+	}
+	fromSt, err := h.queryAt(from)
+	if err != nil {
+		return Diff{}, err
+	}
+	toSt, err := h.queryAt(to)
+	if err != nil {
+		return Diff{}, err
+	}
+	d.Delta = delta.Delta(fromSt, toSt)
+	return d, nil
+}
@@ -2,11 +2,9 @@ package crdt
 
 import (
 	"library/packages/communication"
-	"library/packages/utils"
+	"library/packages/replica"
 	"strconv"
 	"sync"
-
-	"github.com/dominikbraun/graph"
 )
 
 // data interface
@@ -22,15 +20,35 @@ type EcroDataI interface {
 	Commutes(op1 communication.Operation, op2 communication.Operation) bool
 }
 
+// Data interfaces that can undo operations can implement InverseDataI,
+// so operations after an out of order operation are undone and redone instead of replayed from a checkpoint
+type InverseDataI interface {
+	// Inverse returns the operation that undoes `op` when applied to the state resulting from applying `op` to `state`.
+	Inverse(state any, op communication.Operation) communication.Operation
+}
+
+// Data interfaces whose states keep metadata only needed by unstable operations can implement CollectDataI,
+// so the stable state drops it once the operations that need it are stable
+type CollectDataI interface {
+	// Collect returns `state` without the metadata needed by the `stable` operations just applied to it that no `unstable` operation needs,
+	// and whether it dropped any. Every `unstable` operation has seen all the operations of the stable state.
+	Collect(state any, stable []communication.Operation, unstable []communication.Operation) (any, bool)
+}
+
 type EcroCRDT struct {
 	Id                  string
-	Data                EcroDataI //data interface
-	Stable_st           any       // stable state
-	Unstable_operations graph.Graph[string, communication.Operation]
+	Data                EcroDataI                 //data interface
+	Stable_st           any                       // stable state
+	stableVersion       communication.VClock      // merged versions of the operations of the stable state
+	uncollected         []communication.Operation // operations of the stable state whose metadata was not collected yet
+	Unstable_operations *OpGraph
 	Stable_operation    communication.Operation
 	Unstable_st         any //most recent state
 	Sorted_ops          []communication.Operation
-	Rem_Edges           []string
+	Checkpoints         *Checkpoints              //intermediate states along Sorted_ops
+	Inverses            []communication.Operation //Inverses[i] undoes Sorted_ops[i], only kept if Data implements InverseDataI
+	Debug               *Debug                    //checks the invariants of Data after every Effect and Stabilize when set
+	views               *views                    //versions and stable state of the reads
 
 	N_Ops uint64
 	S_Ops uint64
@@ -39,15 +57,31 @@ type EcroCRDT struct {
 }
 
 // initialize ecrocrdt
-func NewEcroCRDT(id string, state any, data EcroDataI) *EcroCRDT {
+// the stable reads come from the stable state, it has the stable operations that no unstable operation is concurrent with,
+// so StableReads changes nothing and only HistoryReads is used from options
+func NewEcroCRDT(id string, state any, data EcroDataI, options ...ReadOption) *EcroCRDT {
+	c := newEcroCRDT(id, state, data)
+	if readOptions(options)&HistoryReads != 0 {
+		c.views.history = newHistory(c, state, func(state any) replica.CrdtI {
+			return newEcroCRDT(id, state, data)
+		}, func(engine replica.CrdtI) any { return engine.(*EcroCRDT).Unstable_st })
+	}
+	return c
+}
+
+// initialize ecrocrdt without a history for the reads
+func newEcroCRDT(id string, state any, data EcroDataI) *EcroCRDT {
 	c := EcroCRDT{Id: id,
 		Data:                data,
 		Stable_st:           state,
-		Unstable_operations: graph.New(opHash, graph.Directed(), graph.Acyclic()),
+		stableVersion:       communication.NewVClock(),
+		Unstable_operations: NewOpGraph(),
 		Unstable_st:         state,
+		Checkpoints:         NewCheckpoints(DefaultCheckpointInterval, state),
 		N_Ops:               0,
 		S_Ops:               0,
 		StabilizeLock:       new(sync.RWMutex),
+		views:               newViews(nil),
 	}
 
 	return &c
@@ -57,16 +91,59 @@ func (r *EcroCRDT) Effect(op communication.Operation) {
 	r.StabilizeLock.Lock()
 	defer r.StabilizeLock.Unlock()
 
-	r.Unstable_operations.AddVertex(op, graph.VertexAttribute("label", opHash(op)+" "+op.Type+" "+op.Version.ReturnVCString()))
+	r.views.effect(op)
+	r.Unstable_operations.AddVertex(op)
 	if r.addEdges(op) {
+		if inv, ok := r.Data.(InverseDataI); ok && len(r.Inverses) == len(r.Sorted_ops) {
+			r.Inverses = append(r.Inverses, inv.Inverse(r.Unstable_st, op))
+		}
 		r.Sorted_ops = append(r.Sorted_ops, op)
 		r.Unstable_st = r.Data.Apply(r.Unstable_st, []communication.Operation{op})
+		r.Checkpoints.Add(len(r.Sorted_ops), r.Unstable_st)
 	} else {
-		r.Sorted_ops = r.incTopologicalSort(r.Sorted_ops, op)
-		r.Unstable_st = r.Data.Apply(r.Stable_st, r.Sorted_ops)
+		r.reorder(r.sortWith(op))
 	}
 
 	r.N_Ops++
+	r.Debug.check(r.Data, r.Id, op, false, r.Stable_st, r.Unstable_st)
+}
+
+// updates the state to a new order of the unstable operations
+func (r *EcroCRDT) reorder(sorted []communication.Operation) {
+	from := firstDifference(r.Sorted_ops, sorted)
+
+	inv, ok := r.Data.(InverseDataI)
+	if !ok || len(r.Inverses) != len(r.Sorted_ops) {
+		//replay from the nearest checkpoint before the first operation whose position changed
+		r.Sorted_ops = sorted
+		r.Unstable_st = r.Checkpoints.Replay(r.Data, r.Sorted_ops, from)
+		return
+	}
+
+	//undo the operations after the first operation whose position changed and redo them in the new order
+	st := r.Unstable_st
+	for i := len(r.Inverses) - 1; i >= from; i-- {
+		st = r.Data.Apply(st, []communication.Operation{r.Inverses[i]})
+	}
+	r.Inverses = r.Inverses[:from]
+	r.Checkpoints.Truncate(from)
+
+	for i := from; i < len(sorted); i++ {
+		r.Inverses = append(r.Inverses, inv.Inverse(st, sorted[i]))
+		st = r.Data.Apply(st, []communication.Operation{sorted[i]})
+		r.Checkpoints.Add(i+1, st)
+	}
+	r.Sorted_ops = sorted
+	r.Unstable_st = st
+}
+
+// sets the number of sorted operations between checkpoints, 0 replays always from the stable state
+func (r *EcroCRDT) SetCheckpointInterval(interval int) {
+	r.StabilizeLock.Lock()
+	defer r.StabilizeLock.Unlock()
+
+	r.Checkpoints = NewCheckpoints(interval, r.Stable_st)
+	r.Unstable_st = r.Checkpoints.Replay(r.Data, r.Sorted_ops, 0)
 }
 
 func (r *EcroCRDT) Stabilize(op communication.Operation) {
@@ -74,37 +151,108 @@ func (r *EcroCRDT) Stabilize(op communication.Operation) {
 	defer r.StabilizeLock.Unlock()
 
 	r.S_Ops++
+	r.views.stabilize(op)
 
-	//remove vertex of the operation and all its edges
 	r.Stable_operation = op
-	t := r.Sorted_ops
-	io := indexOf(t, op)
+	r.Unstable_operations.SetStable(opHash(op))
+	defer func() {
+		r.Debug.check(r.Data, r.Id, op, true, r.Stable_st, r.Unstable_st)
+	}()
 
-	if !r.prefixStable(t, io) {
+	n := stablePrefix(r.Unstable_operations, r.Sorted_ops)
+	if n == 0 {
 		return
 	}
 
-	//remove all edges that have the operation as target or source
-	adjacencyMap, _ := r.Unstable_operations.AdjacencyMap()
-	for _, edges := range adjacencyMap {
-		for _, edge := range edges {
-			if edge.Source == opHash(op) || edge.Target == opHash(op) {
-				r.Unstable_operations.RemoveEdge(edge.Source, edge.Target)
-			}
+	//remove the vertices of the stable prefix and all their edges
+	for _, o := range r.Sorted_ops[:n] {
+		r.Unstable_operations.RemoveVertex(opHash(o))
+	}
+
+	//the stable prefix moves to the stable state, the unstable state is the stable state with the remaining operations
+	stable := r.Sorted_ops[:n]
+	r.Stable_st = r.Data.Apply(r.Stable_st, stable)
+	for _, o := range stable {
+		r.stableVersion.Merge(o.Version)
+	}
+	r.Sorted_ops = r.Sorted_ops[n:]
+	if len(r.Inverses) >= n {
+		r.Inverses = r.Inverses[n:]
+	}
+	r.Checkpoints.Reset(r.Stable_st)
+	r.collect(stable)
+}
+
+// drops from the stable state the metadata of the stable operations that no unstable operation needs,
+// the unstable operations are applied again on the collected stable state
+// stable operations left after the stable prefix can be concurrent with the ones in the stable state, and the metadata can place them,
+// e.g. a tombstone between the inserts after the same vertex, so the collect waits until all of them have seen the stable state
+func (r *EcroCRDT) collect(stable []communication.Operation) {
+	c, ok := r.Data.(CollectDataI)
+	if !ok {
+		return
+	}
+	r.uncollected = append(r.uncollected, stable...)
+	for _, o := range r.Sorted_ops {
+		if !o.Version.Descends(r.stableVersion) {
+			return
+		}
+	}
+	st, collected := c.Collect(r.Stable_st, r.uncollected, r.Sorted_ops)
+	r.uncollected = nil
+	if !collected {
+		return
+	}
+
+	r.Stable_st = st
+	r.Checkpoints.Reset(st)
+	inv, ok := r.Data.(InverseDataI)
+	r.Inverses = nil
+	for i, o := range r.Sorted_ops {
+		if ok {
+			r.Inverses = append(r.Inverses, inv.Inverse(st, o))
 		}
+		st = r.Data.Apply(st, []communication.Operation{o})
+		r.Checkpoints.Add(i+1, st)
 	}
+	r.Unstable_st = st
+}
+
+func (r *EcroCRDT) Read(level replica.Level) (any, communication.VClock) {
+	r.StabilizeLock.Lock()
+	defer r.StabilizeLock.Unlock()
+
+	//the version of a stable read is the one of the stable state, it can be behind the stable operations concurrent with unstable ones
+	if level == replica.Stable {
+		return query(r.Data, r.Stable_st), r.stableVersion.Copy()
+	}
+	return r.views.read(level, func() any {
+		return query(r.Data, r.Unstable_st)
+	})
+}
 
-	r.Unstable_operations.RemoveVertex(opHash(op))
+// state with all delivered operations before Query
+func (r *EcroCRDT) OptimisticState() any {
+	r.StabilizeLock.Lock()
+	defer r.StabilizeLock.Unlock()
 
-	r.Stable_st = r.Data.Apply(r.Stable_st, t[:io+1])
-	//r.Unstable_st = r.Data.Apply(r.Stable_st, t[io+1:])
+	return r.Unstable_st
 }
 
-func (r *EcroCRDT) Query() (any, any) {
+// state with the operations dominated by version, returns a CompactedError if they were compacted away
+func (r *EcroCRDT) QueryAt(version communication.VClock) (any, error) {
 	r.StabilizeLock.Lock()
 	defer r.StabilizeLock.Unlock()
 
-	return r.Unstable_st, nil
+	return r.views.queryAt(version)
+}
+
+// change between the versions from and to, returns a CompactedError if the operations after from were compacted away
+func (r *EcroCRDT) Diff(from communication.VClock, to communication.VClock) (Diff, error) {
+	r.StabilizeLock.Lock()
+	defer r.StabilizeLock.Unlock()
+
+	return r.views.diff(from, to, r.Data)
 }
 
 func (r *EcroCRDT) NumOps() uint64 {
@@ -118,34 +266,31 @@ func (r *EcroCRDT) NumSOps() uint64 {
 // add edges to graph and return if its descendant of all operations or not
 func (r *EcroCRDT) addEdges(op communication.Operation) bool {
 	isSafe := true
-	adjacencyMap, _ := r.Unstable_operations.AdjacencyMap()
-	for vertexHash := range adjacencyMap {
-		vertex, _ := r.Unstable_operations.Vertex(vertexHash)
+	maxRemoved := r.Unstable_operations.maxRemovedId()
+	r.Unstable_operations.Each(func(vertexHash string, vertex communication.Operation) {
 		if op.Equals(vertex) {
-			continue
+			return
 		}
 		cmp := op.Version.Compare(vertex.Version)
 		opHash := opHash(op)
 
 		if cmp == communication.Ancestor && !r.Data.Commutes(op, vertex) {
 			isSafe = false
-			r.Unstable_operations.AddEdge(vertexHash, opHash, graph.EdgeAttributes(map[string]string{"label": "hb", "id": vertexHash + opHash}))
+			r.Unstable_operations.AddEdge(vertexHash, opHash, "hb")
 		} else if cmp == communication.Concurrent && !r.Data.Commutes(op, vertex) {
-			if r.Data.Order(op, vertex) {
+			//operations the data type does not order are ordered by their hash, so every conflict has an edge
+			if r.Data.Order(op, vertex) || !r.Data.Order(vertex, op) && opHash < vertexHash {
 				isSafe = false
-				r.Unstable_operations.AddEdge(opHash, vertexHash, graph.EdgeAttributes(map[string]string{"label": "ao", "id": opHash + vertexHash}))
-			} else if r.Data.Order(vertex, op) {
-				if isSafe {
-					for _, edge := range r.Rem_Edges {
-						if vertexHash+opHash < edge {
-							isSafe = false
-						}
-					}
+				r.Unstable_operations.AddEdge(opHash, vertexHash, "ao")
+			} else {
+				//the new edge could be removed instead of an edge removed before, so the operations are sorted again
+				if vertexHash+opHash < maxRemoved {
+					isSafe = false
 				}
-				r.Unstable_operations.AddEdge(vertexHash, opHash, graph.EdgeAttributes(map[string]string{"label": "ao", "id": vertexHash + opHash}))
+				r.Unstable_operations.AddEdge(vertexHash, opHash, "ao")
 			}
 		}
-	}
+	})
 
 	return isSafe
 }
@@ -155,132 +300,79 @@ func opHash(op communication.Operation) string {
 	return strconv.FormatUint(op.Version.Sum(), 10) + op.OriginID
 }
 
-func (r *EcroCRDT) incTopologicalSort(topoSort []communication.Operation, u communication.Operation) []communication.Operation {
-	if len(topoSort) == 0 {
-		return []communication.Operation{u}
-	}
-
-	x := topoSort[0]
-
-	if x.Version.Compare(u.Version) == communication.Descendant && !r.Data.Commutes(x, u) {
-		return append([]communication.Operation{x}, r.incTopologicalSort(topoSort[1:], u)...)
-
-	} else if r.Data.Order(x, u) && !r.Data.Commutes(x, u) {
-		return append([]communication.Operation{x}, r.incTopologicalSort(topoSort[1:], u)...)
-	} else {
-		isLess := true
-		for _, y := range topoSort {
-			if !(r.Data.Order(u, y) && !r.Data.Commutes(y, u)) {
-				isLess = false
+// order of the unstable operations with op
+// without removed arbitration edges the graph has no cycles and every topological order gives the same state,
+// so only the operations from the first one that op must precede are sorted again,
+// otherwise all of them are sorted, so the order only depends on the graph and not on the order they were received
+func (r *EcroCRDT) sortWith(op communication.Operation) []communication.Operation {
+	if len(r.Unstable_operations.removed) == 0 {
+		from := len(r.Sorted_ops)
+		for i, o := range r.Sorted_ops {
+			if r.Unstable_operations.HasEdge(opHash(op), opHash(o)) {
+				from = i
+				break
 			}
 		}
-		if isLess {
-			return append([]communication.Operation{u}, topoSort...)
+		suffix, removed := r.Unstable_operations.TopologicalSort(append(append([]communication.Operation{}, r.Sorted_ops[from:]...), op))
+		if len(removed) == 0 {
+			return append(append([]communication.Operation{}, r.Sorted_ops[:from]...), suffix...)
 		}
 	}
-
-	return r.topologicalSort(append([]communication.Operation{u}, topoSort...))
+	return r.topologicalSort(append(append([]communication.Operation{}, r.Sorted_ops...), op))
 }
 
-// orders the operations in the graph
+// orders the operations in the graph, arbitration edges removed to break cycles are kept in the graph
 func (r *EcroCRDT) topologicalSort(vertices []communication.Operation) []communication.Operation {
-	//find minimum vertex of the graph (vertex with no incoming edges)
-	//it can have more than one minimum, choose deterministically (by finding the minimum id) and continue algorithm
-
-	//if the minimum exists put it in the topological order and search for the next recursively
-
-	//if the minimum does not exist, the graph has cycles
-	//the algorithm kills an arbitration edge deterministically (by finding the edge with the minimum id)
-	//after killing the edge one of the verices will be the minimum if there is only one cycle
-	//if there's another cycle repeat the process
-
-	var order []communication.Operation
-	removedVertices := make(map[string]bool)
-	removedEdges := make(map[string]map[string]bool)
-	//predecessorMap, _ := r.Unstable_operations.PredecessorMap()
-	edgesG, _ := r.Unstable_operations.Edges()
-
-	edges := []graph.Edge[string]{}
+	order, _ := r.Unstable_operations.TopologicalSort(vertices)
+	return order
+}
 
-	for _, edge := range edgesG {
-		target, _ := r.Unstable_operations.Vertex(edge.Target)
-		source, _ := r.Unstable_operations.Vertex(edge.Source)
-		if utils.Contains(vertices, target) && utils.Contains(vertices, source) {
-			edges = append(edges, edge)
-		}
+// number of sorted operations at the start of the order that can move to the stable state
+// they must be stable and have no concurrent unstable operation after them, so removing them does not change the order of the others,
+// every unstable operation after them must have seen them, which is checked against the minimum version of the unstable operations after each position,
+// stable operations after them can be their ancestors, commuting operations are not ordered by causality
+func stablePrefix(graph *OpGraph, sorted []communication.Operation) int {
+	stable := 0
+	for stable < len(sorted) && graph.IsStable(opHash(sorted[stable])) {
+		stable++
+	}
+	if stable == 0 {
+		return 0
 	}
 
-	for {
-		// Create map to count incoming edges
-		inDegree := make(map[string]int)
-
-		for _, vertex := range vertices {
-			inDegree[opHash(vertex)] = 0 // Initialize inDegree for all vertices to 0
-		}
-
-		for _, edge := range edges {
-			if !removedVertices[edge.Source] && !removedVertices[edge.Target] && !removedEdges[edge.Source][edge.Target] {
-				inDegree[edge.Target]++
-			}
-		}
-
-		// Find minimum vertex
-		minVertex := communication.Operation{Type: ""}
-
-		for vertex, degree := range inDegree {
-			if degree == 0 && !removedVertices[vertex] {
-				if minVertex.Type == "" || vertex < opHash(minVertex) {
-					minVertex, _ = r.Unstable_operations.Vertex(vertex)
-				}
-			}
-		}
-
-		// If no minimum vertex found, there is a cycle
-		if minVertex.Type == "" {
-			minEdge := graph.Edge[string]{Source: "", Target: "", Properties: graph.EdgeProperties{Attributes: map[string]string{"label": "ao"}}}
-			for _, edge := range edges {
-				if edge.Properties.Attributes["label"] == "ao" && (minEdge.Source == "" || edge.Properties.Attributes["id"] < minEdge.Properties.Attributes["id"]) && !removedEdges[edge.Source][edge.Target] {
-					minEdge = edge
-				}
-			}
-
-			// Remove the minimum ID edge from the graph
-			if removedEdges[minEdge.Source] == nil {
-				removedEdges[minEdge.Source] = make(map[string]bool)
-			}
-			removedEdges[minEdge.Source][minEdge.Target] = true
-			r.Rem_Edges = append(r.Rem_Edges, minEdge.Properties.Attributes["id"])
-			continue
-		}
-
-		// Add minimum vertex to topological order and "remove" it from the graph
-		order = append(order, minVertex)
-		removedVertices[opHash(minVertex)] = true
-		// If all vertices are "removed", we are done
-		if len(order) == len(vertices) {
-			break
+	//after[n] is the minimum version of the unstable operations from position n, nil if there are none
+	after := make([]communication.VClock, len(sorted)+1)
+	for n := len(sorted) - 1; n >= 1; n-- {
+		after[n] = after[n+1]
+		if !graph.IsStable(opHash(sorted[n])) {
+			after[n] = minVersion(sorted[n].Version, after[n+1])
 		}
 	}
 
-	return order
-}
-
-// gets index of operation in array
-func indexOf(operations []communication.Operation, op communication.Operation) int {
-	for i, o := range operations {
-		if op.Equals(o) {
-			return i
+	prefix := 0
+	seen := communication.NewVClock()
+	for n := 1; n <= stable; n++ {
+		seen.Merge(sorted[n-1].Version)
+		if after[n].RWMutex == nil || after[n].Descends(seen) {
+			prefix = n
 		}
 	}
-	return -1
+	return prefix
 }
 
-// check if prefix of the operations is stable (all operations of the prefix are in stable_operations)
-func (r EcroCRDT) prefixStable(operations []communication.Operation, index int) bool {
-	for _, o := range operations[:index+1] {
-		if o.Version.Compare(r.Stable_operation.Version) != communication.Descendant {
-			return false
+// entries of version that are not bigger in other, version if other is nil
+func minVersion(version communication.VClock, other communication.VClock) communication.VClock {
+	if other.RWMutex == nil {
+		return version.Copy()
+	}
+	min := communication.NewVClock()
+	for id, ticks := range version.GetMap() {
+		if o := other.FindTicks(id); o < ticks {
+			ticks = o
+		}
+		if ticks > 0 {
+			min.Set(id, ticks)
 		}
 	}
-	return true
+	return min
 }
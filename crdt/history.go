@@ -0,0 +1,94 @@
+package crdt
+
+import (
+	"fmt"
+	"library/packages/communication"
+	"library/packages/replica"
+)
+
+// CompactedError is returned by QueryAt when the operations that the requested version does not dominate
+// were already compacted into the snapshot of the engine
+type CompactedError struct {
+	Version  communication.VClock // requested version
+	Snapshot communication.VClock // version of the snapshot of the engine
+}
+
+func (e *CompactedError) Error() string {
+	return fmt.Sprintf("version %s was compacted away, the snapshot has version %s", e.Version.ReturnVCString(), e.Snapshot.ReturnVCString())
+}
+
+// history keeps a snapshot of the state of an engine and the operations delivered after it, so earlier states can be rebuilt
+// the snapshot takes the stable operations that no unstable operation is concurrent with,
+// so all operations of the log are causally after the operations of the snapshot
+type history struct {
+	snapshot any                       // state of the operations compacted away
+	version  communication.VClock      // merged versions of the operations compacted away
+	log      []communication.Operation // operations delivered after the snapshot, in delivery order
+	engine   replica.CrdtI             // engine of the history
+	fork     func(state any) replica.CrdtI
+	state    func(engine replica.CrdtI) any // state of all delivered operations of an engine, before conversion by query
+}
+
+// returns the history of an engine without operations
+// fork returns an engine of the same type starting at a state, state returns the current state of an engine of that type
+func newHistory(engine replica.CrdtI, snapshot any, fork func(state any) replica.CrdtI, state func(engine replica.CrdtI) any) *history {
+	return &history{snapshot: snapshot, version: communication.NewVClock(), engine: engine, fork: fork, state: state}
+}
+
+// records a delivered operation
+func (h *history) effect(op communication.Operation) {
+	h.log = append(h.log, op)
+}
+
+// compacts the log into the snapshot up to the stable cut, delivered and stable are the versions of the delivered and stable operations
+// a stable operation concurrent with an unstable one stays in the log, the engine may still order them either way
+func (h *history) stabilize(delivered communication.VClock, stable communication.VClock) {
+	if stable.Descends(delivered) {
+		h.snapshot = h.state(h.engine)
+		h.version = delivered.Copy()
+		h.log = nil
+		return
+	}
+
+	//an unstable operation is never before a stable one, so it is concurrent with a stable operation it has not seen
+	var unstable communication.VClock
+	for _, op := range h.log {
+		if !stable.Descends(op.Version) {
+			unstable = minVersion(op.Version, unstable)
+		}
+	}
+	var engine replica.CrdtI
+	log := []communication.Operation{}
+	for _, op := range h.log {
+		if !stable.Descends(op.Version) || unstable.RWMutex != nil && !unstable.Descends(op.Version) {
+			log = append(log, op)
+			continue
+		}
+		if engine == nil {
+			engine = h.fork(h.snapshot)
+		}
+		engine.Effect(op)
+		h.version.Merge(op.Version)
+	}
+	if engine == nil {
+		return
+	}
+	h.snapshot = h.state(engine)
+	h.log = log
+}
+
+// state of the operations dominated by version, applied by a new engine in its arbitration order
+func (h *history) queryAt(version communication.VClock) (any, error) {
+	if !version.Descends(h.version) {
+		return nil, &CompactedError{Version: version.Copy(), Snapshot: h.version.Copy()}
+	}
+
+	engine := h.fork(h.snapshot)
+	for _, op := range h.log {
+		if version.Descends(op.Version) {
+			engine.Effect(op)
+		}
+	}
+	st, _ := engine.Read(replica.Optimistic)
+	return st, nil
+}
@@ -0,0 +1,89 @@
+package crdt
+
+import (
+	"fmt"
+	"library/packages/communication"
+	"log"
+	"sync"
+)
+
+// Invariant is a predicate that every state of a data interface must satisfy
+type Invariant struct {
+	Name  string
+	Holds func(state any) bool
+}
+
+// Data interfaces can implement InvariantDataI to declare the invariants of their states,
+// engines in debug mode check them after every Effect and Stabilize
+type InvariantDataI interface {
+	// Invariants returns the predicates that the stable and unstable states must satisfy
+	Invariants() []Invariant
+}
+
+// InvariantViolation is a state of an engine that does not satisfy an invariant of its data interface
+type InvariantViolation struct {
+	Invariant string
+	Replica   string
+	Operation communication.Operation // operation whose Effect or Stabilize produced the state
+	Stabilize bool                    // the operation was being stabilized
+	Stable    bool                    // the state is the stable state of the engine
+	State     any
+}
+
+func (v InvariantViolation) String() string {
+	callback, state := "Effect", "unstable"
+	if v.Stabilize {
+		callback = "Stabilize"
+	}
+	if v.Stable {
+		state = "stable"
+	}
+	return fmt.Sprintf("replica %s: invariant %q does not hold in the %s state after %s of %s %v with version %s: %v",
+		v.Replica, v.Invariant, state, callback, v.Operation.Type, v.Operation.Value, v.Operation.Version.ReturnVCString(), v.State)
+}
+
+// Debug mode of an engine, engines with a Debug check the invariants of their data interface
+// violations are logged and kept to be inspected by tests
+type Debug struct {
+	lock       sync.Mutex
+	violations []InvariantViolation
+}
+
+// returns a debug mode without violations
+func NewDebug() *Debug {
+	return &Debug{}
+}
+
+// violations found so far
+func (d *Debug) Violations() []InvariantViolation {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return append([]InvariantViolation{}, d.violations...)
+}
+
+// checks the invariants of data on the stable and unstable states of an engine after the Effect or Stabilize of op
+// does nothing if the debug mode is off or data declares no invariants
+func (d *Debug) check(data any, id string, op communication.Operation, stabilize bool, stable any, unstable any) {
+	inv, ok := data.(InvariantDataI)
+	if d == nil || !ok {
+		return
+	}
+
+	for _, invariant := range inv.Invariants() {
+		for _, st := range []struct {
+			state  any
+			stable bool
+		}{{stable, true}, {unstable, false}} {
+			if invariant.Holds(st.state) {
+				continue
+			}
+			v := InvariantViolation{Invariant: invariant.Name, Replica: id, Operation: op, Stabilize: stabilize, Stable: st.stable, State: st.state}
+			log.Println(v)
+
+			d.lock.Lock()
+			d.violations = append(d.violations, v)
+			d.lock.Unlock()
+		}
+	}
+}
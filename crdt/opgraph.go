@@ -0,0 +1,412 @@
+package crdt
+
+import (
+	"container/heap"
+	"library/packages/communication"
+	"sort"
+)
+
+// edge of the graph of unstable operations
+type OpEdge struct {
+	Source string
+	Target string
+	Label  string // "hb" if the source happened before the target, "ao" if the arbitration order puts the source first
+	Id     string // used to choose deterministically which arbitration edge is removed to break a cycle
+}
+
+// ids are not unique, two different pairs of hashes can have the same concatenation
+type edgeKey struct {
+	source string
+	target string
+}
+
+func (e OpEdge) key() edgeKey {
+	return edgeKey{e.Source, e.Target}
+}
+
+// order in which arbitration edges are chosen to be removed
+func (e OpEdge) less(other OpEdge) bool {
+	return e.Id < other.Id || e.Id == other.Id && e.Source < other.Source
+}
+
+type opVertex struct {
+	op      communication.Operation
+	in      map[string]OpEdge // source -> edge
+	out     map[string]OpEdge // target -> edge
+	removed []edgeKey         // removed edges that have the vertex as an endpoint
+	stable  bool
+}
+
+// OpGraph is the graph of unstable operations of the ECRO engines
+// vertices keep their incoming and outgoing edges, so removing a vertex is O(degree)
+// arbitration edges removed to break cycles are kept until both their endpoints leave the graph
+type OpGraph struct {
+	vertices map[string]*opVertex
+	removed  map[edgeKey]OpEdge
+}
+
+// returns an empty graph
+func NewOpGraph() *OpGraph {
+	return &OpGraph{vertices: make(map[string]*opVertex), removed: make(map[edgeKey]OpEdge)}
+}
+
+// number of vertices of the graph
+func (g *OpGraph) Len() int {
+	return len(g.vertices)
+}
+
+// adds an operation to the graph
+func (g *OpGraph) AddVertex(op communication.Operation) {
+	hash := opHash(op)
+	if _, ok := g.vertices[hash]; ok {
+		return
+	}
+	g.vertices[hash] = &opVertex{op: op, in: make(map[string]OpEdge), out: make(map[string]OpEdge)}
+}
+
+// returns the operation of a vertex
+func (g *OpGraph) Vertex(hash string) (communication.Operation, bool) {
+	v, ok := g.vertices[hash]
+	if !ok {
+		return communication.Operation{}, false
+	}
+	return v.op, true
+}
+
+// marks the operation of a vertex as stable
+func (g *OpGraph) SetStable(hash string) {
+	if v, ok := g.vertices[hash]; ok {
+		v.stable = true
+	}
+}
+
+// tells if the operation of a vertex is stable
+func (g *OpGraph) IsStable(hash string) bool {
+	v, ok := g.vertices[hash]
+	return ok && v.stable
+}
+
+// removes a vertex and all its edges
+// removed edges whose other endpoint already left the graph are forgotten
+func (g *OpGraph) RemoveVertex(hash string) {
+	v, ok := g.vertices[hash]
+	if !ok {
+		return
+	}
+	for source := range v.in {
+		delete(g.vertices[source].out, hash)
+	}
+	for target := range v.out {
+		delete(g.vertices[target].in, hash)
+	}
+	delete(g.vertices, hash)
+
+	for _, key := range v.removed {
+		edge, ok := g.removed[key]
+		if !ok {
+			continue
+		}
+		other := edge.Source
+		if other == hash {
+			other = edge.Target
+		}
+		if _, ok := g.vertices[other]; !ok {
+			delete(g.removed, key)
+		}
+	}
+}
+
+// adds an edge between two vertices of the graph
+func (g *OpGraph) AddEdge(source string, target string, label string) {
+	s, ok1 := g.vertices[source]
+	t, ok2 := g.vertices[target]
+	if !ok1 || !ok2 {
+		return
+	}
+	edge := OpEdge{Source: source, Target: target, Label: label, Id: source + target}
+	s.out[target] = edge
+	t.in[source] = edge
+}
+
+// tells if the graph has an edge from source to target
+func (g *OpGraph) HasEdge(source string, target string) bool {
+	v, ok := g.vertices[source]
+	if !ok {
+		return false
+	}
+	_, ok = v.out[target]
+	return ok
+}
+
+// number of edges that have the vertex as target
+func (g *OpGraph) InDegree(hash string) int {
+	v, ok := g.vertices[hash]
+	if !ok {
+		return 0
+	}
+	return len(v.in)
+}
+
+// records an arbitration edge removed to break a cycle
+func (g *OpGraph) removeEdge(edge OpEdge) {
+	if _, ok := g.removed[edge.key()]; ok {
+		return
+	}
+	g.removed[edge.key()] = edge
+	g.vertices[edge.Source].removed = append(g.vertices[edge.Source].removed, edge.key())
+	g.vertices[edge.Target].removed = append(g.vertices[edge.Target].removed, edge.key())
+}
+
+// arbitration edges removed to break cycles that still have an endpoint in the graph, sorted by id
+func (g *OpGraph) RemovedEdges() []OpEdge {
+	edges := make([]OpEdge, 0, len(g.removed))
+	for _, edge := range g.removed {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].less(edges[j])
+	})
+	return edges
+}
+
+// the maximum id of the removed edges, an arbitration edge with a smaller id could have been removed instead
+func (g *OpGraph) maxRemovedId() string {
+	max := ""
+	for _, edge := range g.removed {
+		if edge.Id > max {
+			max = edge.Id
+		}
+	}
+	return max
+}
+
+// calls f for every vertex of the graph
+func (g *OpGraph) Each(f func(hash string, op communication.Operation)) {
+	for hash, v := range g.vertices {
+		f(hash, v.op)
+	}
+}
+
+// returns the operations of the graph
+func (g *OpGraph) Operations() []communication.Operation {
+	ops := make([]communication.Operation, 0, len(g.vertices))
+	for _, v := range g.vertices {
+		ops = append(ops, v.op)
+	}
+	return ops
+}
+
+// orders the given operations following the edges between them
+// when more than one operation can come next the one with the minimum id is chosen, so the order is deterministic
+// if the edges have a cycle, the arbitration edge with the minimum id in it is removed, removed edges are recorded and returned
+func (g *OpGraph) TopologicalSort(operations []communication.Operation) ([]communication.Operation, []OpEdge) {
+	ops := make(map[string]communication.Operation, len(operations))
+	for _, op := range operations {
+		ops[opHash(op)] = op
+	}
+
+	// count incoming edges from the given operations
+	inDegree := make(map[string]int, len(ops))
+	for hash := range ops {
+		if v, ok := g.vertices[hash]; ok {
+			for source := range v.in {
+				if _, ok := ops[source]; ok {
+					inDegree[hash]++
+				}
+			}
+		}
+	}
+
+	next := &hashHeap{}
+	for hash := range ops {
+		if inDegree[hash] == 0 {
+			heap.Push(next, hash)
+		}
+	}
+
+	order := make([]communication.Operation, 0, len(ops))
+	removedEdges := []OpEdge{}
+	done := make(map[string]bool, len(ops))
+	cut := make(map[edgeKey]bool)
+	var cycles *cycles
+
+	for len(order) < len(ops) {
+		if next.Len() == 0 {
+			// there is a cycle, kill the arbitration edge with the minimum id between operations of the same cycle
+			if cycles == nil {
+				pending := []string{}
+				for hash := range ops {
+					if !done[hash] {
+						pending = append(pending, hash)
+					}
+				}
+				cycles = g.newCycles(pending, done, cut)
+			}
+			minEdge := cycles.minEdge()
+			if minEdge.Id == "" {
+				panic("cycle without arbitration edges in the graph of unstable operations")
+			}
+			cut[minEdge.key()] = true
+			removedEdges = append(removedEdges, minEdge)
+			g.removeEdge(minEdge)
+			cycles.split(minEdge)
+			inDegree[minEdge.Target]--
+			if inDegree[minEdge.Target] == 0 {
+				heap.Push(next, minEdge.Target)
+			}
+			continue
+		}
+
+		// add the minimum operation to the order and remove its edges
+		hash := heap.Pop(next).(string)
+		order = append(order, ops[hash])
+		done[hash] = true
+		if v, ok := g.vertices[hash]; ok {
+			for target, edge := range v.out {
+				if _, ok := ops[target]; !ok || done[target] || cut[edge.key()] {
+					continue
+				}
+				inDegree[target]--
+				if inDegree[target] == 0 {
+					heap.Push(next, target)
+				}
+			}
+		}
+	}
+
+	return order, removedEdges
+}
+
+// strongly connected components of the operations of a sort that are not done, following the edges that are not cut,
+// with the arbitration edge of minimum id inside each component
+// only edges inside a component are in a cycle, so edges to operations outside cycles are never removed
+// operations are done once no edge that is not cut comes to them, so they are alone in their component,
+// and cutting an edge only splits its component, so only that component is computed again
+type cycles struct {
+	g         *OpGraph
+	done      map[string]bool
+	cut       map[edgeKey]bool
+	component map[string]int   // vertex -> component
+	members   map[int][]string // component -> vertices
+	minEdges  map[int]OpEdge   // component -> arbitration edge with the minimum id inside it
+	count     int
+}
+
+// components of the given vertices of a sort
+func (g *OpGraph) newCycles(vertices []string, done map[string]bool, cut map[edgeKey]bool) *cycles {
+	c := &cycles{g: g, done: done, cut: cut, component: make(map[string]int), members: make(map[int][]string), minEdges: make(map[int]OpEdge)}
+	c.compute(vertices)
+	return c
+}
+
+// arbitration edge with the minimum id inside a component, an empty edge if there is none
+func (c *cycles) minEdge() OpEdge {
+	minEdge := OpEdge{}
+	for _, edge := range c.minEdges {
+		if minEdge.Id == "" || edge.less(minEdge) {
+			minEdge = edge
+		}
+	}
+	return minEdge
+}
+
+// computes again the component of a cut edge
+func (c *cycles) split(edge OpEdge) {
+	id := c.component[edge.Target]
+	vertices := []string{}
+	for _, hash := range c.members[id] {
+		if !c.done[hash] {
+			vertices = append(vertices, hash)
+		}
+	}
+	delete(c.members, id)
+	delete(c.minEdges, id)
+	c.compute(vertices)
+}
+
+// strongly connected components of the vertices and their arbitration edges of minimum id, the vertices must be closed under cycles
+func (c *cycles) compute(vertices []string) {
+	inside := make(map[string]bool, len(vertices))
+	for _, hash := range vertices {
+		inside[hash] = true
+	}
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := []string{}
+	next := 0
+
+	var visit func(hash string)
+	visit = func(hash string) {
+		index[hash], lowlink[hash] = next, next
+		next++
+		stack = append(stack, hash)
+		onStack[hash] = true
+
+		if v, ok := c.g.vertices[hash]; ok {
+			for target, edge := range v.out {
+				if !inside[target] || c.cut[edge.key()] {
+					continue
+				}
+				if _, visited := index[target]; !visited {
+					visit(target)
+					if lowlink[target] < lowlink[hash] {
+						lowlink[hash] = lowlink[target]
+					}
+				} else if onStack[target] && index[target] < lowlink[hash] {
+					lowlink[hash] = index[target]
+				}
+			}
+		}
+
+		if lowlink[hash] == index[hash] {
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				c.component[top] = c.count
+				c.members[c.count] = append(c.members[c.count], top)
+				if top == hash {
+					break
+				}
+			}
+			c.count++
+		}
+	}
+
+	for _, hash := range vertices {
+		if _, visited := index[hash]; !visited {
+			visit(hash)
+		}
+	}
+
+	for _, hash := range vertices {
+		v, ok := c.g.vertices[hash]
+		if !ok {
+			continue
+		}
+		id := c.component[hash]
+		for source, edge := range v.in {
+			if !inside[source] || c.component[source] != id || c.cut[edge.key()] || edge.Label != "ao" {
+				continue
+			}
+			if minEdge, ok := c.minEdges[id]; !ok || edge.less(minEdge) {
+				c.minEdges[id] = edge
+			}
+		}
+	}
+}
+
+// min heap of vertex hashes
+type hashHeap []string
+
+func (h hashHeap) Len() int           { return len(h) }
+func (h hashHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h hashHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *hashHeap) Push(x any)        { *h = append(*h, x.(string)) }
+func (h *hashHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
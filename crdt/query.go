@@ -0,0 +1,23 @@
+package crdt
+
+// Data interfaces whose state is not the value returned to clients (e.g. indexed structures)
+// can implement QueryDataI to convert it on Query
+type QueryDataI interface {
+	// Query returns the value of `state` returned to clients
+	Query(state any) any
+}
+
+// returns the value of state returned to clients
+func query(data any, state any) any {
+	if q, ok := data.(QueryDataI); ok {
+		return q.Query(state)
+	}
+	return state
+}
+
+// StateI is implemented by the engines that give the state of their optimistic reads before Query converts it,
+// so clients of indexed states (e.g. the positions of a text) look up what they need without a copy of the whole state
+type StateI interface {
+	// OptimisticState returns the state with all delivered operations, it must not be modified
+	OptimisticState() any
+}
@@ -0,0 +1,146 @@
+package crdt
+
+import (
+	"library/packages/communication"
+	"library/packages/replica"
+	"sync"
+)
+
+// a stable operation of the shadow engine that waits for the operations delivered before it became stable
+type pendingStable struct {
+	op        communication.Operation
+	delivered communication.VClock
+}
+
+// ReadOption turns on reads that need more than the state of an engine, engines take them on creation
+type ReadOption uint8
+
+const (
+	// HistoryReads keeps a snapshot and a log of the operations after it for QueryAt and Diff.
+	// Without it every version is compacted away
+	HistoryReads ReadOption = 1 << iota
+	// StableReads keeps a shadow engine with only the stable operations, so stable reads are exact.
+	// Without it an engine whose state mixes stable and unstable operations returns the last state whose operations were all stable
+	StableReads
+)
+
+// options combined into one
+func readOptions(options []ReadOption) ReadOption {
+	var all ReadOption
+	for _, o := range options {
+		all |= o
+	}
+	return all
+}
+
+// views keeps what the reads of an engine need: the versions of the delivered and of the stable operations,
+// and, for engines whose state mixes stable and unstable operations, a shadow engine that only receives the stable ones
+// or the last state whose operations were all stable
+type views struct {
+	lock           *sync.Mutex
+	delivered      communication.VClock // merged versions of the delivered operations
+	stable         communication.VClock // merged versions of the stable operations
+	shadow         replica.CrdtI        // engine of the same type with the stable operations, nil if the engine keeps its stable state or settles
+	pending        []pendingStable      // stable operations of the shadow that are not stabilized in it yet
+	settle         func() func() any    // captures the current state of the engine and returns its read, nil if the engine keeps its stable state or has a shadow
+	settled        func() any           // read of the last state whose operations were all stable
+	settledVersion communication.VClock // merged versions of the operations of the settled state
+	history        *history             // snapshot and log of the historical reads, nil unless asked for with HistoryReads
+}
+
+// returns views of an engine without operations, shadow is a new engine of the same type or nil
+func newViews(shadow replica.CrdtI) *views {
+	return &views{lock: new(sync.Mutex), delivered: communication.NewVClock(), stable: communication.NewVClock(), shadow: shadow}
+}
+
+// returns views of an engine without operations whose stable reads come from a shadow with StableReads and from settle otherwise
+// newShadow returns a new engine of the same type, settle captures the current state of the engine and returns its read
+func newMixedViews(options ReadOption, newShadow func() replica.CrdtI, settle func() func() any) *views {
+	if options&StableReads != 0 {
+		return newViews(newShadow())
+	}
+	v := newViews(nil)
+	v.settle = settle
+	return v
+}
+
+// records a delivered operation, before the engine applies it
+// the state is settled before the first operation delivered after all operations were stable
+func (v *views) effect(op communication.Operation) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.settle != nil && v.stable.Descends(v.delivered) {
+		v.settled, v.settledVersion = v.settle(), v.delivered.Copy()
+	}
+	v.delivered.Merge(op.Version)
+	if v.history != nil {
+		v.history.effect(op)
+	}
+}
+
+// records a stable operation
+// the shadow stabilizes an operation once all operations delivered before it became stable are stable,
+// so the operations concurrent with it were all given to the shadow before
+func (v *views) stabilize(op communication.Operation) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.stable.Merge(op.Version)
+	if v.history != nil {
+		v.history.stabilize(v.delivered, v.stable)
+	}
+	if v.shadow == nil {
+		return
+	}
+
+	v.shadow.Effect(op)
+	v.pending = append(v.pending, pendingStable{op: op, delivered: v.delivered.Copy()})
+	i := 0
+	for ; i < len(v.pending) && v.stable.Descends(v.pending[i].delivered); i++ {
+		v.shadow.Stabilize(v.pending[i].op)
+	}
+	v.pending = v.pending[i:]
+}
+
+// state and version of a read at level
+// current returns the state of the engine at level, it is only called for stable reads if there is no shadow
+// and the engine keeps its stable state or all operations are stable
+func (v *views) read(level replica.Level, current func() any) (any, communication.VClock) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if level == replica.Optimistic {
+		return current(), v.delivered.Copy()
+	}
+	if v.settle != nil && !v.stable.Descends(v.delivered) {
+		return v.settled(), v.settledVersion.Copy()
+	}
+	if v.shadow == nil {
+		return current(), v.stable.Copy()
+	}
+	st, _ := v.shadow.Read(replica.Optimistic)
+	return st, v.stable.Copy()
+}
+
+// state of the operations dominated by version
+func (v *views) queryAt(version communication.VClock) (any, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.history == nil {
+		return nil, &CompactedError{Version: version.Copy(), Snapshot: v.delivered.Copy()}
+	}
+	return v.history.queryAt(version)
+}
+
+// change between the states at from and to
+func (v *views) diff(from communication.VClock, to communication.VClock, data any) (Diff, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.history == nil {
+		return Diff{}, &CompactedError{Version: from.Copy(), Snapshot: v.delivered.Copy()}
+	}
+	return v.history.diff(from, to, data)
+}
@@ -2,6 +2,7 @@ package crdt
 
 import (
 	"library/packages/communication"
+	"library/packages/replica"
 	"sync"
 )
 
@@ -42,10 +43,31 @@ type Semidirect2CRDT struct {
 	S_Ops                uint64
 
 	effectLock *sync.RWMutex
+	views      *views //versions and stable state of the reads
 }
 
 // initialize semidirectcrdt
-func NewSemidirect2CRDT(id string, state any, data Semidirect2DataI) *Semidirect2CRDT {
+// options turns on the reads that need more than the state
+func NewSemidirect2CRDT(id string, state any, data Semidirect2DataI, options ...ReadOption) *Semidirect2CRDT {
+	c := newSemidirect2CRDT(id, state, data)
+	c.views = newMixedViews(readOptions(options), func() replica.CrdtI { return newSemidirect2CRDT(id, state, data) }, func() func() any {
+		st, nonMain := c.Unstable_st, c.getNonMainOperations()
+		return func() any { return query(c.Data, c.Data.Apply(st, nonMain)) }
+	})
+	//the non main operations are only applied to the state once stable
+	if readOptions(options)&HistoryReads != 0 {
+		c.views.history = newHistory(c, state, func(state any) replica.CrdtI {
+			return newSemidirect2CRDT(id, state, data)
+		}, func(engine replica.CrdtI) any {
+			e := engine.(*Semidirect2CRDT)
+			return e.Data.Apply(e.Unstable_st, e.getNonMainOperations())
+		})
+	}
+	return c
+}
+
+// initialize semidirectcrdt without a stable state for the reads
+func newSemidirect2CRDT(id string, state any, data Semidirect2DataI) *Semidirect2CRDT {
 	c := Semidirect2CRDT{
 		Id:                  id,
 		Data:                data,
@@ -55,6 +77,7 @@ func NewSemidirect2CRDT(id string, state any, data Semidirect2DataI) *Semidirect
 		N_Ops:               0,
 		S_Ops:               0,
 		effectLock:          new(sync.RWMutex),
+		views:               newViews(nil),
 	}
 
 	return &c
@@ -65,6 +88,7 @@ func (r *Semidirect2CRDT) Effect(op communication.Operation) {
 	defer r.effectLock.Unlock()
 
 	r.N_Ops++
+	r.views.effect(op)
 
 	if r.Data.MainOp() != op.Type {
 		r.NonMain_operations = append(r.NonMain_operations, NonMainOp{op, []communication.Operation{}})
@@ -101,6 +125,7 @@ func (r *Semidirect2CRDT) Stabilize(op communication.Operation) {
 	defer r.effectLock.Unlock()
 
 	r.S_Ops++
+	r.views.stabilize(op)
 
 	if r.Data.MainOp() == op.Type {
 		r.StableMain_operation = op
@@ -141,14 +166,47 @@ func (r *Semidirect2CRDT) Stabilize(op communication.Operation) {
 	r.Unstable_operations = append(r.Unstable_operations[:io], r.Unstable_operations[io+1:]...)
 }
 
-func (r *Semidirect2CRDT) Query() (any, any) {
-	//apply all non main operations
+func (r *Semidirect2CRDT) Read(level replica.Level) (any, communication.VClock) {
+	r.effectLock.Lock()
+	defer r.effectLock.Unlock()
+
+	return r.views.read(level, func() any {
+		//apply all non main operations
+		query_st := r.Data.Apply(r.Unstable_st, r.getNonMainOperations())
+		return query(r.Data, query_st)
+	})
+}
+
+// state with all delivered operations before Query, the non main operations applied to it
+func (r *Semidirect2CRDT) OptimisticState() any {
+	r.effectLock.Lock()
+	defer r.effectLock.Unlock()
+
+	return r.Data.Apply(r.Unstable_st, r.getNonMainOperations())
+}
+
+// non main operations that are not in the state yet, they are applied to it on reads
+func (r *Semidirect2CRDT) NonMainOperations() []communication.Operation {
+	r.effectLock.Lock()
+	defer r.effectLock.Unlock()
+
+	return r.getNonMainOperations()
+}
+
+// state with the operations dominated by version, returns a CompactedError if they were compacted away
+func (r *Semidirect2CRDT) QueryAt(version communication.VClock) (any, error) {
+	r.effectLock.Lock()
+	defer r.effectLock.Unlock()
+
+	return r.views.queryAt(version)
+}
+
+// change between the versions from and to, returns a CompactedError if the operations after from were compacted away
+func (r *Semidirect2CRDT) Diff(from communication.VClock, to communication.VClock) (Diff, error) {
 	r.effectLock.Lock()
 	defer r.effectLock.Unlock()
 
-	nonMainOp := r.getNonMainOperations()
-	query_st := r.Data.Apply(r.Unstable_st, nonMainOp)
-	return query_st, nonMainOp
+	return r.views.diff(from, to, r.Data)
 }
 
 func (r *Semidirect2CRDT) NumOps() uint64 {
@@ -2,6 +2,8 @@ package crdt
 
 import (
 	"library/packages/communication"
+	"library/packages/replica"
+	"sync"
 )
 
 // all updates are reparable
@@ -25,9 +27,14 @@ type SemidirectCRDT struct {
 	Unstable_st         any
 	N_Ops               uint64
 	S_Ops               uint64
+	Options             ReadOption // reads that need more than the state, see ReadOption
+
+	views *views //versions and stable state of the reads, created on first use from Unstable_st
+	once  sync.Once
 }
 
 func (r *SemidirectCRDT) Effect(op communication.Operation) {
+	r.reads().effect(op)
 	newOp := r.repair(op)
 	r.Unstable_st = r.Data.Apply(r.Unstable_st, []communication.Operation{newOp})
 
@@ -45,10 +52,47 @@ func (r *SemidirectCRDT) Stabilize(op communication.Operation) {
 		}
 	}
 	r.S_Ops++
+	r.reads().stabilize(op)
+}
+
+func (r *SemidirectCRDT) Read(level replica.Level) (any, communication.VClock) {
+	return r.reads().read(level, func() any {
+		return query(r.Data, r.Unstable_st)
+	})
+}
+
+// state with all delivered operations before Query
+func (r *SemidirectCRDT) OptimisticState() any {
+	return r.Unstable_st
+}
+
+// views of the reads, created with the reads turned on by Options
+func (r *SemidirectCRDT) reads() *views {
+	r.once.Do(func() {
+		if r.views == nil {
+			fork := func(state any) replica.CrdtI {
+				return &SemidirectCRDT{Id: r.Id, Data: r.Data, Unstable_operations: []communication.Operation{}, Unstable_st: state, views: newViews(nil)}
+			}
+			r.views = newMixedViews(r.Options, func() replica.CrdtI { return fork(r.Unstable_st) }, func() func() any {
+				st := r.Unstable_st
+				return func() any { return query(r.Data, st) }
+			})
+			if r.Options&HistoryReads != 0 {
+				r.views.history = newHistory(r, r.Unstable_st, fork, func(engine replica.CrdtI) any { return engine.(*SemidirectCRDT).Unstable_st })
+			}
+		}
+	})
+	return r.views
+}
+
+// state with the operations dominated by version, returns a CompactedError if they were compacted away
+func (r *SemidirectCRDT) QueryAt(version communication.VClock) (any, error) {
+	return r.reads().queryAt(version)
 }
 
-func (r *SemidirectCRDT) Query() (any, any) {
-	return r.Unstable_st, nil
+// change between the versions from and to, returns a CompactedError if the operations after from were compacted away
+func (r *SemidirectCRDT) Diff(from communication.VClock, to communication.VClock) (Diff, error) {
+	return r.reads().diff(from, to, r.Data)
 }
 
 func (r *SemidirectCRDT) NumOps() uint64 {
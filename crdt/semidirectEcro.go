@@ -2,12 +2,10 @@ package crdt
 
 import (
 	"library/packages/communication"
-	"strconv"
+	"library/packages/replica"
 	"sync"
 
 	"library/packages/utils"
-
-	"github.com/dominikbraun/graph"
 )
 
 // all updates are reparable
@@ -37,41 +35,56 @@ type SemidirectECRODataI interface {
 	RepairLeft(op1 communication.Operation, op2 communication.Operation) communication.Operation
 }
 
-type ECROOp struct {
-	Op              communication.Operation
-	HigherTimestamp []communication.Operation //to stabilize Op, all operations with lower timestamp must be stable when Op is applied to the state
-}
-
 type SemidirectECRO struct {
 	Id                   string
 	Data                 SemidirectECRODataI //data interface
 	Stable_st            any
 	SemidirectLog        []communication.Operation
 	StableMain_operation communication.Operation
-	ECROLog              graph.Graph[string, ECROOp]
+	ECROLog              *OpGraph
 	Unstable_st          any
 	Sorted_ops           []communication.Operation
-	Rem_Edges            []string
+	Checkpoints          *Checkpoints //intermediate states along Sorted_ops
+	Debug                *Debug       //checks the invariants of Data after every Effect and Stabilize when set
 
 	N_Ops uint64
 	S_Ops uint64
 
 	effectLock *sync.RWMutex
+	views      *views //versions and stable state of the reads
 }
 
 // initialize semidirectcrdt
-func NewSemidirectECRO(id string, state any, data SemidirectECRODataI) *SemidirectECRO {
+// options turns on the reads that need more than the state
+func NewSemidirectECRO(id string, state any, data SemidirectECRODataI, options ...ReadOption) *SemidirectECRO {
+	c := newSemidirectECRO(id, state, data)
+	c.views = newMixedViews(readOptions(options), func() replica.CrdtI { return newSemidirectECRO(id, state, data) }, func() func() any {
+		st := c.Unstable_st
+		return func() any { return query(c.Data, st) }
+	})
+	if readOptions(options)&HistoryReads != 0 {
+		c.views.history = newHistory(c, state, func(state any) replica.CrdtI {
+			return newSemidirectECRO(id, state, data)
+		}, func(engine replica.CrdtI) any { return engine.(*SemidirectECRO).Unstable_st })
+	}
+	return c
+}
+
+// initialize semidirectcrdt without a stable state for the reads
+func newSemidirectECRO(id string, state any, data SemidirectECRODataI) *SemidirectECRO {
 	c := SemidirectECRO{
 		Id:            id,
 		Data:          data,
 		Stable_st:     state,
 		SemidirectLog: []communication.Operation{},
-		ECROLog:       graph.New(opHashSemiECRO, graph.Directed(), graph.Acyclic()),
+		ECROLog:       NewOpGraph(),
 		Unstable_st:   state,
 		Sorted_ops:    []communication.Operation{},
+		Checkpoints:   NewCheckpoints(DefaultCheckpointInterval, state),
 		N_Ops:         0,
 		S_Ops:         0,
 		effectLock:    new(sync.RWMutex),
+		views:         newViews(nil),
 	}
 
 	return &c
@@ -80,23 +93,18 @@ func NewSemidirectECRO(id string, state any, data SemidirectECRODataI) *Semidire
 func (r *SemidirectECRO) Effect(op communication.Operation) {
 	r.effectLock.Lock()
 	defer r.effectLock.Unlock()
+	//op is repaired below, the received operation is reported
+	defer func(op communication.Operation) {
+		r.Debug.check(r.Data, r.Id, op, false, r.Stable_st, r.Unstable_st)
+	}(op)
 
 	r.N_Ops++
+	r.views.effect(op)
 
 	//------------------------- ECRO ------------------------
 
 	if utils.ContainsString(r.Data.ECROOps(), op.Type) {
-		ecroOp := ECROOp{op, []communication.Operation{}}
-		r.ECROLog.AddVertex(ecroOp, graph.VertexAttribute("label", opHashSemiECRO(ecroOp)+" "+op.Type+" "+op.Version.ReturnVCString()))
-		//checks if op respects arbitration order
-		if r.addEdges(op) {
-			r.Sorted_ops = append(r.Sorted_ops, op)
-			r.Unstable_st = r.Data.Apply(r.Unstable_st, []communication.Operation{op})
-		} else {
-			r.Sorted_ops = r.incTopologicalSort(r.Sorted_ops, op)
-			r.Unstable_st = r.Data.Apply(r.Stable_st, r.Sorted_ops)
-		}
-
+		r.addUnstable(op)
 		return
 	}
 
@@ -104,12 +112,20 @@ func (r *SemidirectECRO) Effect(op communication.Operation) {
 
 	op = r.repairLeft(op)
 
+	//the unstable operations op has seen and does not commute with are not in the stable state yet,
+	//op waits for them in the graph and moves to the stable state after them
+	if r.waits(op) {
+		r.addUnstable(op)
+		return
+	}
+
 	//-------------------------------------------------------
 
 	// --------------- semidirect continuous ----------------
 	newOp := r.repairRight(op)
 
 	r.Stable_st = r.Data.Apply(r.Stable_st, []communication.Operation{newOp})
+	r.Checkpoints.Reset(r.Stable_st)
 
 	if utils.ContainsString(r.Data.SemidirectOps(), op.Type) {
 		//add repairLeft operation to log
@@ -136,18 +152,52 @@ func (r *SemidirectECRO) Effect(op communication.Operation) {
 	if r.hasConcurrentRem(ecroNewOP) {
 		//add operation to unstable state
 		r.Unstable_st = r.Data.Apply(r.Unstable_st, []communication.Operation{ecroNewOP})
+		r.Checkpoints.Diverge()
 	} else {
-		r.Unstable_st = r.Data.Apply(r.Stable_st, r.Sorted_ops)
+		r.Unstable_st = r.Checkpoints.Replay(r.Data, r.Sorted_ops, 0)
 	}
 
 }
 
+// adds an operation to the graph and to the sorted operations
+func (r *SemidirectECRO) addUnstable(op communication.Operation) {
+	r.ECROLog.AddVertex(op)
+	//checks if op respects arbitration order
+	if r.addEdges(op) {
+		r.Sorted_ops = append(r.Sorted_ops, op)
+		r.Unstable_st = r.Data.Apply(r.Unstable_st, []communication.Operation{op})
+		r.Checkpoints.Add(len(r.Sorted_ops), r.Unstable_st)
+	} else {
+		//replay from the nearest checkpoint before the first operation whose position changed
+		sorted := r.incTopologicalSort(r.Sorted_ops, op)
+		from := firstDifference(r.Sorted_ops, sorted)
+		r.Sorted_ops = sorted
+		r.Unstable_st = r.Checkpoints.Replay(r.Data, r.Sorted_ops, from)
+	}
+}
+
+// tells if a semidirect operation has seen an unstable operation it does not commute with
+func (r *SemidirectECRO) waits(op communication.Operation) bool {
+	if !r.isSemidirect(op) {
+		return false
+	}
+	for _, o := range r.Sorted_ops {
+		if op.Version.Descends(o.Version) && !r.Data.Commutes(o, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// tells if an operation is of a semidirect type, repaired operations may have none of the types
+func (r *SemidirectECRO) isSemidirect(op communication.Operation) bool {
+	return utils.ContainsString(r.Data.SemidirectOps(), op.Type)
+}
+
 func (r *SemidirectECRO) hasConcurrentRem(op communication.Operation) bool {
 	//checks if op respects arbitration order
-	adjacencyMap, _ := r.ECROLog.AdjacencyMap()
-	for vertexHash := range adjacencyMap {
-		vertex, _ := r.ECROLog.Vertex(vertexHash)
-		if vertex.Op.Version.Compare(op.Version) != communication.Concurrent || !r.Data.Commutes(vertex.Op, op) {
+	for _, vertex := range r.ECROLog.Operations() {
+		if vertex.Version.Compare(op.Version) != communication.Concurrent || !r.Data.Commutes(vertex, op) {
 			return false
 		}
 	}
@@ -157,24 +207,35 @@ func (r *SemidirectECRO) hasConcurrentRem(op communication.Operation) bool {
 func (r *SemidirectECRO) Stabilize(op communication.Operation) {
 	r.effectLock.Lock()
 	defer r.effectLock.Unlock()
+	defer func() {
+		r.Debug.check(r.Data, r.Id, op, true, r.Stable_st, r.Unstable_st)
+	}()
 
 	r.S_Ops++
+	r.views.stabilize(op)
 
 	if utils.ContainsString(r.Data.SemidirectOps(), op.Type) {
 		r.StableMain_operation = op
 	}
 
-	if utils.ContainsString(r.Data.ECROOps(), op.Type) {
-		//remove from non main operations
-		adjacencyMap, _ := r.ECROLog.AdjacencyMap()
-		for vertexHash := range adjacencyMap {
-			vertex, _ := r.ECROLog.Vertex(vertexHash)
-			if vertex.Op.Equals(op) {
-				r.Stable_st = r.Data.Apply(r.Stable_st, []communication.Operation{op})
-				r.ECROLog.RemoveVertex(vertexHash)
-				break
+	//ECRO operations and the semidirect operations waiting in the graph move to the stable state in their sorted order
+	if vertex, ok := r.ECROLog.Vertex(opHash(op)); ok && vertex.Equals(op) || utils.ContainsString(r.Data.ECROOps(), op.Type) {
+		r.ECROLog.SetStable(opHash(op))
+		n := stablePrefix(r.ECROLog, r.Sorted_ops)
+		if n == 0 {
+			return
+		}
+
+		for _, o := range r.Sorted_ops[:n] {
+			r.ECROLog.RemoveVertex(opHash(o))
+			//a stable operation is not concurrent with later operations, so a waiting semidirect operation is not added to the log
+			if r.isSemidirect(o) {
+				o = r.repairRight(o)
 			}
+			r.Stable_st = r.Data.Apply(r.Stable_st, []communication.Operation{o})
 		}
+		r.Sorted_ops = r.Sorted_ops[n:]
+		r.Checkpoints.Reset(r.Stable_st)
 
 		return
 	}
@@ -189,13 +250,45 @@ func (r *SemidirectECRO) Stabilize(op communication.Operation) {
 	r.SemidirectLog = append(r.SemidirectLog[:io], r.SemidirectLog[io+1:]...)
 }
 
-func (r *SemidirectECRO) Query() (any, any) {
-	//apply all non main operations
+func (r *SemidirectECRO) Read(level replica.Level) (any, communication.VClock) {
+	r.effectLock.Lock()
+	defer r.effectLock.Unlock()
+
+	return r.views.read(level, func() any {
+		return query(r.Data, r.Unstable_st)
+	})
+}
+
+// state with all delivered operations before Query
+func (r *SemidirectECRO) OptimisticState() any {
 	r.effectLock.Lock()
 	defer r.effectLock.Unlock()
 
-	nonMainOp := r.getNonMainOperations()
-	return r.Unstable_st, nonMainOp
+	return r.Unstable_st
+}
+
+// ECRO operations that are not stable yet
+func (r *SemidirectECRO) NonMainOperations() []communication.Operation {
+	r.effectLock.Lock()
+	defer r.effectLock.Unlock()
+
+	return r.getNonMainOperations()
+}
+
+// state with the operations dominated by version, returns a CompactedError if they were compacted away
+func (r *SemidirectECRO) QueryAt(version communication.VClock) (any, error) {
+	r.effectLock.Lock()
+	defer r.effectLock.Unlock()
+
+	return r.views.queryAt(version)
+}
+
+// change between the versions from and to, returns a CompactedError if the operations after from were compacted away
+func (r *SemidirectECRO) Diff(from communication.VClock, to communication.VClock) (Diff, error) {
+	r.effectLock.Lock()
+	defer r.effectLock.Unlock()
+
+	return r.views.diff(from, to, r.Data)
 }
 
 func (r *SemidirectECRO) NumOps() uint64 {
@@ -220,11 +313,9 @@ func (r *SemidirectECRO) repairRight(op communication.Operation) communication.O
 
 func (r *SemidirectECRO) repairLeft(op communication.Operation) communication.Operation {
 
-	adjacencyMap, _ := r.ECROLog.AdjacencyMap()
-	for vertexHash := range adjacencyMap {
-		vertex, _ := r.ECROLog.Vertex(vertexHash)
-		if vertex.Op.Version.Compare(op.Version) == communication.Descendant {
-			op = r.Data.RepairLeft(vertex.Op, op)
+	for _, vertex := range r.ECROLog.Operations() {
+		if vertex.Version.Compare(op.Version) == communication.Descendant {
+			op = r.Data.RepairLeft(vertex, op)
 		}
 	}
 
@@ -255,13 +346,7 @@ func (r SemidirectECRO) indexOf(op communication.Operation) int {
 }
 
 func (r SemidirectECRO) getNonMainOperations() []communication.Operation {
-	nonMainOps := []communication.Operation{}
-	adjacencyMap, _ := r.ECROLog.AdjacencyMap()
-	for vertexHash := range adjacencyMap {
-		op, _ := r.ECROLog.Vertex(vertexHash)
-		nonMainOps = append(nonMainOps, op.Op)
-	}
-	return nonMainOps
+	return r.ECROLog.Operations()
 }
 
 func (r SemidirectECRO) getGreatestOps() []communication.Operation {
@@ -311,7 +396,8 @@ func (r *SemidirectECRO) incTopologicalSort(topoSort []communication.Operation,
 	} else if r.Data.Order(x, u) && !r.Data.Commutes(x, u) {
 		return append([]communication.Operation{x}, r.incTopologicalSort(topoSort[1:], u)...)
 	} else {
-		isLess := true
+		//u goes first if nothing in the graph must precede it and it is ordered before every operation
+		isLess := r.ECROLog.InDegree(opHash(u)) == 0
 		for _, y := range topoSort {
 			if !(r.Data.Order(u, y) && !r.Data.Commutes(y, u)) {
 				isLess = false
@@ -325,159 +411,45 @@ func (r *SemidirectECRO) incTopologicalSort(topoSort []communication.Operation,
 	return r.topologicalSort(append([]communication.Operation{u}, topoSort...))
 }
 
-// orders the operations in the graph
+// orders the operations in the graph, arbitration edges removed to break cycles are kept in the graph
 func (r *SemidirectECRO) topologicalSort(vertices []communication.Operation) []communication.Operation {
-	//find minimum vertex of the graph (vertex with no incoming edges)
-	//it can have more than one minimum, choose deterministically (by finding the minimum id) and continue algorithm
-
-	//if the minimum exists put it in the topological order and search for the next recursively
-
-	//if the minimum does not exist, the graph has cycles
-	//the algorithm kills an arbitration edge deterministically (by finding the edge with the minimum id)
-	//after killing the edge one of the verices will be the minimum if there is only one cycle
-	//if there's another cycle repeat the process
-
-	var order []communication.Operation
-	removedVertices := make(map[string]bool)
-	removedEdges := make(map[string]map[string]bool)
-	//predecessorMap, _ := r.Unstable_operations.PredecessorMap()
-	edgesG, _ := r.ECROLog.Edges()
-
-	edges := []graph.Edge[string]{}
-
-	for _, edge := range edgesG {
-		target, _ := r.ECROLog.Vertex(edge.Target)
-		source, _ := r.ECROLog.Vertex(edge.Source)
-		if utils.Contains(vertices, target.Op) && utils.Contains(vertices, source.Op) {
-			edges = append(edges, edge)
-		}
-	}
-
-	for {
-		// Create map to count incoming edges
-		inDegree := make(map[string]int)
-
-		for _, vertex := range vertices {
-			inDegree[opHash(vertex)] = 0 // Initialize inDegree for all vertices to 0
-		}
-
-		for _, edge := range edges {
-			if !removedVertices[edge.Source] && !removedVertices[edge.Target] && !removedEdges[edge.Source][edge.Target] {
-				inDegree[edge.Target]++
-			}
-		}
-
-		// Find minimum vertex
-		minVertex := communication.Operation{}
-
-		for vertex, degree := range inDegree {
-			if degree == 0 && !removedVertices[vertex] {
-				if minVertex.Type == "" || vertex < opHash(minVertex) {
-					minV, _ := r.ECROLog.Vertex(vertex)
-					minVertex = minV.Op
-				}
-			}
-		}
-
-		// If no minimum vertex found, there is a cycle
-		if minVertex.Type == "" {
-			minEdge := graph.Edge[string]{Source: "", Target: "", Properties: graph.EdgeProperties{Attributes: map[string]string{"label": "ao"}}}
-			for _, edge := range edges {
-				if edge.Properties.Attributes["label"] == "ao" && (minEdge.Source == "" || edge.Properties.Attributes["id"] < minEdge.Properties.Attributes["id"]) && !removedEdges[edge.Source][edge.Target] {
-					minEdge = edge
-				}
-			}
-
-			// Remove the minimum ID edge from the graph
-			if removedEdges[minEdge.Source] == nil {
-				removedEdges[minEdge.Source] = make(map[string]bool)
-			}
-			removedEdges[minEdge.Source][minEdge.Target] = true
-			r.Rem_Edges = append(r.Rem_Edges, minEdge.Properties.Attributes["id"])
-			continue
-		}
-
-		// Add minimum vertex to topological order and "remove" it from the graph
-		order = append(order, minVertex)
-		removedVertices[opHash(minVertex)] = true
-
-		// If all vertices are "removed", we are done
-		if len(order) == len(vertices) {
-			break
-		}
-	}
-
+	order, _ := r.ECROLog.TopologicalSort(vertices)
 	return order
 }
 
 // add edges to graph and return if its descendant of all operations or not
 func (r *SemidirectECRO) addEdges(op communication.Operation) bool {
 	isSafe := true
-	adjacencyMap, _ := r.ECROLog.AdjacencyMap()
-	for vertexHash := range adjacencyMap {
-		vertex, _ := r.ECROLog.Vertex(vertexHash)
-		if op.Equals(vertex.Op) {
-			continue
+	maxRemoved := r.ECROLog.maxRemovedId()
+	r.ECROLog.Each(func(vertexHash string, vertex communication.Operation) {
+		if op.Equals(vertex) {
+			return
 		}
-		cmp := op.Version.Compare(vertex.Op.Version)
+		cmp := op.Version.Compare(vertex.Version)
 		opHash := opHash(op)
 
-		if cmp == communication.Ancestor && !r.Data.Commutes(op, vertex.Op) {
-			r.ECROLog.AddEdge(vertexHash, opHash, graph.EdgeAttributes(map[string]string{"label": "hb", "id": vertexHash + opHash}))
-		} else if cmp == communication.Concurrent && !r.Data.Commutes(op, vertex.Op) {
-			if r.Data.Order(op, vertex.Op) {
+		if cmp == communication.Ancestor && !r.Data.Commutes(op, vertex) {
+			r.ECROLog.AddEdge(vertexHash, opHash, "hb")
+		} else if cmp == communication.Concurrent && !r.Data.Commutes(op, vertex) {
+			//a semidirect operation waiting in the graph goes before the concurrent ECRO operations
+			if r.isSemidirect(op) != r.isSemidirect(vertex) {
+				if r.isSemidirect(op) {
+					isSafe = false
+					r.ECROLog.AddEdge(opHash, vertexHash, "ao")
+				} else {
+					r.ECROLog.AddEdge(vertexHash, opHash, "ao")
+				}
+			} else if r.Data.Order(op, vertex) {
 				isSafe = false
-				r.ECROLog.AddEdge(opHash, vertexHash, graph.EdgeAttributes(map[string]string{"label": "ao", "id": opHash + vertexHash}))
-			} else if r.Data.Order(vertex.Op, op) {
-				if isSafe {
-					for _, edge := range r.Rem_Edges {
-						if vertexHash+opHash < edge {
-							isSafe = false
-						}
-					}
+				r.ECROLog.AddEdge(opHash, vertexHash, "ao")
+			} else if r.Data.Order(vertex, op) {
+				if isSafe && vertexHash+opHash < maxRemoved {
+					isSafe = false
 				}
-				r.ECROLog.AddEdge(vertexHash, opHash, graph.EdgeAttributes(map[string]string{"label": "ao", "id": vertexHash + opHash}))
+				r.ECROLog.AddEdge(vertexHash, opHash, "ao")
 			}
 		}
-	}
+	})
 
 	return isSafe
 }
-
-// creates hash for operation
-func opHashSemiECRO(op ECROOp) string {
-	return strconv.FormatUint(op.Op.Version.Sum(), 10) + op.Op.OriginID
-}
-
-// update vertex of the graph by removing all edges that have the operation as target or source and then removing the vertex and adding it again
-func (r *SemidirectECRO) updateVertex(op ECROOp, newop ECROOp) {
-
-	tempEdges := []graph.Edge[string]{} //edges to be removed
-	adjacencyMap, _ := r.ECROLog.AdjacencyMap()
-	for _, edges := range adjacencyMap {
-		for _, edge := range edges {
-			if edge.Source == opHashSemiECRO(op) || edge.Target == opHashSemiECRO(op) {
-				tempEdges = append(tempEdges, edge)
-				r.ECROLog.RemoveEdge(edge.Source, edge.Target)
-			}
-		}
-	}
-	//remove from graph
-	err := r.ECROLog.RemoveVertex(opHashSemiECRO(op))
-
-	if err != nil {
-		panic(err)
-	}
-	//add to graph
-	err = r.ECROLog.AddVertex(newop, graph.VertexAttribute("label", opHashSemiECRO(newop)+" "+newop.Op.Type+" "+newop.Op.Version.ReturnVCString()))
-	if err != nil {
-		panic(err)
-	}
-	//add edges again
-	for _, edge := range tempEdges {
-		err = r.ECROLog.AddEdge(edge.Source, edge.Target, graph.EdgeAttributes(map[string]string{"label": edge.Properties.Attributes["label"], "id": edge.Properties.Attributes["id"]}))
-		if err != nil {
-			panic(err)
-		}
-	}
-}
@@ -0,0 +1,184 @@
+package crdtcheck
+
+import (
+	"fmt"
+	"library/packages/communication"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// default configuration of a check
+const (
+	DefaultMaxCount   = 100
+	DefaultHistory    = 10
+	DefaultConcurrent = 3
+)
+
+// Generator builds the states and operations the laws are checked on
+// states are built by applying generated operations to the initial state, so only reachable states are checked
+type Generator struct {
+	State     any                                       // initial state
+	Operation func(state any, choice int) (string, any) // type and value of an operation prepared on state, choice picks one of the possible operations
+	Equal     func(st1 any, st2 any) bool               // tells if two states are equal
+}
+
+// Config of a check, zero values are replaced by the defaults
+type Config struct {
+	MaxCount   int        // number of random scenarios
+	History    int        // maximum number of operations applied to the initial state before the concurrent operations
+	Concurrent int        // number of concurrent operations
+	Rand       *rand.Rand // source of the choices, seeded with the time if nil
+}
+
+// Counterexample is a scenario that breaks a law
+// it is shrunk until removing an operation or simplifying its choice satisfies the law
+type Counterexample struct {
+	Law        string
+	History    []communication.Operation // operations applied one after the other to the initial state
+	State      any                       // state after the history, the concurrent operations are prepared on it
+	Operations []communication.Operation // concurrent operations
+	Reason     string
+}
+
+func (c *Counterexample) Error() string {
+	return fmt.Sprintf("law %q is broken: %s\nhistory: %v\nstate: %v\nconcurrent operations: %v", c.Law, c.Reason, c.History, c.State, c.Operations)
+}
+
+// data interfaces that can apply operations to a state
+type applier interface {
+	Apply(state any, operations []communication.Operation) any
+}
+
+// law returns why the concurrent operations prepared on state break it, or "" if they satisfy it
+type law struct {
+	name  string
+	check func(state any, ops []communication.Operation) string
+}
+
+// choices of the operations of a scenario, operations are generated again from them after shrinking
+type scenario struct {
+	history    []int // operations of replica "0", each one prepared on the state after the previous ones
+	concurrent []int // the i-th operation is prepared by replica i+1 on the state after the history
+}
+
+// generates the operations of a scenario
+func (s scenario) build(data applier, gen Generator) ([]communication.Operation, any, []communication.Operation) {
+	state := gen.State
+	history := make([]communication.Operation, len(s.history))
+	for i, choice := range s.history {
+		opType, opValue := gen.Operation(state, choice)
+		history[i] = communication.Operation{Type: opType, Value: opValue, Version: communication.NewVClockFromMap(map[string]uint64{"0": uint64(i + 1)}), OriginID: "0"}
+		state = data.Apply(state, history[i:i+1])
+	}
+
+	concurrent := make([]communication.Operation, len(s.concurrent))
+	for i, choice := range s.concurrent {
+		id := strconv.Itoa(i + 1)
+		version := map[string]uint64{id: 1}
+		if len(history) > 0 {
+			version["0"] = uint64(len(history))
+		}
+		opType, opValue := gen.Operation(state, choice)
+		concurrent[i] = communication.Operation{Type: opType, Value: opValue, Version: communication.NewVClockFromMap(version), OriginID: id}
+	}
+	return history, state, concurrent
+}
+
+// returns why the scenario breaks the law, or "" if it satisfies it
+func (s scenario) violates(data applier, gen Generator, l law) string {
+	_, state, ops := s.build(data, gen)
+	return l.check(state, ops)
+}
+
+// scenarios with one operation less or one choice halved, simplest first
+func (s scenario) simpler() []scenario {
+	simpler := []scenario{}
+	for i := range s.history {
+		simpler = append(simpler, scenario{history: without(s.history, i), concurrent: s.concurrent})
+	}
+	for i := range s.concurrent {
+		if len(s.concurrent) > 2 {
+			simpler = append(simpler, scenario{history: s.history, concurrent: without(s.concurrent, i)})
+		}
+	}
+	for i := range s.history {
+		if s.history[i] > 0 {
+			simpler = append(simpler, scenario{history: halved(s.history, i), concurrent: s.concurrent})
+		}
+	}
+	for i := range s.concurrent {
+		if s.concurrent[i] > 0 {
+			simpler = append(simpler, scenario{history: s.history, concurrent: halved(s.concurrent, i)})
+		}
+	}
+	return simpler
+}
+
+// copy of choices without the i-th one
+func without(choices []int, i int) []int {
+	return append(append([]int{}, choices[:i]...), choices[i+1:]...)
+}
+
+// copy of choices with the i-th one halved
+func halved(choices []int, i int) []int {
+	cp := append([]int{}, choices...)
+	cp[i] /= 2
+	return cp
+}
+
+// checks the laws on random scenarios and returns the shrunk counterexample of the first broken law
+func check(data applier, gen Generator, config *Config, laws []law) error {
+	cfg := Config{MaxCount: DefaultMaxCount, History: DefaultHistory, Concurrent: DefaultConcurrent}
+	if config != nil {
+		cfg = *config
+		if cfg.MaxCount == 0 {
+			cfg.MaxCount = DefaultMaxCount
+		}
+		if cfg.History == 0 {
+			cfg.History = DefaultHistory
+		}
+		if cfg.Concurrent == 0 {
+			cfg.Concurrent = DefaultConcurrent
+		}
+	}
+	if cfg.Rand == nil {
+		cfg.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	if cfg.Concurrent < 2 {
+		cfg.Concurrent = 2 //laws are about pairs of concurrent operations
+	}
+
+	for n := 0; n < cfg.MaxCount; n++ {
+		s := scenario{history: make([]int, cfg.Rand.Intn(cfg.History+1)), concurrent: make([]int, cfg.Concurrent)}
+		for i := range s.history {
+			s.history[i] = cfg.Rand.Intn(1000)
+		}
+		for i := range s.concurrent {
+			s.concurrent[i] = cfg.Rand.Intn(1000)
+		}
+
+		for _, l := range laws {
+			if s.violates(data, gen, l) != "" {
+				return shrink(data, gen, l, s)
+			}
+		}
+	}
+	return nil
+}
+
+// simplifies a scenario that breaks the law while it keeps breaking it
+func shrink(data applier, gen Generator, l law, s scenario) *Counterexample {
+	for shrunk := true; shrunk; {
+		shrunk = false
+		for _, simpler := range s.simpler() {
+			if simpler.violates(data, gen, l) != "" {
+				s, shrunk = simpler, true
+				break
+			}
+		}
+	}
+
+	history, state, ops := s.build(data, gen)
+	return &Counterexample{Law: l.name, History: history, State: state, Operations: ops, Reason: l.check(state, ops)}
+}
@@ -0,0 +1,111 @@
+package crdtcheck
+
+import (
+	"fmt"
+	"library/packages/communication"
+	"library/packages/replica"
+	"library/packages/utils"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dominikbraun/graph"
+)
+
+// Divergence is a pair of delivery orders of the same operations that end in different query results
+type Divergence struct {
+	Order1 []communication.Operation
+	Query1 any
+	Order2 []communication.Operation
+	Query2 any
+	Stable bool // the queries were made after stabilizing all operations in delivery order
+}
+
+func (d *Divergence) Error() string {
+	when := "after delivering"
+	if d.Stable {
+		when = "after delivering and stabilizing"
+	}
+	return fmt.Sprintf("interleavings diverge %s\n%v\nquery: %v\n%v\nquery: %v", when, d.Order1, d.Query1, d.Order2, d.Query2)
+}
+
+// CheckInterleavings delivers the operations to a new engine in every order allowed by their causal order,
+// given by their versions, and returns a Divergence if two orders end in different query results
+// queries are optimistic reads, if stabilize is true the operations are also stabilized in delivery order and the stable reads compared
+// query results are compared with equal, or with reflect.DeepEqual if it is nil
+// engines run in the calling goroutine, so the same operations always give the same result
+func CheckInterleavings(newEngine func() replica.CrdtI, operations []communication.Operation, equal func(q1 any, q2 any) bool, stabilize bool) error {
+	if equal == nil {
+		equal = reflect.DeepEqual
+	}
+
+	orders, err := interleavings(operations)
+	if err != nil {
+		return err
+	}
+
+	var first, firstStable any
+	for i, order := range orders {
+		engine := newEngine()
+		for _, op := range order {
+			engine.Effect(op)
+		}
+		q, _ := engine.Read(replica.Optimistic)
+		if i == 0 {
+			first = q
+		} else if !equal(first, q) {
+			return &Divergence{Order1: orders[0], Query1: first, Order2: order, Query2: q}
+		}
+
+		if !stabilize {
+			continue
+		}
+		for _, op := range order {
+			engine.Stabilize(op)
+		}
+		q, _ = engine.Read(replica.Stable)
+		if i == 0 {
+			firstStable = q
+		} else if !equal(firstStable, q) {
+			return &Divergence{Order1: orders[0], Query1: firstStable, Order2: order, Query2: q, Stable: true}
+		}
+	}
+	return nil
+}
+
+// every order of the operations that respects the causal order of their versions, sorted so results are reproducible
+func interleavings(operations []communication.Operation) ([][]communication.Operation, error) {
+	hash := func(op communication.Operation) string {
+		return op.Version.ReturnVCString() + op.OriginID
+	}
+
+	causal := graph.New(hash, graph.Directed(), graph.Acyclic())
+	for _, op := range operations {
+		if err := causal.AddVertex(op); err != nil {
+			return nil, fmt.Errorf("operation %v: %w", op, err)
+		}
+	}
+	for _, op1 := range operations {
+		for _, op2 := range operations {
+			if op1.Version.Compare(op2.Version) == communication.Descendant {
+				if err := causal.AddEdge(hash(op1), hash(op2)); err != nil {
+					return nil, fmt.Errorf("causal order of %v and %v: %w", op1, op2, err)
+				}
+			}
+		}
+	}
+
+	hashOrders := utils.GetAllTopologicalOrders(&causal)
+	sort.Slice(hashOrders, func(i, j int) bool {
+		return strings.Join(hashOrders[i], " ") < strings.Join(hashOrders[j], " ")
+	})
+
+	orders := make([][]communication.Operation, len(hashOrders))
+	for i, hashOrder := range hashOrders {
+		orders[i] = make([]communication.Operation, len(hashOrder))
+		for j, h := range hashOrder {
+			orders[i][j], _ = causal.Vertex(h)
+		}
+	}
+	return orders, nil
+}
@@ -0,0 +1,152 @@
+package crdtcheck
+
+import (
+	"fmt"
+	"library/packages/communication"
+	"library/packages/crdt"
+)
+
+// CheckEcro checks that Commutes is symmetric and agrees with Apply,
+// and that Order is irreflexive and acyclic on concurrent operations that do not commute
+func CheckEcro(data crdt.EcroDataI, gen Generator, config *Config) error {
+	return check(data, gen, config, []law{
+		{"Commutes is symmetric", func(state any, ops []communication.Operation) string {
+			for i := range ops {
+				for j := i + 1; j < len(ops); j++ {
+					if data.Commutes(ops[i], ops[j]) != data.Commutes(ops[j], ops[i]) {
+						return fmt.Sprintf("Commutes(%v, %v) is %v but Commutes(%v, %v) is %v",
+							ops[i], ops[j], data.Commutes(ops[i], ops[j]), ops[j], ops[i], data.Commutes(ops[j], ops[i]))
+					}
+				}
+			}
+			return ""
+		}},
+		{"commuting operations commute", func(state any, ops []communication.Operation) string {
+			for i := range ops {
+				for j := i + 1; j < len(ops); j++ {
+					if !data.Commutes(ops[i], ops[j]) {
+						continue
+					}
+					st1 := data.Apply(state, []communication.Operation{ops[i], ops[j]})
+					st2 := data.Apply(state, []communication.Operation{ops[j], ops[i]})
+					if !gen.Equal(st1, st2) {
+						return fmt.Sprintf("%v and %v commute but applying them in both orders gives %v and %v", ops[i], ops[j], st1, st2)
+					}
+				}
+			}
+			return ""
+		}},
+		{"Order is irreflexive", func(state any, ops []communication.Operation) string {
+			for _, op := range ops {
+				if data.Order(op, op) {
+					return fmt.Sprintf("Order(%v, %v) is true", op, op)
+				}
+			}
+			return ""
+		}},
+		{"Order is acyclic on operations that do not commute", func(state any, ops []communication.Operation) string {
+			if cycle := orderCycle(data, ops); cycle != nil {
+				return fmt.Sprintf("Order has the cycle %v", cycle)
+			}
+			return ""
+		}},
+	})
+}
+
+// CheckSemidirect checks that delivering concurrent operations in any order,
+// repairing them with the operations that satisfy the arbitration constraint, ends in the same state
+func CheckSemidirect(data crdt.SemidirectDataI, gen Generator, config *Config) error {
+	return check(data, gen, config, []law{
+		{"Repair converges", func(state any, ops []communication.Operation) string {
+			orders := permutations(ops)
+			first := deliverSemidirect(data, state, orders[0])
+			for _, order := range orders[1:] {
+				if st := deliverSemidirect(data, state, order); !gen.Equal(first, st) {
+					return fmt.Sprintf("delivering %v gives %v but delivering %v gives %v", orders[0], first, order, st)
+				}
+			}
+			return ""
+		}},
+	})
+}
+
+// returns a cycle of the order between operations that do not commute, or nil if there is none
+func orderCycle(data crdt.EcroDataI, ops []communication.Operation) []communication.Operation {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	color := make([]int, len(ops))
+	path := []int{}
+
+	var visit func(i int) []communication.Operation
+	visit = func(i int) []communication.Operation {
+		color[i] = visiting
+		path = append(path, i)
+		for j := range ops {
+			if i == j || data.Commutes(ops[i], ops[j]) || !data.Order(ops[i], ops[j]) {
+				continue
+			}
+			if color[j] == visiting {
+				// the cycle is the path from j to i
+				cycle := []communication.Operation{}
+				for k := len(path) - 1; k >= 0; k-- {
+					cycle = append([]communication.Operation{ops[path[k]]}, cycle...)
+					if path[k] == j {
+						break
+					}
+				}
+				return cycle
+			}
+			if color[j] == unvisited {
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		color[i] = visited
+		return nil
+	}
+
+	for i := range ops {
+		if color[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// applies concurrent operations as a SemidirectCRDT that receives them in the given order
+func deliverSemidirect(data crdt.SemidirectDataI, state any, ops []communication.Operation) any {
+	unstable := []communication.Operation{}
+	for _, op := range ops {
+		newOp := op
+		for _, o := range unstable {
+			newOp = data.Repair(o, newOp)
+		}
+		state = data.Apply(state, []communication.Operation{newOp})
+		if data.ArbitrationConstraint(newOp) {
+			unstable = append(unstable, op)
+		}
+	}
+	return state
+}
+
+// all orders of the operations
+func permutations(ops []communication.Operation) [][]communication.Operation {
+	if len(ops) <= 1 {
+		return [][]communication.Operation{append([]communication.Operation{}, ops...)}
+	}
+	orders := [][]communication.Operation{}
+	for i := range ops {
+		rest := append(append([]communication.Operation{}, ops[:i]...), ops[i+1:]...)
+		for _, order := range permutations(rest) {
+			orders = append(orders, append([]communication.Operation{ops[i]}, order...))
+		}
+	}
+	return orders
+}
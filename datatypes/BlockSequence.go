@@ -0,0 +1,325 @@
+package datatypes
+
+import (
+	"library/packages/communication"
+	"library/packages/datatypes/persistent"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BlockID identifies the operation that inserted a block of characters
+type BlockID struct {
+	Sum      uint64 // sum of the version of the operation
+	OriginID string
+}
+
+// returns the identifier of the block inserted by the operation with the given version and origin
+func NewBlockID(version communication.VClock, originID string) BlockID {
+	return BlockID{Sum: version.Sum(), OriginID: originID}
+}
+
+// check if the block was inserted before other in the arbitration order
+func (id BlockID) Less(other BlockID) bool {
+	return id.Sum < other.Sum || id.Sum == other.Sum && id.OriginID < other.OriginID
+}
+
+// CharID identifies a character by its block and its offset in the block, the zero CharID is the start of the sequence
+type CharID struct {
+	Block  BlockID
+	Offset int
+}
+
+// CharSpan is a run of Length characters of a block starting at Offset
+type CharSpan struct {
+	Block  BlockID
+	Offset int
+	Length int
+}
+
+// BlockOpValue is the value of an operation on a block sequence
+// an insert adds Text as one block after the character After, a remove deletes the characters of Spans
+type BlockOpValue struct {
+	After CharID
+	Text  string
+	Spans []CharSpan
+}
+
+// consecutive characters of a block, blocks are split in runs when an insert or a remove targets their middle
+type run struct {
+	block   BlockID
+	offset  int    // offset of the first character of the run in its block
+	text    []rune // never modified, runs split from the same block share it
+	deleted bool   // the characters are tombstones, kept so inserts after them keep their place
+}
+
+// identifier of a run, the block and offset of its first character
+type runKey struct {
+	block  BlockID
+	offset int
+}
+
+func keyOf(r run) runKey {
+	return runKey{block: r.block, offset: r.offset}
+}
+
+// number of visible characters of a run
+func visibleLen(r run) int {
+	if r.deleted {
+		return 0
+	}
+	return len(r.text)
+}
+
+// check if the run has one of the characters
+func (r run) hasAny(chars []CharID) bool {
+	for _, c := range chars {
+		if c.Block == r.block && r.offset <= c.Offset && c.Offset < r.offset+len(r.text) {
+			return true
+		}
+	}
+	return false
+}
+
+func hashBlock(id BlockID) uint64 {
+	return persistent.HashString(strconv.FormatUint(id.Sum, 10) + id.OriginID)
+}
+
+func hashRun(k runKey) uint64 {
+	return persistent.HashString(strconv.FormatUint(k.block.Sum, 10) + k.block.OriginID + ":" + strconv.Itoa(k.offset))
+}
+
+// BlockSequence is the state of a block-wise RGA, a sequence of runs of characters
+// runs are kept in a labelled tree weighted by their visible characters, and the runs of each block are indexed by their offsets,
+// so finding a character by its identifier or by its position is O(log n) and copies of the sequence are O(1)
+type BlockSequence struct {
+	runs   labelled[runKey, run]
+	blocks *persistent.Map[BlockID, []int] // offsets of the runs of each block in increasing order, never modified
+}
+
+// returns an empty sequence
+func NewBlockSequence() *BlockSequence {
+	return &BlockSequence{runs: newLabelled[runKey, run](keyOf, hashRun, visibleLen), blocks: persistent.NewMapWithHash[BlockID, []int](hashBlock)}
+}
+
+// copy of the sequence, O(1) since runs are never modified
+func (s *BlockSequence) Copy() *BlockSequence {
+	return &BlockSequence{runs: s.runs.copy(), blocks: s.blocks.Clone()}
+}
+
+// inserts text as the block id after the character after, or at the start if the character is not found
+func (s *BlockSequence) Insert(after CharID, id BlockID, text string) {
+	i := 0
+	if after != (CharID{}) {
+		if j, k := s.find(after); j != -1 {
+			s.split(j, k+1)
+			i = j + 1
+		}
+	}
+	s.runs.insert(i, run{block: id, text: []rune(text)})
+	s.addOffset(id, 0)
+}
+
+// marks the characters of span as deleted
+func (s *BlockSequence) Delete(span CharSpan) {
+	end := span.Offset + span.Length
+	for offset := span.Offset; offset < end; {
+		j, k := s.find(CharID{Block: span.Block, Offset: offset})
+		if j == -1 {
+			//the characters were collected, continue with the next run of the block
+			next, ok := s.nextOffset(span.Block, offset)
+			if !ok {
+				return
+			}
+			offset = next
+			continue
+		}
+		//split the run so only the characters of the span are deleted
+		if k > 0 {
+			s.split(j, k)
+			j++
+		}
+		if r := s.runs.at(j); r.offset+len(r.text) > end {
+			s.split(j, end-r.offset)
+		}
+		r := s.runs.at(j)
+		r.deleted = true
+		s.runs.set(j, r)
+		offset = r.offset + len(r.text)
+	}
+}
+
+// removes the deleted runs with characters of span, except the ones with a character of keep, and returns whether it removed any
+// deleted runs are only needed by the inserts after their characters
+func (s *BlockSequence) Collect(span CharSpan, keep []CharID) bool {
+	offsets, _ := s.blocks.Get(span.Block)
+	dropped := []int{}
+	for i := s.runOffset(offsets, span.Offset); i < len(offsets) && offsets[i] < span.Offset+span.Length; i++ {
+		r := s.runs.at(s.runs.indexOf(runKey{block: span.Block, offset: offsets[i]}))
+		if r.deleted && r.offset+len(r.text) > span.Offset && !r.hasAny(keep) {
+			dropped = append(dropped, r.offset)
+		}
+	}
+	for _, offset := range dropped {
+		s.runs.remove(s.runs.indexOf(runKey{block: span.Block, offset: offset}))
+		s.removeOffset(span.Block, offset)
+	}
+	return len(dropped) > 0
+}
+
+// index of the run with the character and the offset of the character in the run, -1 if not found
+func (s *BlockSequence) find(c CharID) (int, int) {
+	offsets, _ := s.blocks.Get(c.Block)
+	i := s.runOffset(offsets, c.Offset)
+	if i == len(offsets) || offsets[i] > c.Offset {
+		return -1, 0
+	}
+	j := s.runs.indexOf(runKey{block: c.Block, offset: offsets[i]})
+	if r := s.runs.at(j); c.Offset >= r.offset+len(r.text) {
+		return -1, 0
+	}
+	return j, c.Offset - offsets[i]
+}
+
+// index in offsets of the run that can have the character at offset, the first run if none is before it
+func (s *BlockSequence) runOffset(offsets []int, offset int) int {
+	i := sort.SearchInts(offsets, offset+1) - 1
+	if i < 0 {
+		return 0
+	}
+	return i
+}
+
+// offset of the first run of block after offset
+func (s *BlockSequence) nextOffset(block BlockID, offset int) (int, bool) {
+	offsets, _ := s.blocks.Get(block)
+	i := sort.SearchInts(offsets, offset+1)
+	if i == len(offsets) {
+		return 0, false
+	}
+	return offsets[i], true
+}
+
+// splits the run at index i before its k-th character
+func (s *BlockSequence) split(i int, k int) {
+	r := s.runs.at(i)
+	if k <= 0 || k >= len(r.text) {
+		return
+	}
+	s.runs.set(i, run{block: r.block, offset: r.offset, text: r.text[:k:k], deleted: r.deleted})
+	s.runs.insert(i+1, run{block: r.block, offset: r.offset + k, text: r.text[k:], deleted: r.deleted})
+	s.addOffset(r.block, r.offset+k)
+}
+
+// adds the offset of a run of block to the index
+func (s *BlockSequence) addOffset(block BlockID, offset int) {
+	offsets, _ := s.blocks.Get(block)
+	i := sort.SearchInts(offsets, offset)
+	s.blocks.Set(block, append(append(append(make([]int, 0, len(offsets)+1), offsets[:i]...), offset), offsets[i:]...))
+}
+
+// removes the offset of a run of block from the index
+func (s *BlockSequence) removeOffset(block BlockID, offset int) {
+	offsets, _ := s.blocks.Get(block)
+	i := sort.SearchInts(offsets, offset)
+	if len(offsets) == 1 {
+		s.blocks.Delete(block)
+		return
+	}
+	s.blocks.Set(block, append(append(make([]int, 0, len(offsets)-1), offsets[:i]...), offsets[i+1:]...))
+}
+
+// visible text of the sequence
+func (s *BlockSequence) String() string {
+	var b strings.Builder
+	walk(s.runs.root, func(n *labelledNode[run]) {
+		if !n.item.deleted {
+			b.WriteString(string(n.item.text))
+		}
+	})
+	return b.String()
+}
+
+// number of visible characters
+func (s *BlockSequence) Len() int {
+	return s.runs.totalWeight()
+}
+
+// number of runs, tombstones included
+func (s *BlockSequence) NumRuns() int {
+	return s.runs.len()
+}
+
+// identifier of the visible character at index, the start of the sequence if index is -1
+// inserts at position index go after CharAt(index - 1)
+func (s *BlockSequence) CharAt(index int) CharID {
+	if index < 0 {
+		return CharID{}
+	}
+	j, k := s.runs.atWeight(index)
+	if j == -1 {
+		panic("block sequence: index out of range")
+	}
+	r := s.runs.at(j)
+	return CharID{Block: r.block, Offset: r.offset + k}
+}
+
+// spans of the length visible characters starting at index
+func (s *BlockSequence) Spans(index int, length int) []CharSpan {
+	spans := []CharSpan{}
+	if length == 0 {
+		return spans
+	}
+	j, k := s.runs.atWeight(index)
+	if j == -1 {
+		return spans
+	}
+	for ; j < s.runs.len() && length > 0; j, k = j+1, 0 {
+		r := s.runs.at(j)
+		if r.deleted {
+			continue
+		}
+		n := len(r.text) - k
+		if n > length {
+			n = length
+		}
+		spans = append(spans, CharSpan{Block: r.block, Offset: r.offset + k, Length: n})
+		length -= n
+	}
+	return spans
+}
+
+// check if two sequences have the same visible characters with the same identifiers, however their blocks are split
+// and whichever tombstones they kept
+func (s *BlockSequence) Equal(other *BlockSequence) bool {
+	c1, c2 := s.chars(), other.chars()
+	if len(c1) != len(c2) {
+		return false
+	}
+	for i := range c1 {
+		if c1[i] != c2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// a character of the sequence
+type blockChar struct {
+	id    CharID
+	value rune
+}
+
+// visible characters of the sequence
+func (s *BlockSequence) chars() []blockChar {
+	chars := []blockChar{}
+	walk(s.runs.root, func(n *labelledNode[run]) {
+		if n.item.deleted {
+			return
+		}
+		for k, c := range n.item.text {
+			chars = append(chars, blockChar{id: CharID{Block: n.item.block, Offset: n.item.offset + k}, value: c})
+		}
+	})
+	return chars
+}
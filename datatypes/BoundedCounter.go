@@ -0,0 +1,123 @@
+package datatypes
+
+import "fmt"
+
+// TransferValue is the value of an operation giving N rights of the replica preparing it to replica To
+type TransferValue struct {
+	To string
+	N  int
+}
+
+// RightsError is an operation of a bounded counter that needs more rights than its replica holds
+type RightsError struct {
+	Replica  string
+	Rights   int // rights held by the replica
+	Required int // rights used by the operation
+}
+
+func (e *RightsError) Error() string {
+	return fmt.Sprintf("bounded counter: replica %s holds %d rights, %d required", e.Replica, e.Rights, e.Required)
+}
+
+// BoundedCounter is the state of a counter that never goes below zero, each replica holds rights to decrement it
+// increments create rights for their replica and transfers move rights between replicas,
+// so as long as replicas only use the rights they hold the sum of the rights is the value of the counter
+// methods return a new counter, so states given to the engines are never changed
+type BoundedCounter struct {
+	given      map[string]map[string]int // rights given by a replica to another one, increments give rights of a replica to itself
+	decrements map[string]int            // rights used by the decrements of a replica
+}
+
+// returns a counter with value zero
+func NewBoundedCounter() BoundedCounter {
+	return BoundedCounter{given: map[string]map[string]int{}, decrements: map[string]int{}}
+}
+
+// copy of the counter whose maps can be changed
+func (c BoundedCounter) clone() BoundedCounter {
+	given := make(map[string]map[string]int, len(c.given))
+	for from, to := range c.given {
+		given[from] = make(map[string]int, len(to))
+		for id, n := range to {
+			given[from][id] = n
+		}
+	}
+	decrements := make(map[string]int, len(c.decrements))
+	for id, n := range c.decrements {
+		decrements[id] = n
+	}
+	return BoundedCounter{given: given, decrements: decrements}
+}
+
+// counter incremented by n, the rights are held by replica id
+func (c BoundedCounter) Increment(id string, n int) BoundedCounter {
+	return c.Transfer(id, id, n)
+}
+
+// counter decremented by n using rights of replica id
+func (c BoundedCounter) Decrement(id string, n int) BoundedCounter {
+	st := c.clone()
+	st.decrements[id] += n
+	return st
+}
+
+// counter where replica from gave n rights to replica to
+func (c BoundedCounter) Transfer(from string, to string, n int) BoundedCounter {
+	st := c.clone()
+	if st.given[from] == nil {
+		st.given[from] = map[string]int{}
+	}
+	st.given[from][to] += n
+	return st
+}
+
+// value of the counter, increments minus decrements
+func (c BoundedCounter) Value() int {
+	value := 0
+	for id, to := range c.given {
+		value += to[id]
+	}
+	for _, n := range c.decrements {
+		value -= n
+	}
+	return value
+}
+
+// rights held by replica id, received minus given and used
+func (c BoundedCounter) Rights(id string) int {
+	rights := -c.decrements[id]
+	for from, to := range c.given {
+		rights += to[id]
+		if from != id {
+			continue
+		}
+		for other, n := range to {
+			if other != id {
+				rights -= n
+			}
+		}
+	}
+	return rights
+}
+
+// replicas with rights given or received
+func (c BoundedCounter) Replicas() []string {
+	seen := map[string]bool{}
+	ids := []string{}
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for from, to := range c.given {
+		add(from)
+		for id := range to {
+			add(id)
+		}
+	}
+	for id := range c.decrements {
+		add(id)
+	}
+	return ids
+}
@@ -0,0 +1,92 @@
+package datatypes
+
+import (
+	"library/packages/communication"
+	"library/packages/datatypes/persistent"
+)
+
+// SetDelta is the change between two states of a set
+type SetDelta struct {
+	Added   []any
+	Removed []any
+}
+
+// returns the elements added and removed from `from` to `to`
+func NewSetDelta(from *persistent.Set[any], to *persistent.Set[any]) SetDelta {
+	d := SetDelta{Added: []any{}, Removed: []any{}}
+	to.Each(func(e any) bool {
+		if !from.Contains(e) {
+			d.Added = append(d.Added, e)
+		}
+		return true
+	})
+	from.Each(func(e any) bool {
+		if !to.Contains(e) {
+			d.Removed = append(d.Removed, e)
+		}
+		return true
+	})
+	return d
+}
+
+// SequenceRange is a run of consecutive visible vertices of a sequence starting at Index
+type SequenceRange struct {
+	Index    int
+	Vertices []Vertex
+}
+
+// SequenceDelta is the change between two states of a sequence
+// inserted ranges are indexed in the visible vertices of the new state, deleted ranges in the visible vertices of the old state
+type SequenceDelta struct {
+	Inserted []SequenceRange
+	Deleted  []SequenceRange
+}
+
+// returns the ranges inserted and deleted from `from` to `to`, tombstones are not visible
+func NewSequenceDelta(from []Vertex, to []Vertex) SequenceDelta {
+	from, to = visible(from), visible(to)
+	return SequenceDelta{Inserted: missingRanges(to, vertexIDs(from)), Deleted: missingRanges(from, vertexIDs(to))}
+}
+
+// vertices that are not tombstones
+func visible(vertices []Vertex) []Vertex {
+	v := []Vertex{}
+	for _, vertex := range vertices {
+		if vertex.Value != nil {
+			v = append(v, vertex)
+		}
+	}
+	return v
+}
+
+// identifiers of the vertices, vertices are identified by the operation that inserted them
+func vertexIDs(vertices []Vertex) map[string]bool {
+	ids := make(map[string]bool, len(vertices))
+	for _, v := range vertices {
+		ids[vertexKey(v)] = true
+	}
+	return ids
+}
+
+func vertexKey(v Vertex) string {
+	if v.Timestamp == nil {
+		return v.OriginID
+	}
+	return v.Timestamp.(communication.VClock).ReturnVCString() + v.OriginID
+}
+
+// runs of consecutive vertices whose identifier is not in ids
+func missingRanges(vertices []Vertex, ids map[string]bool) []SequenceRange {
+	ranges := []SequenceRange{}
+	for i, v := range vertices {
+		if ids[vertexKey(v)] {
+			continue
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].Index+len(ranges[n-1].Vertices) == i {
+			ranges[n-1].Vertices = append(ranges[n-1].Vertices, v)
+		} else {
+			ranges = append(ranges, SequenceRange{Index: i, Vertices: []Vertex{v}})
+		}
+	}
+	return ranges
+}
@@ -0,0 +1,123 @@
+package datatypes
+
+import (
+	"library/packages/communication"
+	"library/packages/datatypes/persistent"
+)
+
+// Stamp orders the writes of last-writer-wins datatypes by the sum of the version of the write and then its origin
+// a write has a greater sum than the writes it has seen, so it always wins over them, and the zero Stamp is older than every write
+type Stamp struct {
+	Sum      uint64
+	OriginID string
+}
+
+// returns the stamp of the write with the given version and origin
+func NewStamp(version communication.VClock, originID string) Stamp {
+	return Stamp{Sum: version.Sum(), OriginID: originID}
+}
+
+// check if the write of the stamp is older than the write of other
+func (s Stamp) Less(other Stamp) bool {
+	return s.Sum < other.Sum || s.Sum == other.Sum && s.OriginID < other.OriginID
+}
+
+// LWWRegister is the state of a last-writer-wins register, the value of the newest write and its stamp
+type LWWRegister struct {
+	Value any
+	Stamp Stamp
+}
+
+// LWWOpValue is the value of an operation on a last-writer-wins map, removes only use Key
+type LWWOpValue struct {
+	Key   string
+	Value any
+}
+
+// value of a key of a last-writer-wins map, removed keys are kept so older concurrent sets do not add them again
+type lwwEntry struct {
+	value   any
+	stamp   Stamp
+	removed bool
+}
+
+// LWWMap is the state of a last-writer-wins element map, every key has the value of its newest set or remove
+type LWWMap struct {
+	entries *persistent.Map[string, lwwEntry]
+}
+
+// returns an empty map
+func NewLWWMap() *LWWMap {
+	return &LWWMap{entries: persistent.NewMap[string, lwwEntry]()}
+}
+
+// copy of the map, O(1)
+func (m *LWWMap) Copy() *LWWMap {
+	return &LWWMap{entries: m.entries.Clone()}
+}
+
+// sets key to value if stamp is newer than the last set or remove of key
+func (m *LWWMap) Set(key string, value any, stamp Stamp) {
+	if e, ok := m.entries.Get(key); !ok || e.stamp.Less(stamp) {
+		m.entries.Set(key, lwwEntry{value: value, stamp: stamp})
+	}
+}
+
+// removes key if stamp is newer than the last set or remove of key
+func (m *LWWMap) Remove(key string, stamp Stamp) {
+	if e, ok := m.entries.Get(key); !ok || e.stamp.Less(stamp) {
+		m.entries.Set(key, lwwEntry{stamp: stamp, removed: true})
+	}
+}
+
+// forgets the stamp of the last write of key if it is stamp, every write delivered after a stable one is newer
+// a removed key is dropped, a set key keeps its value with the zero stamp
+func (m *LWWMap) Stabilize(key string, stamp Stamp) {
+	e, ok := m.entries.Get(key)
+	if !ok || e.stamp != stamp {
+		return
+	}
+	if e.removed {
+		m.entries.Delete(key)
+		return
+	}
+	e.stamp = Stamp{}
+	m.entries.Set(key, e)
+}
+
+// value of key, false if it was never set or is removed
+func (m *LWWMap) Get(key string) (any, bool) {
+	e, ok := m.entries.Get(key)
+	return e.value, ok && !e.removed
+}
+
+// values of the keys that are not removed
+func (m *LWWMap) Values() map[string]any {
+	values := map[string]any{}
+	m.entries.Range(func(key string, e lwwEntry) bool {
+		if !e.removed {
+			values[key] = e.value
+		}
+		return true
+	})
+	return values
+}
+
+// number of keys kept by the map, removed keys included until their remove is stable
+func (m *LWWMap) Entries() int {
+	return m.entries.Len()
+}
+
+// check if two maps have the same keys with the same values and stamps
+func (m *LWWMap) Equal(other *LWWMap) bool {
+	if m.entries.Len() != other.entries.Len() {
+		return false
+	}
+	equal := true
+	m.entries.Range(func(key string, e lwwEntry) bool {
+		o, ok := other.entries.Get(key)
+		equal = ok && o == e
+		return equal
+	})
+	return equal
+}
@@ -0,0 +1,67 @@
+package datatypes
+
+import (
+	"library/packages/communication"
+	"sort"
+)
+
+// MVWrite is a write of a multi-value register, Version is emptied once the write is stable
+type MVWrite[V any] struct {
+	Value   V
+	Version communication.VClock
+	Stamp   Stamp // orders the values of concurrent writes
+}
+
+// MVRegister is the state of a multi-value register, the writes that no other delivered write has seen
+// methods return a new register, so states given to the engines are never changed
+type MVRegister[V any] struct {
+	writes []MVWrite[V]
+}
+
+// returns a register without writes
+func NewMVRegister[V any]() MVRegister[V] {
+	return MVRegister[V]{writes: []MVWrite[V]{}}
+}
+
+// register with the write of value, the writes seen by it are pruned
+// a stable write has an empty version, every write delivered after it has seen it
+func (r MVRegister[V]) Write(value V, version communication.VClock, originID string) MVRegister[V] {
+	writes := make([]MVWrite[V], 0, len(r.writes)+1)
+	for _, w := range r.writes {
+		if version.Descends(w.Version) {
+			continue
+		}
+		writes = append(writes, w)
+	}
+	writes = append(writes, MVWrite[V]{Value: value, Version: version, Stamp: NewStamp(version, originID)})
+	sort.Slice(writes, func(i, j int) bool { return writes[i].Stamp.Less(writes[j].Stamp) })
+	return MVRegister[V]{writes: writes}
+}
+
+// register where the write with stamp has an empty version, so the next write prunes it
+func (r MVRegister[V]) Stabilize(stamp Stamp) MVRegister[V] {
+	writes := make([]MVWrite[V], len(r.writes))
+	copy(writes, r.writes)
+	for i, w := range writes {
+		if w.Stamp == stamp {
+			writes[i].Version = communication.NewVClock()
+		}
+	}
+	return MVRegister[V]{writes: writes}
+}
+
+// values of the concurrent writes, ordered by their stamps
+func (r MVRegister[V]) Values() []V {
+	values := make([]V, 0, len(r.writes))
+	for _, w := range r.writes {
+		values = append(values, w.Value)
+	}
+	return values
+}
+
+// writes kept by the register
+func (r MVRegister[V]) Writes() []MVWrite[V] {
+	writes := make([]MVWrite[V], len(r.writes))
+	copy(writes, r.writes)
+	return writes
+}
@@ -0,0 +1,175 @@
+package datatypes
+
+import (
+	"library/packages/datatypes/persistent"
+)
+
+// MoveOpValue is the value of an operation on a list whose elements can be moved
+// an insert adds Value after the position V, a move places Element after the position V,
+// a set replaces the value of Element and a remove deletes Element
+type MoveOpValue struct {
+	Element Vertex
+	V       Vertex
+	Value   any
+}
+
+// ListItem is a visible element of a MoveList
+type ListItem struct {
+	Element  Vertex // vertex of the insert of the element
+	Position Vertex // vertex of the current position of the element, inserts and moves go after positions
+	Value    any
+}
+
+// an element of a MoveList, the winning position and value are the ones of the greatest operation
+type listElement struct {
+	value     any
+	valueID   vertexID
+	position  Vertex
+	positions []Vertex // positions of the element in the sequence, never modified after being set so copies of the list share them
+	removed   bool
+}
+
+// MoveList is the state of an RGA whose elements can be moved
+// every insert and move adds a position to the sequence, only the position of the greatest insert or move of an element is visible,
+// so concurrent moves of an element end in one position and the element keeps its identity and value
+type MoveList struct {
+	positions *Sequence // the value of a position is the vertex of its element
+	elements  *persistent.Map[vertexID, listElement]
+}
+
+// returns a list without elements, root is the position before the first element
+func NewMoveList(root Vertex) *MoveList {
+	return &MoveList{positions: NewSequence(root), elements: persistent.NewMapWithHash[vertexID, listElement](hashID)}
+}
+
+// copy of the list, O(1)
+func (l *MoveList) Copy() *MoveList {
+	return &MoveList{positions: l.positions.Copy(), elements: l.elements.Clone()}
+}
+
+// inserts the element with value after the position prev, or after the root if prev is not found
+func (l *MoveList) Insert(prev Vertex, element Vertex, value any) {
+	l.addPosition(prev, Vertex{Timestamp: element.Timestamp, Value: element, OriginID: element.OriginID})
+	l.elements.Set(idOf(element), listElement{value: value, valueID: idOf(element), position: element, positions: []Vertex{element}})
+}
+
+// adds position after prev and makes it the position of element if it is greater than the current one
+func (l *MoveList) Move(prev Vertex, element Vertex, position Vertex) {
+	e, ok := l.elements.Get(idOf(element))
+	if !ok {
+		return
+	}
+	l.addPosition(prev, Vertex{Timestamp: position.Timestamp, Value: element, OriginID: position.OriginID})
+	e.positions = append(append([]Vertex{}, e.positions...), position)
+	if idOf(e.position).less(idOf(position)) {
+		e.position = position
+	}
+	l.elements.Set(idOf(element), e)
+}
+
+// replaces the value of element if id is greater than the operation that set the current one
+func (l *MoveList) Set(element Vertex, value any, id Vertex) {
+	e, ok := l.elements.Get(idOf(element))
+	if !ok || !e.valueID.less(idOf(id)) {
+		return
+	}
+	e.value, e.valueID = value, idOf(id)
+	l.elements.Set(idOf(element), e)
+}
+
+// removes element, its positions are kept so inserts and moves after them keep their place
+func (l *MoveList) Remove(element Vertex) {
+	e, ok := l.elements.Get(idOf(element))
+	if !ok {
+		return
+	}
+	e.removed = true
+	l.elements.Set(idOf(element), e)
+}
+
+// drops the positions of element that are not its current one, all of them if it is removed, except the ones in keep,
+// an element removed without positions left is dropped too unless it is in keep, returns false if nothing was dropped
+func (l *MoveList) Collect(element Vertex, keep []Vertex) bool {
+	e, ok := l.elements.Get(idOf(element))
+	if !ok {
+		return false
+	}
+	kept := map[vertexID]bool{}
+	for _, v := range keep {
+		kept[idOf(v)] = true
+	}
+
+	positions := []Vertex{}
+	for _, p := range e.positions {
+		if (!e.removed && idOf(p) == idOf(e.position)) || kept[idOf(p)] {
+			positions = append(positions, p)
+		} else if i := l.positions.IndexOf(p); i != -1 {
+			l.positions.Remove(i)
+		}
+	}
+	if len(positions) == len(e.positions) {
+		return false
+	}
+	if e.removed && len(positions) == 0 && !kept[idOf(element)] {
+		l.elements.Delete(idOf(element))
+		return true
+	}
+	e.positions = positions
+	l.elements.Set(idOf(element), e)
+	return true
+}
+
+// inserts a position right after prev, concurrent positions after prev are ordered by the engine
+func (l *MoveList) addPosition(prev Vertex, position Vertex) {
+	i := l.positions.IndexOf(prev)
+	if i == -1 {
+		i = 0
+	}
+	l.positions.Insert(i+1, position)
+}
+
+// visible elements in list order
+func (l *MoveList) Items() []ListItem {
+	items := []ListItem{}
+	for _, p := range l.positions.Vertices() {
+		element, ok := p.Value.(Vertex)
+		if !ok {
+			continue
+		}
+		e, _ := l.elements.Get(idOf(element))
+		if !e.removed && idOf(e.position) == idOf(p) {
+			items = append(items, ListItem{Element: element, Position: e.position, Value: e.value})
+		}
+	}
+	return items
+}
+
+// root and positions of all inserts and moves
+func (l *MoveList) Positions() []Vertex {
+	return l.positions.Vertices()
+}
+
+// check if two lists have the same positions and visible elements
+func (l *MoveList) Equal(other *MoveList) bool {
+	p1, p2 := l.Positions(), other.Positions()
+	i1, i2 := l.Items(), other.Items()
+	if len(p1) != len(p2) || len(i1) != len(i2) {
+		return false
+	}
+	for i := range p1 {
+		if idOf(p1[i]) != idOf(p2[i]) {
+			return false
+		}
+	}
+	for i := range i1 {
+		if idOf(i1[i].Element) != idOf(i2[i].Element) || idOf(i1[i].Position) != idOf(i2[i].Position) || i1[i].Value != i2[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+// check if the vertex was created before other in the arbitration order
+func (id vertexID) less(other vertexID) bool {
+	return id.sum < other.sum || id.sum == other.sum && id.originID < other.originID
+}
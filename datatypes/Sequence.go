@@ -0,0 +1,90 @@
+package datatypes
+
+import (
+	"library/packages/communication"
+	"library/packages/datatypes/persistent"
+	"strconv"
+)
+
+// identifier of a vertex, the sum of its timestamp and the replica that created it
+// the root vertex of every replica has an empty timestamp and the same identifier
+type vertexID struct {
+	sum      uint64
+	originID string
+}
+
+func idOf(v Vertex) vertexID {
+	if v.Timestamp == nil {
+		return vertexID{}
+	}
+	sum := v.Timestamp.(communication.VClock).Sum()
+	if sum == 0 {
+		return vertexID{}
+	}
+	return vertexID{sum, v.OriginID}
+}
+
+func hashID(id vertexID) uint64 {
+	return persistent.HashString(strconv.FormatUint(id.sum, 10) + id.originID)
+}
+
+// Sequence of vertices shared by the RGA datatypes
+// vertices are kept in a labelled tree indexed by their identifiers,
+// so inserting, removing and finding the position of a vertex are O(log n) and copies of the sequence are O(1)
+type Sequence struct {
+	vertices labelled[vertexID, Vertex]
+}
+
+// returns a new sequence with the given vertices
+func NewSequence(vertices ...Vertex) *Sequence {
+	s := &Sequence{vertices: newLabelled[vertexID, Vertex](idOf, hashID, nil)}
+	for i, v := range vertices {
+		s.Insert(i, v)
+	}
+	return s
+}
+
+// number of vertices in the sequence
+func (s *Sequence) Len() int {
+	return s.vertices.len()
+}
+
+// returns the vertex at position i
+func (s *Sequence) At(i int) Vertex {
+	return s.vertices.at(i)
+}
+
+// returns the position of a vertex, -1 if it is not in the sequence
+func (s *Sequence) IndexOf(v Vertex) int {
+	return s.vertices.indexOf(idOf(v))
+}
+
+// check if the sequence has a vertex
+func (s *Sequence) Contains(v Vertex) bool {
+	return s.vertices.contains(idOf(v))
+}
+
+// inserts a vertex at position i
+func (s *Sequence) Insert(i int, v Vertex) {
+	s.vertices.insert(i, v)
+}
+
+// removes the vertex at position i
+func (s *Sequence) Remove(i int) {
+	s.vertices.remove(i)
+}
+
+// replaces the vertex at position i, the identifier of the vertex must not change
+func (s *Sequence) Set(i int, v Vertex) {
+	s.vertices.set(i, v)
+}
+
+// returns the vertices of the sequence in order
+func (s *Sequence) Vertices() []Vertex {
+	return s.vertices.items()
+}
+
+// copy of the sequence, O(1) since nodes are never modified
+func (s *Sequence) Copy() *Sequence {
+	return &Sequence{vertices: s.vertices.copy()}
+}
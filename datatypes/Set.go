@@ -0,0 +1,9 @@
+package datatypes
+
+// Bias is the operation that wins between concurrent adds and removes of the same element of a set
+type Bias int
+
+const (
+	AddBias    Bias = iota // the element is in the set, as in the add-wins sets
+	RemoveBias             // the element is not in the set, as in the remove-wins sets
+)
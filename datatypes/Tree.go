@@ -0,0 +1,162 @@
+package datatypes
+
+import (
+	"library/packages/communication"
+	"library/packages/datatypes/persistent"
+	"sort"
+)
+
+// NodeID identifies a node of a tree by the operation that created it
+type NodeID struct {
+	Sum      uint64 // sum of the version of the operation
+	OriginID string
+}
+
+// the root and the trash are not created by operations, deleted nodes are moved under the trash
+var (
+	RootNode  = NodeID{}
+	TrashNode = NodeID{OriginID: "trash"}
+)
+
+// returns the identifier of the node created by the operation with the given version and origin
+func NewNodeID(version communication.VClock, originID string) NodeID {
+	return NodeID{Sum: version.Sum(), OriginID: originID}
+}
+
+// check if the node was created before other in the arbitration order
+func (id NodeID) Less(other NodeID) bool {
+	return id.Sum < other.Sum || id.Sum == other.Sum && id.OriginID < other.OriginID
+}
+
+// TreeOpValue is the value of an operation on a tree
+// a create adds a node with Value under Parent, a move places Node under Parent and a delete moves Node under the trash
+type TreeOpValue struct {
+	Node   NodeID
+	Parent NodeID
+	Value  any
+}
+
+type treeNode struct {
+	parent NodeID
+	value  any
+}
+
+// Tree is the state of a tree whose nodes can be moved under new parents
+// moves that would make a node its own ancestor are ignored, so the nodes always form a tree under the root or the trash
+type Tree struct {
+	nodes *persistent.Map[NodeID, treeNode]
+}
+
+// returns a tree with only the root
+func NewTree() *Tree {
+	return &Tree{nodes: persistent.NewMap[NodeID, treeNode]()}
+}
+
+// copy of the tree, O(1)
+func (t *Tree) Copy() *Tree {
+	return &Tree{nodes: t.nodes.Clone()}
+}
+
+// adds node with value under parent
+func (t *Tree) Create(node NodeID, parent NodeID, value any) {
+	t.nodes.Set(node, treeNode{parent: parent, value: value})
+}
+
+// places node under parent, returns false if the move is ignored
+// the move is ignored if node is the root, the trash or deleted, if parent does not exist, or if parent is node or one of its descendants
+func (t *Tree) Move(node NodeID, parent NodeID) bool {
+	n, ok := t.nodes.Get(node)
+	if !ok || n.parent == TrashNode || !t.exists(parent) || t.isAncestor(node, parent) {
+		return false
+	}
+	n.parent = parent
+	t.nodes.Set(node, n)
+	return true
+}
+
+// moves node under the trash, later moves of a deleted node are ignored
+func (t *Tree) Delete(node NodeID) {
+	n, ok := t.nodes.Get(node)
+	if !ok {
+		return
+	}
+	n.parent = TrashNode
+	t.nodes.Set(node, n)
+}
+
+// check if node is the root, the trash or a created node
+func (t *Tree) exists(node NodeID) bool {
+	return node == RootNode || node == TrashNode || t.nodes.Contains(node)
+}
+
+// check if ancestor is node or one of the nodes above it
+func (t *Tree) isAncestor(ancestor NodeID, node NodeID) bool {
+	for {
+		if node == ancestor {
+			return true
+		}
+		n, ok := t.nodes.Get(node)
+		if !ok {
+			return false
+		}
+		node = n.parent
+	}
+}
+
+// parent of the node, false if the node is the root, the trash or was not created
+func (t *Tree) Parent(node NodeID) (NodeID, bool) {
+	n, ok := t.nodes.Get(node)
+	return n.parent, ok
+}
+
+// value of the node, false if the node is the root, the trash or was not created
+func (t *Tree) Value(node NodeID) (any, bool) {
+	n, ok := t.nodes.Get(node)
+	return n.value, ok
+}
+
+// check if the node is under the root, nodes under the trash are deleted
+func (t *Tree) Contains(node NodeID) bool {
+	return node != RootNode && t.isAncestor(RootNode, node)
+}
+
+// nodes whose parent is the given node, in the arbitration order of their creation
+func (t *Tree) Children(parent NodeID) []NodeID {
+	children := []NodeID{}
+	t.nodes.Range(func(k NodeID, v treeNode) bool {
+		if v.parent == parent {
+			children = append(children, k)
+		}
+		return true
+	})
+	sort.Slice(children, func(i, j int) bool { return children[i].Less(children[j]) })
+	return children
+}
+
+// nodes under the root in depth-first order
+func (t *Tree) Nodes() []NodeID {
+	nodes := []NodeID{}
+	var walk func(parent NodeID)
+	walk = func(parent NodeID) {
+		for _, child := range t.Children(parent) {
+			nodes = append(nodes, child)
+			walk(child)
+		}
+	}
+	walk(RootNode)
+	return nodes
+}
+
+// check if two trees have the same nodes with the same parents and values
+func (t *Tree) Equal(other *Tree) bool {
+	if t.nodes.Len() != other.nodes.Len() {
+		return false
+	}
+	equal := true
+	t.nodes.Range(func(k NodeID, v treeNode) bool {
+		o, ok := other.nodes.Get(k)
+		equal = ok && o.parent == v.parent && o.value == v.value
+		return equal
+	})
+	return equal
+}
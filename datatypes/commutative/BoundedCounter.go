@@ -0,0 +1,71 @@
+package datatypes
+
+import (
+	"fmt"
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	"library/packages/replica"
+)
+
+// BoundedCounter is a counter that never goes below zero without coordinating decrements
+// "Inc" and "Dec" take a positive int, "Transfer" a datatypes.TransferValue,
+// decrements and transfers use rights of their replica and are rejected at Prepare if it does not hold them
+type BoundedCounter struct{}
+
+func (c BoundedCounter) Apply(state any, operations []communication.Operation) any {
+	st := state.(datatypes.BoundedCounter)
+	for _, op := range operations {
+		switch op.Type {
+		case "Inc":
+			st = st.Increment(op.OriginID, op.Value.(int))
+		case "Dec":
+			st = st.Decrement(op.OriginID, op.Value.(int))
+		case "Transfer":
+			t := op.Value.(datatypes.TransferValue)
+			st = st.Transfer(op.OriginID, t.To, t.N)
+		}
+	}
+	return st
+}
+
+func (c BoundedCounter) Query(state any) any {
+	return state.(datatypes.BoundedCounter).Value()
+}
+
+// decrements and transfers need as many rights as they use, rights held by a replica only decrease by its own operations
+func (c BoundedCounter) Check(state any, op communication.Operation) error {
+	n, ok := 0, false
+	switch op.Type {
+	case "Inc", "Dec":
+		n, ok = op.Value.(int)
+	case "Transfer":
+		var t datatypes.TransferValue
+		t, ok = op.Value.(datatypes.TransferValue)
+		n = t.N
+	default:
+		return fmt.Errorf("bounded counter: unknown operation %s", op.Type)
+	}
+	if !ok {
+		return fmt.Errorf("bounded counter: %s of %T", op.Type, op.Value)
+	}
+	if n <= 0 {
+		return fmt.Errorf("bounded counter: %s of %d is not positive", op.Type, n)
+	}
+	// rights given by a replica to itself would be an increment that is not counted as one
+	if op.Type == "Transfer" && op.Value.(datatypes.TransferValue).To == op.OriginID {
+		return fmt.Errorf("bounded counter: transfer of replica %s to itself", op.OriginID)
+	}
+	if rights := state.(datatypes.BoundedCounter).Rights(op.OriginID); op.Type != "Inc" && rights < n {
+		return &datatypes.RightsError{Replica: op.OriginID, Rights: rights, Required: n}
+	}
+	return nil
+}
+
+// initialize bounded counter replica, TryPrepare returns why an operation is rejected
+func NewBoundedCounterReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
+
+	c := crdt.CommutativeCRDT{Data: BoundedCounter{}, Stable_st: datatypes.NewBoundedCounter()}
+
+	return replica.NewReplica(id, &c, channels, delay)
+}
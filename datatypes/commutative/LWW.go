@@ -0,0 +1,102 @@
+package datatypes
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	"library/packages/replica"
+)
+
+// LWWRegister is a register whose concurrent writes are resolved by their stamps, so it holds a single value
+type LWWRegister struct{}
+
+func (r LWWRegister) Apply(state any, operations []communication.Operation) any {
+	st := state.(datatypes.LWWRegister)
+	for _, op := range operations {
+		if stamp := datatypes.NewStamp(op.Version, op.OriginID); op.Type == "Write" && st.Stamp.Less(stamp) {
+			st = datatypes.LWWRegister{Value: op.Value, Stamp: stamp}
+		}
+	}
+	return st
+}
+
+func (r LWWRegister) Query(state any) any {
+	return state.(datatypes.LWWRegister).Value
+}
+
+// StableLWWRegister is an LWWRegister that forgets the stamp of its value once the write is stable
+type StableLWWRegister struct {
+	LWWRegister
+}
+
+func (r StableLWWRegister) Stabilize(state any, op communication.Operation) any {
+	st := state.(datatypes.LWWRegister)
+	if st.Stamp == datatypes.NewStamp(op.Version, op.OriginID) {
+		st.Stamp = datatypes.Stamp{}
+	}
+	return st
+}
+
+// LWWMap is a map whose keys have the value of their newest set or remove
+type LWWMap struct{}
+
+func (m LWWMap) Apply(state any, operations []communication.Operation) any {
+	st := state.(*datatypes.LWWMap).Copy()
+	for _, op := range operations {
+		value := op.Value.(datatypes.LWWOpValue)
+		switch op.Type {
+		case "Set":
+			st.Set(value.Key, value.Value, datatypes.NewStamp(op.Version, op.OriginID))
+		case "Rem":
+			st.Remove(value.Key, datatypes.NewStamp(op.Version, op.OriginID))
+		}
+	}
+	return st
+}
+
+func (m LWWMap) Query(state any) any {
+	return state.(*datatypes.LWWMap).Values()
+}
+
+// StableLWWMap is an LWWMap that forgets the stamps of stable writes and drops keys whose remove is stable
+type StableLWWMap struct {
+	LWWMap
+}
+
+func (m StableLWWMap) Stabilize(state any, op communication.Operation) any {
+	st := state.(*datatypes.LWWMap).Copy()
+	st.Stabilize(op.Value.(datatypes.LWWOpValue).Key, datatypes.NewStamp(op.Version, op.OriginID))
+	return st
+}
+
+// initialize last-writer-wins register replica
+func NewLWWRegisterReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
+
+	c := crdt.CommutativeCRDT{Data: LWWRegister{}, Stable_st: datatypes.LWWRegister{}}
+
+	return replica.NewReplica(id, &c, channels, delay)
+}
+
+// initialize last-writer-wins register replica that forgets stable stamps
+func NewStableLWWRegisterReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
+
+	c := crdt.CommutativeStableCRDT{Data: StableLWWRegister{}, Stable_st: datatypes.LWWRegister{}}
+
+	return replica.NewReplica(id, &c, channels, delay)
+}
+
+// initialize last-writer-wins map replica
+func NewLWWMapReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
+
+	c := crdt.CommutativeCRDT{Data: LWWMap{}, Stable_st: datatypes.NewLWWMap()}
+
+	return replica.NewReplica(id, &c, channels, delay)
+}
+
+// initialize last-writer-wins map replica that drops the metadata of stable writes
+func NewStableLWWMapReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
+
+	c := crdt.CommutativeStableCRDT{Data: StableLWWMap{}, Stable_st: datatypes.NewLWWMap()}
+
+	return replica.NewReplica(id, &c, channels, delay)
+}
@@ -11,7 +11,7 @@ import (
 type RGA datatypes.RGA
 
 func (r *RGA) Apply(state any, operations []communication.Operation) any {
-	st := state.([]datatypes.Vertex)
+	st := state.(*datatypes.Sequence).Copy()
 	for _, op := range operations {
 		msg := op
 		switch msg.Type {
@@ -20,7 +20,7 @@ func (r *RGA) Apply(state any, operations []communication.Operation) any {
 			newVertexPrev := msg.Value.(datatypes.RGAOpValue).V
 
 			// find index where predecessor vertex can be found
-			predecessorIdx := indexOfVPtr(newVertexPrev, st)
+			predecessorIdx := st.IndexOf(newVertexPrev)
 
 			// if predecessor vertex is not found, insert on root
 			if predecessorIdx == -1 {
@@ -30,75 +30,103 @@ func (r *RGA) Apply(state any, operations []communication.Operation) any {
 			// adjust index where new vertex is to be inserted when concurrent insertions for the same predecessor occur
 			insertIdx := shift(predecessorIdx+1, newVertex, st)
 
-			newVertices := append(st[:insertIdx], append([]datatypes.Vertex{newVertex}, st[insertIdx:]...)...)
-
-			st = newVertices
+			st.Insert(insertIdx, newVertex)
 		case "Rem":
 			removeVertex := msg.Value.(datatypes.RGAOpValue).V
 			// find index where removed vertex can be found and clear its content to tombstone it
-			index := indexOfVPtr(removeVertex, st)
+			index := st.IndexOf(removeVertex)
 			if index == -1 {
 				continue
 			}
-			st[index] = datatypes.Vertex{st[index].Timestamp, nil, st[index].OriginID}
+			tombstone := st.At(index)
+			st.Set(index, datatypes.Vertex{Timestamp: tombstone.Timestamp, Value: nil, OriginID: tombstone.OriginID})
 		}
 	}
 	return st
 }
 
+// value of the tombstone of a vertex whose remove is stable, kept until the vertex inserted after it is stable too
+type stableTombstone struct{}
+
 func (r RGA) Stabilize(state any, op communication.Operation) any {
-	//if operation is remove, remove the vertex from the state
-	if op.Type == "Rem" {
-		st := state.([]datatypes.Vertex)
-		removeVertex := op.Value.(datatypes.RGAOpValue).V
-		index := indexOfVPtr(removeVertex, st)
+	// the operations seen by a stable operation are stable
+	return r.StabilizeAt(state, op, op.Version)
+}
+
+// drops the tombstone of a stable remove once the vertex after it, if inserted after it, is stable.
+// Inserts that have seen both are greater than them, so they are placed before that vertex as they were before the tombstone,
+// while the inserts concurrent with an unstable vertex after the tombstone are still placed by comparing with the tombstone
+func (r RGA) StabilizeAt(state any, op communication.Operation, stable communication.VClock) any {
+	st := state.(*datatypes.Sequence)
+	switch op.Type {
+	case "Rem":
+		index := st.IndexOf(op.Value.(datatypes.RGAOpValue).V)
 		if index == -1 {
 			return state
 		}
-		st = append(st[:index], st[index+1:]...)
+		st = st.Copy()
+		if precedesUnstable(st, index, stable) {
+			tombstone := st.At(index)
+			st.Set(index, datatypes.Vertex{Timestamp: tombstone.Timestamp, Value: stableTombstone{}, OriginID: tombstone.OriginID})
+		} else {
+			st.Remove(index)
+		}
+		return st
+	case "Add":
+		// the vertex of a stable insert can be the one a stable tombstone before it waits for
+		index := st.IndexOf(datatypes.Vertex{Timestamp: op.Version, OriginID: op.OriginID})
+		if index < 1 {
+			return state
+		}
+		if _, ok := st.At(index - 1).Value.(stableTombstone); !ok || precedesUnstable(st, index-1, stable) {
+			return state
+		}
+		st = st.Copy()
+		st.Remove(index - 1)
 		return st
 	}
 	return state
 }
 
+// tells if the vertex after the one at index was inserted after it and is not stable
+func precedesUnstable(st *datatypes.Sequence, index int, stable communication.VClock) bool {
+	if index+1 >= st.Len() {
+		return false
+	}
+	next := st.At(index + 1)
+	timestamp := next.Timestamp.(communication.VClock)
+	return st.At(index).Timestamp.(communication.VClock).Compare(timestamp) == communication.Descendant && stable.FindTicks(next.OriginID) < timestamp.FindTicks(next.OriginID)
+}
+
 func (r RGA) Query(state any) any {
 	//removes tombstones
 	noTombs := []datatypes.Vertex{}
-	for i := 0; i < len(state.([]datatypes.Vertex)); i++ {
-		if state.([]datatypes.Vertex)[i].Value != nil {
-			noTombs = append(noTombs, state.([]datatypes.Vertex)[i])
+	for _, v := range state.(*datatypes.Sequence).Vertices() {
+		if _, stable := v.Value.(stableTombstone); v.Value != nil && !stable {
+			noTombs = append(noTombs, v)
 		}
 	}
 	return noTombs
 }
 
+// ranges inserted and deleted between two states
+func (r RGA) Delta(from any, to any) any {
+	return datatypes.NewSequenceDelta(from.([]datatypes.Vertex), to.([]datatypes.Vertex))
+}
+
 // initialize RGA
 func NewRGAReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
-	r := crdt.CommutativeStableCRDT{Data: &RGA{Id: id}, Stable_st: []datatypes.Vertex{{communication.NewVClockFromMap(map[string]uint64{}), "", id}}}
+	r := crdt.CommutativeStableCRDT{Data: &RGA{Id: id}, Stable_st: datatypes.NewSequence(datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: id})}
 
 	return replica.NewReplica(id, &r, channels, delay)
 }
 
-func indexOfVPtr(vertex datatypes.Vertex, vertices []datatypes.Vertex) int {
-	for i, v := range vertices {
-		if vertex.Timestamp == nil && v.Timestamp == nil || vertex.Timestamp == nil {
-			return 0
-		} else if v.Timestamp == nil {
-			continue
-		}
-		if vertex.Timestamp.(communication.VClock).Equal(v.Timestamp.(communication.VClock)) {
-			return i
-		}
-	}
-	return -1
-}
-
-func shift(offset int, newVertex datatypes.Vertex, vertices []datatypes.Vertex) int {
-	if offset >= len(vertices) {
+func shift(offset int, newVertex datatypes.Vertex, vertices *datatypes.Sequence) int {
+	if offset >= vertices.Len() {
 		return offset
 	}
 
-	at := vertices[offset]
+	at := vertices.At(offset)
 
 	id1, _ := strconv.Atoi(strconv.Itoa(int(at.Timestamp.(communication.VClock).Sum())) + at.OriginID)
 	id2, _ := strconv.Atoi(strconv.Itoa(int(newVertex.Timestamp.(communication.VClock).Sum())) + newVertex.OriginID)
@@ -11,7 +11,7 @@ import (
 type RGA datatypes.RGA
 
 func (r RGA) Apply(state any, operations []communication.Operation) any {
-	stCpy := RGACopy(state.([]datatypes.Vertex))
+	stCpy := state.(*datatypes.Sequence).Copy()
 
 	for _, op := range operations {
 		msg := op
@@ -21,25 +21,22 @@ func (r RGA) Apply(state any, operations []communication.Operation) any {
 			newVertexPrev := msg.Value.(datatypes.RGAOpValue).V
 
 			// find index where predecessor vertex can be found
-			predecessorIdx := indexOfVPtr(newVertexPrev, stCpy)
+			predecessorIdx := stCpy.IndexOf(newVertexPrev)
 
 			// if predecessor vertex is not found, insert on root
 			if predecessorIdx == -1 {
 				continue
 			}
 
-			newVertices := append(stCpy[:predecessorIdx+1], append([]datatypes.Vertex{newVertex}, stCpy[predecessorIdx+1:]...)...)
-
-			stCpy = newVertices
+			stCpy.Insert(predecessorIdx+1, newVertex)
 		case "Rem":
 			removeVertex := msg.Value.(datatypes.RGAOpValue).V
 			// find index where removed vertex can be found and clear its content to tombstone it
-			index := indexOfVPtr(removeVertex, stCpy)
+			index := stCpy.IndexOf(removeVertex)
 			if index == -1 {
 				continue
 			}
-			newVertices := append(stCpy[:index], stCpy[index+1:]...)
-			stCpy = newVertices
+			stCpy.Remove(index)
 		case "Nop":
 			continue
 		}
@@ -47,6 +44,15 @@ func (r RGA) Apply(state any, operations []communication.Operation) any {
 	return stCpy
 }
 
+func (r RGA) Query(state any) any {
+	return state.(*datatypes.Sequence).Vertices()
+}
+
+// ranges inserted and deleted between two states
+func (r RGA) Delta(from any, to any) any {
+	return datatypes.NewSequenceDelta(from.([]datatypes.Vertex), to.([]datatypes.Vertex))
+}
+
 func (r RGA) Order(op1 communication.Operation, op2 communication.Operation) bool {
 	id1, _ := strconv.Atoi(strconv.Itoa(int(op1.Version.Sum())) + op1.OriginID)
 	id2, _ := strconv.Atoi(strconv.Itoa(int(op2.Version.Sum())) + op2.OriginID)
@@ -107,7 +113,7 @@ func (r RGA) RepairRight(op1 communication.Operation, op2 communication.Operatio
 	if op2.Value == nil {
 		return op2
 	}
-	ef2 := r.effectivePos(op2.Value.(datatypes.RGAOpValue).V, state.([]datatypes.Vertex))
+	ef2 := r.effectivePos(op2.Value.(datatypes.RGAOpValue).V, state.(*datatypes.Sequence))
 	if ef2.Timestamp == nil {
 		return op2
 	}
@@ -161,15 +167,6 @@ func (r RGA) ECROOps() []string {
 	return []string{"Rem"}
 }
 
-func indexOfVPtr(vertex datatypes.Vertex, vertices []datatypes.Vertex) int {
-	for i, v := range vertices {
-		if vertex.Timestamp.(communication.VClock).Equal(v.Timestamp.(communication.VClock)) {
-			return i
-		}
-	}
-	return -1
-}
-
 // check if two array of vertices are equal
 func RGAEqual(vertices1 []datatypes.Vertex, vertices2 []datatypes.Vertex) bool {
 	if len(vertices1) != len(vertices2) {
@@ -189,22 +186,9 @@ func RGAEqual(vertices1 []datatypes.Vertex, vertices2 []datatypes.Vertex) bool {
 	return true
 }
 
-// deep copy state of RGA
-func RGACopy(state []datatypes.Vertex) []datatypes.Vertex {
-	stCpy := make([]datatypes.Vertex, len(state))
-	for i, v := range state {
-		stCpy[i].Value = v.Value
-		stCpy[i].Timestamp = v.Timestamp.(communication.VClock).Copy()
-		stCpy[i].OriginID = v.OriginID
-	}
-	return stCpy
-}
-
-func (r RGA) effectivePos(prevV datatypes.Vertex, state []datatypes.Vertex) datatypes.Vertex {
-	for _, v := range state {
-		if v.Timestamp.(communication.VClock).Equal(prevV.Timestamp.(communication.VClock)) {
-			return prevV
-		}
+func (r RGA) effectivePos(prevV datatypes.Vertex, state *datatypes.Sequence) datatypes.Vertex {
+	if state.Contains(prevV) {
+		return prevV
 	}
 	return datatypes.Vertex{}
 }
@@ -212,7 +196,7 @@ func (r RGA) effectivePos(prevV datatypes.Vertex, state []datatypes.Vertex) data
 // initialize RGA
 func NewRGAReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
 
-	r := crdt.NewSemidirectECRO(id, []datatypes.Vertex{{communication.NewVClockFromMap(map[string]uint64{}), "", id}}, &RGA{id})
+	r := crdt.NewSemidirectECRO(id, datatypes.NewSequence(datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: id}), &RGA{id})
 
 	return replica.NewReplica(id, r, channels, delay)
 }
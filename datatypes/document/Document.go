@@ -0,0 +1,437 @@
+package document
+
+import (
+	"encoding/json"
+	"fmt"
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/replica"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// kinds of the containers of a slot, a slot holds the value of a key of an object or of an element of a list
+const (
+	objectKind = "o" // ORMap of the slots of the keys of an object
+	listKind   = "l" // ORMap with the RGA of the elements of a list and the slots of its elements
+)
+
+// key of the register of a slot, a multi-value register with the concurrent scalars or references assigned to the slot
+// an assignment replaces the whole slot, so the containers of the values it overwrites are removed with them
+const registerKey = "v"
+
+// reference to a container of a slot, its operations are prepared after the assignment of the reference,
+// so a container is never rebuilt from part of its operations while it is referenced
+type ref struct {
+	Kind string
+	ID   string
+}
+
+// key of the container in its slot
+func (r ref) key() string {
+	return r.Kind + ":" + r.ID
+}
+
+// key of the RGA of the elements in the ORMap of a list
+const itemsKey = "items"
+
+// value of the vertices of the elements of a list, their slots are found by the identifier of the vertex
+const itemValue = "item"
+
+// builds the operation of the root object from an operation of a nested container
+type wrapper func(opType string, value any) (string, any)
+
+// wrapper of the operations of the container of kind in the slot key of the container of w
+func (w wrapper) into(key string, kind string) wrapper {
+	return func(opType string, value any) (string, any) {
+		t, v := crdt.MapUpdate(kind, opType, value)
+		t, v = crdt.MapUpdate(key, t, v)
+		return w(t, v)
+	}
+}
+
+// a key of an object or an index of a list in a path
+type segment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// container reached by a path, with its read and the wrapper of its operations
+type container struct {
+	kind string
+	read map[string]any
+	wrap wrapper
+}
+
+// Document is a JSON document edited by paths like "a.b[2].c" on top of nested ORMaps
+// objects are ORMaps of slots, lists are ORMaps with an RGA of their elements and slots hold multi-value registers,
+// so concurrent assignments of a key keep all their values and the one with the greatest stamp is picked
+type Document struct {
+	id       string
+	engine   *crdt.ORMap
+	replica  *replica.Replica
+	next     int         //number of containers created by the replica
+	editLock *sync.Mutex //orders the edits of clients of the replica
+}
+
+// returns an empty document of the replica id
+func NewDocument(id string) *Document {
+	d := &Document{id: id, editLock: new(sync.Mutex)}
+	d.engine = crdt.NewORMap(d.newSlot)
+	return d
+}
+
+// initialize a replica editing the document
+func NewDocumentReplica(id string, doc *Document, channels map[string]chan any, delay int) *replica.Replica {
+	r := replica.NewReplica(id, doc, channels, delay)
+	doc.replica = r
+	return r
+}
+
+// CRDT of a slot, its register and containers are created on their first operation
+func (d *Document) newSlot(key string) replica.CrdtI {
+	return crdt.NewORMap(func(key string) replica.CrdtI {
+		switch {
+		case strings.HasPrefix(key, objectKind+":"):
+			return crdt.NewORMap(d.newSlot)
+		case strings.HasPrefix(key, listKind+":"):
+			return crdt.NewORMap(d.newListEntry)
+		}
+		return &crdt.CommutativeStableCRDT{Data: ecro.MVRegister[any]{}, Stable_st: datatypes.NewMVRegister[any]()}
+	})
+}
+
+// RGA of the elements of a list or slot of one of its elements
+func (d *Document) newListEntry(key string) replica.CrdtI {
+	if key == itemsKey {
+		return crdt.NewEcroCRDT(d.id, datatypes.NewSequence(d.root()), ecro.RGA{Id: d.id})
+	}
+	return d.newSlot(key)
+}
+
+// root vertex of the RGAs of the lists
+func (d *Document) root() datatypes.Vertex {
+	return datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: d.id}
+}
+
+// sets the value at path, objects are created as map[string]any and lists as []any, other values are scalars
+// the last key of the path is added to its object, the last index of the path must be an element of its list
+func (d *Document) Set(path string, value any) error {
+	d.editLock.Lock()
+	defer d.editLock.Unlock()
+
+	c, last, err := d.resolve(path)
+	if err != nil {
+		return err
+	}
+	key, _, err := c.slot(path, last, false)
+	if err != nil {
+		return err
+	}
+	d.assign(c.wrap, key, value)
+	return nil
+}
+
+// inserts value in a list before the element at the last index of path, or at the end if the index is the length of the list
+func (d *Document) Insert(path string, value any) error {
+	d.editLock.Lock()
+	defer d.editLock.Unlock()
+
+	c, last, err := d.resolve(path)
+	if err != nil {
+		return err
+	}
+	if c.kind != listKind || !last.isIndex {
+		return fmt.Errorf("document: path %q is not an index of a list", path)
+	}
+	elements := listElements(c.read)
+	if last.index < 0 || last.index > len(elements) {
+		return fmt.Errorf("document: index of path %q is out of range", path)
+	}
+
+	prev := d.root()
+	if last.index > 0 {
+		prev = elements[last.index-1]
+	}
+	d.insert(c.wrap, prev, value)
+	return nil
+}
+
+// deletes the key or the element at path
+func (d *Document) Delete(path string) error {
+	d.editLock.Lock()
+	defer d.editLock.Unlock()
+
+	c, last, err := d.resolve(path)
+	if err != nil {
+		return err
+	}
+	key, vertex, err := c.slot(path, last, true)
+	if err != nil {
+		return err
+	}
+	if c.kind == listKind {
+		d.replica.Prepare(c.wrap(crdt.MapUpdate(itemsKey, "Rem", datatypes.RGAOpValue{V: vertex})))
+	}
+	d.replica.Prepare(c.wrap(crdt.MapRemove(key)))
+	return nil
+}
+
+// value at path
+func (d *Document) Get(path string) (any, error) {
+	slot, err := d.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	value, _ := slotValue(slot)
+	return value, nil
+}
+
+// concurrent values assigned to path, sorted so the one picked by Get is the last one
+func (d *Document) Conflicts(path string) ([]any, error) {
+	slot, err := d.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	values := []any{}
+	for _, v := range registerValues(slot) {
+		values = append(values, jsonValue(slot, v))
+	}
+	return values, nil
+}
+
+// document with all delivered operations in JSON
+func (d *Document) MarshalJSON() ([]byte, error) {
+	st, _ := d.Read(replica.Optimistic)
+	return json.Marshal(st)
+}
+
+func (d *Document) Effect(op communication.Operation) {
+	d.engine.Effect(op)
+}
+
+func (d *Document) Stabilize(op communication.Operation) {
+	d.engine.Stabilize(op)
+}
+
+// document at level as map[string]any, with lists as []any, and the version it reflects
+func (d *Document) Read(level replica.Level) (any, communication.VClock) {
+	st, version := d.engine.Read(level)
+	return objectValue(st.(map[string]any)), version
+}
+
+func (d *Document) NumOps() uint64 {
+	return d.engine.NumOps()
+}
+
+func (d *Document) NumSOps() uint64 {
+	return d.engine.NumSOps()
+}
+
+// replaces the slot key of the container of w with value, nested objects and lists are filled by more operations
+func (d *Document) assign(w wrapper, key string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		r := d.replace(w, key, objectKind)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			d.assign(w.into(key, r.key()), k, v[k])
+		}
+	case []any:
+		r := d.replace(w, key, listKind)
+		prev := d.root()
+		for _, e := range v {
+			prev = d.insert(w.into(key, r.key()), prev, e)
+		}
+	default:
+		t, opValue := crdt.MapUpdate(registerKey, "Write", v)
+		d.replica.Prepare(w(crdt.MapReplace(key, t, opValue)))
+	}
+}
+
+// replaces the slot key of the container of w with a reference to a new container of kind
+func (d *Document) replace(w wrapper, key string, kind string) ref {
+	d.next++
+	r := ref{Kind: kind, ID: d.id + "." + strconv.Itoa(d.next)}
+	t, opValue := crdt.MapUpdate(registerKey, "Write", r)
+	d.replica.Prepare(w(crdt.MapReplace(key, t, opValue)))
+	return r
+}
+
+// inserts value after prev in the list of w and returns the vertex of the new element
+func (d *Document) insert(w wrapper, prev datatypes.Vertex, value any) datatypes.Vertex {
+	op := d.replica.Prepare(w(crdt.MapUpdate(itemsKey, "Add", datatypes.RGAOpValue{Value: itemValue, V: prev})))
+	vertex := datatypes.Vertex{Timestamp: op.Version, Value: itemValue, OriginID: op.OriginID}
+	d.assign(w, elementKey(vertex), value)
+	return vertex
+}
+
+// read of the slot at path
+func (d *Document) lookup(path string) (map[string]any, error) {
+	c, last, err := d.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	key, _, err := c.slot(path, last, true)
+	if err != nil {
+		return nil, err
+	}
+	return c.read[key].(map[string]any), nil
+}
+
+// container of the last segment of path and the last segment
+func (d *Document) resolve(path string) (container, segment, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return container{}, segment{}, err
+	}
+	st, _ := d.engine.Read(replica.Optimistic)
+	c := container{kind: objectKind, read: st.(map[string]any), wrap: func(opType string, value any) (string, any) { return opType, value }}
+
+	for _, s := range segments[:len(segments)-1] {
+		key, _, err := c.slot(path, s, true)
+		if err != nil {
+			return container{}, segment{}, err
+		}
+		slot := c.read[key].(map[string]any)
+		values := registerValues(slot)
+		r, ok := ref{}, false
+		if len(values) > 0 {
+			r, ok = values[len(values)-1].(ref)
+		}
+		if !ok {
+			return container{}, segment{}, fmt.Errorf("document: path %q goes through a scalar", path)
+		}
+		c = container{kind: r.Kind, read: containerRead(slot, r), wrap: c.wrap.into(key, r.key())}
+	}
+	return c, segments[len(segments)-1], nil
+}
+
+// key of the slot of s in the container and the vertex of the element if it is a list, exists tells if the slot must exist
+func (c container) slot(path string, s segment, exists bool) (string, datatypes.Vertex, error) {
+	if c.kind == objectKind {
+		if s.isIndex {
+			return "", datatypes.Vertex{}, fmt.Errorf("document: path %q indexes an object", path)
+		}
+		if _, ok := c.read[s.key]; exists && !ok {
+			return "", datatypes.Vertex{}, fmt.Errorf("document: path %q is not found", path)
+		}
+		return s.key, datatypes.Vertex{}, nil
+	}
+
+	if !s.isIndex {
+		return "", datatypes.Vertex{}, fmt.Errorf("document: path %q has a key in a list", path)
+	}
+	elements := listElements(c.read)
+	if s.index < 0 || s.index >= len(elements) {
+		return "", datatypes.Vertex{}, fmt.Errorf("document: index of path %q is out of range", path)
+	}
+	return elementKey(elements[s.index]), elements[s.index], nil
+}
+
+// splits a path like "a.b[2].c" in keys and indexes, paths start with a key of the root object
+func parsePath(path string) ([]segment, error) {
+	segments := []segment{}
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" {
+			return nil, fmt.Errorf("document: path %q has an empty key", path)
+		}
+		segments = append(segments, segment{key: key})
+		for rest != "" {
+			index, after, found := strings.Cut(rest, "]")
+			i, err := strconv.Atoi(index)
+			if !found || err != nil || (after != "" && after[0] != '[') {
+				return nil, fmt.Errorf("document: path %q has an invalid index", path)
+			}
+			segments = append(segments, segment{index: i, isIndex: true})
+			rest = strings.TrimPrefix(after, "[")
+		}
+	}
+	return segments, nil
+}
+
+// identifier of the slot of an element of a list
+func elementKey(v datatypes.Vertex) string {
+	return strconv.FormatUint(v.Timestamp.(communication.VClock).Sum(), 10) + "@" + v.OriginID
+}
+
+// vertices of the visible elements of the read of a list, elements whose slot was removed are skipped
+func listElements(read map[string]any) []datatypes.Vertex {
+	items, ok := read[itemsKey].([]datatypes.Vertex)
+	if !ok || len(items) == 0 {
+		return []datatypes.Vertex{}
+	}
+	elements := []datatypes.Vertex{}
+	for _, v := range items[1:] {
+		if _, ok := read[elementKey(v)]; ok {
+			elements = append(elements, v)
+		}
+	}
+	return elements
+}
+
+// values of the register of the read of a slot ordered by the stamps of their writes, so every replica picks the same last one
+func registerValues(slot map[string]any) []any {
+	values, ok := slot[registerKey].([]any)
+	if !ok {
+		return []any{}
+	}
+	return values
+}
+
+// read of the container of the slot, containers without operations are empty
+func containerRead(slot map[string]any, r ref) map[string]any {
+	if read, ok := slot[r.key()].(map[string]any); ok {
+		return read
+	}
+	return map[string]any{}
+}
+
+// JSON value of the read of a slot, false if no value is assigned to it
+func slotValue(slot map[string]any) (any, bool) {
+	values := registerValues(slot)
+	if len(values) == 0 {
+		return nil, false
+	}
+	return jsonValue(slot, values[len(values)-1]), true
+}
+
+// JSON value of a value of the register of a slot
+func jsonValue(slot map[string]any, value any) any {
+	r, ok := value.(ref)
+	if !ok {
+		return value
+	}
+	read := containerRead(slot, r)
+	if r.Kind == objectKind {
+		return objectValue(read)
+	}
+	list := []any{}
+	for _, v := range listElements(read) {
+		if value, ok := slotValue(read[elementKey(v)].(map[string]any)); ok {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// JSON object of the read of the ORMap of an object
+func objectValue(read map[string]any) map[string]any {
+	object := map[string]any{}
+	for key, slot := range read {
+		if value, ok := slotValue(slot.(map[string]any)); ok {
+			object[key] = value
+		}
+	}
+	return object
+}
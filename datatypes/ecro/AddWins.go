@@ -3,27 +3,27 @@ package datatypes
 import (
 	"library/packages/communication"
 	"library/packages/crdt"
+	"library/packages/datatypes"
+	"library/packages/datatypes/persistent"
 	"library/packages/replica"
-
-	mapset "github.com/deckarep/golang-set/v2"
 )
 
 type AddWins struct {
 	id string
 }
 
-func (a AddWins) Add(state mapset.Set[any], elem any) mapset.Set[any] {
+func (a AddWins) Add(state *persistent.Set[any], elem any) *persistent.Set[any] {
 	state.Add(elem.(communication.Operation).Value)
 	return state
 }
 
-func (a AddWins) Remove(state mapset.Set[any], elem any) mapset.Set[any] {
+func (a AddWins) Remove(state *persistent.Set[any], elem any) *persistent.Set[any] {
 	state.Remove(elem.(communication.Operation).Value)
 	return state
 }
 
 func (a AddWins) Apply(state any, operations []communication.Operation) any {
-	st := state.(mapset.Set[any]).Clone()
+	st := state.(*persistent.Set[any]).Clone()
 	for _, op := range operations {
 		switch op.Type {
 		case "Add":
@@ -35,6 +35,21 @@ func (a AddWins) Apply(state any, operations []communication.Operation) any {
 	return st
 }
 
+// elements added and removed between two states
+func (a AddWins) Delta(from any, to any) any {
+	return datatypes.NewSetDelta(from.(*persistent.Set[any]), to.(*persistent.Set[any]))
+}
+
+func (a AddWins) Inverse(state any, op communication.Operation) communication.Operation {
+	contains := state.(*persistent.Set[any]).Contains(op.Value)
+	if op.Type == "Add" && !contains {
+		return communication.Operation{Type: "Rem", Value: op.Value, Version: op.Version, OriginID: op.OriginID}
+	} else if op.Type == "Rem" && contains {
+		return communication.Operation{Type: "Add", Value: op.Value, Version: op.Version, OriginID: op.OriginID}
+	}
+	return communication.Operation{Type: "Nop", Version: op.Version, OriginID: op.OriginID}
+}
+
 func (a AddWins) Order(op1 communication.Operation, op2 communication.Operation) bool {
 	//order map of operations by type of operation, removes come before adds
 
@@ -52,7 +67,7 @@ func (a AddWins) Equals(op1 communication.Operation, op2 communication.Operation
 // initialize counter replica
 func NewAddWinsReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
 
-	c := crdt.NewEcroCRDT(id, mapset.NewSet[any](), AddWins{})
+	c := crdt.NewEcroCRDT(id, persistent.NewSet[any](), AddWins{})
 
 	return replica.NewReplica(id, c, channels, delay)
 }
@@ -0,0 +1,110 @@
+package datatypes
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	"library/packages/replica"
+)
+
+// BlockRGA is an RGA whose inserts add a block of characters under the identifier of the operation,
+// blocks are split when a later insert or remove targets their middle
+type BlockRGA datatypes.RGA
+
+func (r BlockRGA) Apply(state any, operations []communication.Operation) any {
+	st := state.(*datatypes.BlockSequence).Copy()
+	for _, op := range operations {
+		value := op.Value.(datatypes.BlockOpValue)
+		switch op.Type {
+		case "Add":
+			// the block goes right after its predecessor, concurrent inserts after the same character are ordered by Order
+			st.Insert(value.After, datatypes.NewBlockID(op.Version, op.OriginID), value.Text)
+		case "Rem":
+			// removed characters are kept as tombstones so inserts after them do not depend on the order of the remove
+			for _, span := range value.Spans {
+				st.Delete(span)
+			}
+		}
+	}
+	return st
+}
+
+func (r BlockRGA) Order(op1 communication.Operation, op2 communication.Operation) bool {
+	return datatypes.NewBlockID(op1.Version, op1.OriginID).Less(datatypes.NewBlockID(op2.Version, op2.OriginID))
+}
+
+func (r BlockRGA) Commutes(op1 communication.Operation, op2 communication.Operation) bool {
+	v1, v2 := op1.Value.(datatypes.BlockOpValue), op2.Value.(datatypes.BlockOpValue)
+	id1, id2 := datatypes.NewBlockID(op1.Version, op1.OriginID), datatypes.NewBlockID(op2.Version, op2.OriginID)
+
+	if op1.Type == "Add" && op2.Type == "Add" {
+		// inserts after the same character or in the block of the other insert
+		return v1.After != v2.After && v1.After.Block != id2 && v2.After.Block != id1
+	}
+	// removes only conflict with the inserts of the characters they delete
+	if op1.Type == "Rem" && op2.Type == "Add" {
+		return !removesBlock(v1, id2)
+	}
+	if op1.Type == "Add" && op2.Type == "Rem" {
+		return !removesBlock(v2, id1)
+	}
+	return true
+}
+
+// drops the characters removed by stable removes once no unstable insert is after them,
+// the inserts after them that are stable already have their position in the state
+func (r BlockRGA) Collect(state any, stable []communication.Operation, unstable []communication.Operation) (any, bool) {
+	spans := []datatypes.CharSpan{}
+	for _, op := range stable {
+		value := op.Value.(datatypes.BlockOpValue)
+		switch op.Type {
+		case "Rem":
+			spans = append(spans, value.Spans...)
+		case "Add":
+			// the character the insert is after can be removed and kept for it
+			if value.After != (datatypes.CharID{}) {
+				spans = append(spans, datatypes.CharSpan{Block: value.After.Block, Offset: value.After.Offset, Length: 1})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return state, false
+	}
+
+	after := []datatypes.CharID{}
+	for _, op := range unstable {
+		if op.Type == "Add" {
+			after = append(after, op.Value.(datatypes.BlockOpValue).After)
+		}
+	}
+
+	st := state.(*datatypes.BlockSequence).Copy()
+	collected := false
+	for _, span := range spans {
+		if st.Collect(span, after) {
+			collected = true
+		}
+	}
+	if !collected {
+		return state, false
+	}
+	return st, true
+}
+
+// check if the remove deletes characters of the block
+func removesBlock(value datatypes.BlockOpValue, block datatypes.BlockID) bool {
+	for _, span := range value.Spans {
+		if span.Block == block {
+			return true
+		}
+	}
+	return false
+}
+
+// initialize block-wise RGA
+func NewBlockRGAReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
+
+	r := crdt.NewEcroCRDT(id, datatypes.NewBlockSequence(), BlockRGA{id})
+
+	return replica.NewReplica(id, r, channels, delay)
+}
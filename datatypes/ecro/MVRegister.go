@@ -1,68 +1,62 @@
 package datatypes
 
 import (
+	"fmt"
 	"library/packages/communication"
 	"library/packages/crdt"
+	"library/packages/datatypes"
 	"library/packages/replica"
-
-	mapset "github.com/deckarep/golang-set/v2"
+	"reflect"
 )
 
-type update struct {
-	value   int
-	version communication.VClock
-}
-
-type MVRegister struct {
-	vstate []update // versioned state used to check concurrent operations
-}
-
-func (m *MVRegister) Apply(state any, operations []communication.Operation) any {
-	st := []int{}
-
-	// check if there are concurrent operations using vector clocks and join them in a set
-	// if there are concurrent operations, then the set will have more than one element
-	// if there are no concurrent operations, then the set will have only one element
-	concurrent := []update{}
+// MVRegister is a multi-value register, it keeps the values of concurrent writes until a write that has seen them
+// writes of V are prepared with "Write", a merge of the current values is prepared with Resolve
+type MVRegister[V any] struct{}
 
+func (m MVRegister[V]) Apply(state any, operations []communication.Operation) any {
+	st := state.(datatypes.MVRegister[V])
 	for _, op := range operations {
-		tmp := m.vstate[:0]
-		for _, s := range m.vstate {
-			msgOP := op
-
-			cmp := msgOP.Version.Compare(s.version)
-			if cmp == communication.Concurrent {
-				concurrent = append(concurrent, s)
-			}
-			if cmp != communication.Ancestor {
-				tmp = append(tmp, s)
-			}
+		if op.Type == "Write" {
+			// Check only lets nil through for registers of interfaces, where it is the zero value of V
+			value, _ := op.Value.(V)
+			st = st.Write(value, op.Version, op.OriginID)
 		}
-		m.vstate = tmp
 	}
+	return st
+}
 
-	for _, op := range operations {
-		msgOP := op
-		m.vstate = append(m.vstate, update{msgOP.Value.(int), msgOP.Version})
-		st = append(st, msgOP.Value.(int))
+// rejects writes of values that are not of type V, a nil value is only a V if V is an interface, e.g. a null of a register of any
+func (m MVRegister[V]) Check(state any, op communication.Operation) error {
+	if op.Type != "Write" {
+		return fmt.Errorf("multi-value register: unknown operation %s", op.Type)
 	}
+	var zero V
+	if _, ok := op.Value.(V); !ok && (op.Value != nil || any(zero) != nil) {
+		return fmt.Errorf("multi-value register: write of %T to a register of %v", op.Value, reflect.TypeOf((*V)(nil)).Elem())
+	}
+	return nil
+}
 
-	// if there are concurrent operations, then the set will have more than one element
-	m.vstate = append(m.vstate, concurrent...)
+func (m MVRegister[V]) Stabilize(state any, op communication.Operation) any {
+	return state.(datatypes.MVRegister[V]).Stabilize(datatypes.NewStamp(op.Version, op.OriginID))
+}
 
-	for _, s := range concurrent {
-		st = append(st, s.value)
-	}
+func (m MVRegister[V]) Query(state any) any {
+	return state.(datatypes.MVRegister[V]).Values()
+}
 
-	return st
+// prepares on a multi-value register replica the write of the merge by resolve of the values it shows,
+// the merge is computed once by the replica and the other replicas apply the merged value
+func Resolve[V any](r *replica.Replica, resolve func([]V) V) (communication.Operation, error) {
+	return r.Update("Write", func(state any) any {
+		return resolve(state.([]V))
+	})
 }
 
-// initialize counter replica
-func NewMVRegisterReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
+// initialize multi-value register replica
+func NewMVRegisterReplica[V any](id string, channels map[string]chan any, delay int) *replica.Replica {
 
-	m := crdt.CommutativeCRDT{Data: &MVRegister{
-		vstate: []update{},
-	}, Stable_st: mapset.NewSet[int]()}
+	m := crdt.CommutativeStableCRDT{Data: MVRegister[V]{}, Stable_st: datatypes.NewMVRegister[V]()}
 
 	return replica.NewReplica(id, &m, channels, delay)
 }
@@ -0,0 +1,115 @@
+package datatypes
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	"library/packages/replica"
+)
+
+// MoveRGA is an RGA whose elements can be moved after another position, set to a new value or removed
+// concurrent moves of an element converge to the position of the greatest move,
+// and sets concurrent with a move are kept because the element is not removed and inserted again
+type MoveRGA datatypes.RGA
+
+func (r MoveRGA) Apply(state any, operations []communication.Operation) any {
+	st := state.(*datatypes.MoveList).Copy()
+	for _, op := range operations {
+		value := op.Value.(datatypes.MoveOpValue)
+		self := datatypes.Vertex{Timestamp: op.Version, OriginID: op.OriginID}
+		switch op.Type {
+		case "Add":
+			st.Insert(value.V, self, value.Value)
+		case "Move":
+			st.Move(value.V, value.Element, self)
+		case "Set":
+			st.Set(value.Element, value.Value, self)
+		case "Rem":
+			st.Remove(value.Element)
+		}
+	}
+	return st
+}
+
+// drops the positions that stable moves and removes replaced, unless an unstable operation is after them,
+// positions are inserted right after the one they follow so dropping others does not move them
+func (r MoveRGA) Collect(state any, stable []communication.Operation, unstable []communication.Operation) (any, bool) {
+	elements := []datatypes.Vertex{}
+	for _, op := range stable {
+		if op.Type == "Move" || op.Type == "Rem" {
+			elements = append(elements, op.Value.(datatypes.MoveOpValue).Element)
+		}
+	}
+	if len(elements) == 0 {
+		return state, false
+	}
+
+	keep := []datatypes.Vertex{}
+	for _, op := range unstable {
+		value := op.Value.(datatypes.MoveOpValue)
+		keep = append(keep, value.V, value.Element)
+	}
+
+	st := state.(*datatypes.MoveList).Copy()
+	collected := false
+	for _, e := range elements {
+		if st.Collect(e, keep) {
+			collected = true
+		}
+	}
+	return st, collected
+}
+
+func (r MoveRGA) Query(state any) any {
+	return state.(*datatypes.MoveList).Items()
+}
+
+func (r MoveRGA) Order(op1 communication.Operation, op2 communication.Operation) bool {
+	return RGA(r).Order(op1, op2)
+}
+
+func (r MoveRGA) Commutes(op1 communication.Operation, op2 communication.Operation) bool {
+	v1, v2 := op1.Value.(datatypes.MoveOpValue), op2.Value.(datatypes.MoveOpValue)
+
+	// inserts and moves after the same position, or after the position added by the other one
+	if addsPosition(op1) && addsPosition(op2) &&
+		(sameVertex(v1.V, v2.V) || sameVertex(v1.V, opVertex(op2)) || sameVertex(v2.V, opVertex(op1))) {
+		return false
+	}
+
+	// operations on the element added by the other one
+	if op1.Type == "Add" && op2.Type != "Add" && sameVertex(v2.Element, opVertex(op1)) {
+		return false
+	}
+	if op2.Type == "Add" && op1.Type != "Add" && sameVertex(v1.Element, opVertex(op2)) {
+		return false
+	}
+	return true
+}
+
+// check if the operation adds a position to the list
+func addsPosition(op communication.Operation) bool {
+	return op.Type == "Add" || op.Type == "Move"
+}
+
+// vertex of the position or element created by the operation
+func opVertex(op communication.Operation) datatypes.Vertex {
+	return datatypes.Vertex{Timestamp: op.Version, OriginID: op.OriginID}
+}
+
+// check if two vertices were created by the same operation, the roots of all replicas are the same vertex
+func sameVertex(v1 datatypes.Vertex, v2 datatypes.Vertex) bool {
+	if v1.Timestamp == nil || v2.Timestamp == nil {
+		return false
+	}
+	sum1, sum2 := v1.Timestamp.(communication.VClock).Sum(), v2.Timestamp.(communication.VClock).Sum()
+	return sum1 == sum2 && (sum1 == 0 || v1.OriginID == v2.OriginID)
+}
+
+// initialize RGA with moves
+func NewMoveRGAReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
+
+	r := crdt.NewEcroCRDT(id, datatypes.NewMoveList(datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: id}), MoveRGA{id})
+
+	return replica.NewReplica(id, r, channels, delay)
+}
@@ -11,7 +11,7 @@ import (
 type RGA datatypes.RGA
 
 func (r RGA) Apply(state any, operations []communication.Operation) any {
-	stCpy := RGACopy(state.([]datatypes.Vertex))
+	stCpy := state.(*datatypes.Sequence).Copy()
 
 	for _, op := range operations {
 		msg := op
@@ -21,35 +21,130 @@ func (r RGA) Apply(state any, operations []communication.Operation) any {
 			newVertexPrev := msg.Value.(datatypes.RGAOpValue).V
 
 			// find index where predecessor vertex can be found
-			predecessorIdx := indexOfVPtr(newVertexPrev, stCpy)
+			predecessorIdx := stCpy.IndexOf(newVertexPrev)
 
 			// if predecessor vertex is not found, insert on root
+			// it is not found when a remove of the map of the RGA dropped its insert, then the order of the inserts is not given by the graph
 			if predecessorIdx == -1 {
 				predecessorIdx = 0
 			}
 
-			newVertices := append(stCpy[:predecessorIdx+1], append([]datatypes.Vertex{newVertex}, stCpy[predecessorIdx+1:]...)...)
-
-			stCpy = newVertices
+			stCpy.Insert(skipGreater(predecessorIdx+1, newVertex, stCpy), newVertex)
 		case "Rem":
 			removeVertex := msg.Value.(datatypes.RGAOpValue).V
 			// find index where removed vertex can be found and clear its content to tombstone it
-			index := indexOfVPtr(removeVertex, stCpy)
+			// the tombstone is kept so inserts after the removed vertex do not depend on the order of the remove
+			index := stCpy.IndexOf(removeVertex)
 			if index == -1 {
 				continue
 			}
-			newVertices := append(stCpy[:index], stCpy[index+1:]...)
-			stCpy = newVertices
+			tombstone := stCpy.At(index)
+			stCpy.Set(index, datatypes.Vertex{Timestamp: tombstone.Timestamp, Value: nil, OriginID: tombstone.OriginID})
+		case "Drop":
+			// undo of an insert, the inserted vertex is removed without leaving a tombstone
+			index := stCpy.IndexOf(msg.Value.(datatypes.RGAOpValue).V)
+			if index != -1 {
+				stCpy.Remove(index)
+			}
+		case "Restore":
+			// undo of a remove, put the content of the removed vertex back in its tombstone
+			stCpy.Set(msg.Value.(datatypes.RGAOpValue).Value.(int), msg.Value.(datatypes.RGAOpValue).V)
 		}
 	}
 	return stCpy
 }
 
-func (r RGA) Order(op1 communication.Operation, op2 communication.Operation) bool {
-	id1, _ := strconv.Atoi(strconv.Itoa(int(op1.Version.Sum())) + op1.OriginID)
-	id2, _ := strconv.Atoi(strconv.Itoa(int(op2.Version.Sum())) + op2.OriginID)
+func (r RGA) Inverse(state any, op communication.Operation) communication.Operation {
+	st := state.(*datatypes.Sequence)
+	switch op.Type {
+	case "Add":
+		// undo of an insert drops the inserted vertex
+		newVertex := datatypes.Vertex{Timestamp: op.Version, Value: op.Value.(datatypes.RGAOpValue).Value, OriginID: op.OriginID}
+		return communication.Operation{Type: "Drop", Value: datatypes.RGAOpValue{V: newVertex}, Version: op.Version, OriginID: op.OriginID}
+	case "Rem":
+		index := st.IndexOf(op.Value.(datatypes.RGAOpValue).V)
+		if index != -1 && st.At(index).Value != nil {
+			return communication.Operation{Type: "Restore", Value: datatypes.RGAOpValue{V: st.At(index), Value: index}, Version: op.Version, OriginID: op.OriginID}
+		}
+	}
+	return communication.Operation{Type: "Nop", Version: op.Version, OriginID: op.OriginID}
+}
+
+// drops the tombstones of stable removes once no unstable insert is after them,
+// the inserts after a tombstone that are stable already have their position in the state
+func (r RGA) Collect(state any, stable []communication.Operation, unstable []communication.Operation) (any, bool) {
+	candidates := []datatypes.Vertex{}
+	for _, op := range stable {
+		if op.Type == "Rem" || op.Type == "Add" {
+			candidates = append(candidates, op.Value.(datatypes.RGAOpValue).V)
+		}
+	}
+	if len(candidates) == 0 {
+		return state, false
+	}
+
+	after := map[string]bool{}
+	for _, op := range unstable {
+		if op.Type == "Add" {
+			after[vertexKey(op.Value.(datatypes.RGAOpValue).V)] = true
+		}
+	}
+
+	st := state.(*datatypes.Sequence)
+	collected := false
+	for _, v := range candidates {
+		index := st.IndexOf(v)
+		if index == -1 || st.At(index).Value != nil || after[vertexKey(v)] {
+			continue
+		}
+		if !collected {
+			st = st.Copy()
+			collected = true
+		}
+		st.Remove(index)
+	}
+	return st, collected
+}
+
+// position of a vertex inserted after the vertex before offset, after the vertices there with a greater id and the ones inserted after them,
+// so inserts after the same vertex are placed by their ids in any order
+func skipGreater(offset int, newVertex datatypes.Vertex, st *datatypes.Sequence) int {
+	id := vertexID(newVertex)
+	for offset < st.Len() && id.Less(vertexID(st.At(offset))) {
+		offset++
+	}
+	return offset
+}
+
+// id of a vertex, ordered by the sum of its timestamp and then by the replica that created it, like the operations by Order
+func vertexID(v datatypes.Vertex) datatypes.Stamp {
+	return datatypes.NewStamp(v.Timestamp.(communication.VClock), v.OriginID)
+}
 
-	return id1 < id2
+// identifier of a vertex, the sum of its timestamp and the replica that created it
+func vertexKey(v datatypes.Vertex) string {
+	return strconv.FormatUint(v.Timestamp.(communication.VClock).Sum(), 10) + v.OriginID
+}
+
+func (r RGA) Query(state any) any {
+	//removes tombstones
+	noTombs := []datatypes.Vertex{}
+	for _, v := range state.(*datatypes.Sequence).Vertices() {
+		if v.Value != nil {
+			noTombs = append(noTombs, v)
+		}
+	}
+	return noTombs
+}
+
+// ranges inserted and deleted between two states
+func (r RGA) Delta(from any, to any) any {
+	return datatypes.NewSequenceDelta(from.([]datatypes.Vertex), to.([]datatypes.Vertex))
+}
+
+// inserts are ordered by the ids of their vertices, the sums are compared before the replicas so ids of different operations never collide
+func (r RGA) Order(op1 communication.Operation, op2 communication.Operation) bool {
+	return datatypes.NewStamp(op1.Version, op1.OriginID).Less(datatypes.NewStamp(op2.Version, op2.OriginID))
 }
 
 func (r RGA) Commutes(op1 communication.Operation, op2 communication.Operation) bool {
@@ -80,20 +175,11 @@ func (r RGA) Commutes(op1 communication.Operation, op2 communication.Operation)
 // initialize RGA
 func NewRGAReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
 
-	r := crdt.NewEcroCRDT(id, []datatypes.Vertex{{communication.NewVClockFromMap(map[string]uint64{}), "", id}}, RGA{id})
+	r := crdt.NewEcroCRDT(id, datatypes.NewSequence(datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: id}), RGA{id})
 
 	return replica.NewReplica(id, r, channels, delay)
 }
 
-func indexOfVPtr(vertex datatypes.Vertex, vertices []datatypes.Vertex) int {
-	for i, v := range vertices {
-		if vertex.Timestamp.(communication.VClock).Equal(v.Timestamp.(communication.VClock)) {
-			return i
-		}
-	}
-	return -1
-}
-
 // check if two array of vertices are equal
 func RGAEqual(vertices1 []datatypes.Vertex, vertices2 []datatypes.Vertex) bool {
 	if len(vertices1) != len(vertices2) {
@@ -113,22 +199,9 @@ func RGAEqual(vertices1 []datatypes.Vertex, vertices2 []datatypes.Vertex) bool {
 	return true
 }
 
-// deep copy state of RGA
-func RGACopy(state []datatypes.Vertex) []datatypes.Vertex {
-	stCpy := make([]datatypes.Vertex, len(state))
-	for i, v := range state {
-		stCpy[i].Value = v.Value
-		stCpy[i].Timestamp = v.Timestamp.(communication.VClock).Copy()
-		stCpy[i].OriginID = v.OriginID
-	}
-	return stCpy
-}
-
-func (r RGA) effectivePos(prevV datatypes.Vertex, state []datatypes.Vertex) datatypes.Vertex {
-	for _, v := range state {
-		if v.Timestamp.(communication.VClock).Equal(prevV.Timestamp.(communication.VClock)) {
-			return prevV
-		}
+func (r RGA) effectivePos(prevV datatypes.Vertex, state *datatypes.Sequence) datatypes.Vertex {
+	if state.Contains(prevV) {
+		return prevV
 	}
 	return datatypes.Vertex{communication.NewVClockFromMap(map[string]uint64{}), "", r.Id}
 }
@@ -0,0 +1,41 @@
+package datatypes
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	"library/packages/datatypes/persistent"
+	"library/packages/replica"
+)
+
+// RemoveWins is a set whose removes win over concurrent adds of the same element
+type RemoveWins struct {
+	AddWins
+}
+
+func (r RemoveWins) Order(op1 communication.Operation, op2 communication.Operation) bool {
+	//adds come before removes
+
+	return op1.Type == "Add" && op2.Type == "Rem"
+}
+
+// returns the set data with the given bias
+func NewSet(bias datatypes.Bias) crdt.EcroDataI {
+	if bias == datatypes.RemoveBias {
+		return RemoveWins{}
+	}
+	return AddWins{}
+}
+
+// initialize remove-wins set replica
+func NewRemoveWinsReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
+	return NewSetReplica(id, datatypes.RemoveBias, channels, delay)
+}
+
+// initialize set replica with the given bias
+func NewSetReplica(id string, bias datatypes.Bias, channels map[string]chan any, delay int) *replica.Replica {
+
+	c := crdt.NewEcroCRDT(id, persistent.NewSet[any](), NewSet(bias))
+
+	return replica.NewReplica(id, c, channels, delay)
+}
@@ -0,0 +1,66 @@
+package datatypes
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	"library/packages/replica"
+)
+
+// Tree is a tree whose nodes can be created, moved under new parents and deleted
+// concurrent moves are applied in the arbitration order and a move that would create a cycle is ignored,
+// so when an earlier operation is delivered late the engine replays the moves and undoes the ones that became cycles
+type Tree struct {
+	Id string
+}
+
+func (t Tree) Apply(state any, operations []communication.Operation) any {
+	st := state.(*datatypes.Tree).Copy()
+	for _, op := range operations {
+		value := op.Value.(datatypes.TreeOpValue)
+		switch op.Type {
+		case "Create":
+			st.Create(datatypes.NewNodeID(op.Version, op.OriginID), value.Parent, value.Value)
+		case "Move":
+			st.Move(value.Node, value.Parent)
+		case "Delete":
+			st.Delete(value.Node)
+		}
+	}
+	return st
+}
+
+func (t Tree) Order(op1 communication.Operation, op2 communication.Operation) bool {
+	return datatypes.NewNodeID(op1.Version, op1.OriginID).Less(datatypes.NewNodeID(op2.Version, op2.OriginID))
+}
+
+func (t Tree) Commutes(op1 communication.Operation, op2 communication.Operation) bool {
+	// a create only conflicts with the operations on the node it adds, they have seen it and must be applied after it
+	if op1.Type == "Create" || op2.Type == "Create" {
+		return !refersTo(op1, op2) && !refersTo(op2, op1)
+	}
+	// deletes always end under the trash
+	if op1.Type == "Delete" && op2.Type == "Delete" {
+		return true
+	}
+	// whether a move creates a cycle depends on every other move and delete before it
+	return false
+}
+
+// check if op is a create and other places the node it adds or places a node under it
+func refersTo(op communication.Operation, other communication.Operation) bool {
+	if op.Type != "Create" {
+		return false
+	}
+	node := datatypes.NewNodeID(op.Version, op.OriginID)
+	value := other.Value.(datatypes.TreeOpValue)
+	return other.Type != "Create" && value.Node == node || value.Parent == node
+}
+
+// initialize tree with moves
+func NewTreeReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
+
+	r := crdt.NewEcroCRDT(id, datatypes.NewTree(), Tree{id})
+
+	return replica.NewReplica(id, r, channels, delay)
+}
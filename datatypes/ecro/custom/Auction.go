@@ -3,9 +3,8 @@ package custom
 import (
 	"library/packages/communication"
 	"library/packages/crdt"
+	"library/packages/datatypes/persistent"
 	"library/packages/replica"
-
-	mapset "github.com/deckarep/golang-set/v2"
 )
 
 type Bid struct {
@@ -14,8 +13,8 @@ type Bid struct {
 }
 
 type AuctionState struct {
-	Users  mapset.Set[any]
-	Bids   mapset.Set[Bid]
+	Users  *persistent.Set[any]
+	Bids   *persistent.Set[Bid]
 	MaxBid int
 }
 
@@ -36,16 +35,12 @@ func (a Auction) RemUser(state AuctionState, elem any) AuctionState {
 	state.Users.Remove(elem)
 
 	//remove all bids from user
-	bids := state.Bids.ToSlice()
-	for i := 0; i < len(bids); i++ {
-		if bids[i].User == elem {
-			bids = append(bids[:i], bids[i+1:]...)
-			i--
+	for _, bid := range state.Bids.ToSlice() {
+		if bid.User == elem {
+			state.Bids.Remove(bid)
 		}
 	}
 
-	state.Bids = mapset.NewSet(bids...)
-
 	return state
 }
 
@@ -99,12 +94,25 @@ func (a Auction) Commutes(op1 communication.Operation, op2 communication.Operati
 	return false
 }
 
+// bids belong to existing users
+func (a Auction) Invariants() []crdt.Invariant {
+	return []crdt.Invariant{{Name: "bids belong to existing users", Holds: func(state any) bool {
+		st := state.(AuctionState)
+		for _, bid := range st.Bids.ToSlice() {
+			if !st.Users.Contains(bid.User) {
+				return false
+			}
+		}
+		return true
+	}}}
+}
+
 // initialize counter replica
 func NewAuctionReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
 
 	c := crdt.NewEcroCRDT(id, AuctionState{
-		Users:  mapset.NewSet[any](),
-		Bids:   mapset.NewSet[Bid](),
+		Users:  persistent.NewSet[any](),
+		Bids:   persistent.NewSet[Bid](),
 		MaxBid: 0,
 	}, Auction{id})
 
@@ -3,9 +3,8 @@ package custom
 import (
 	"library/packages/communication"
 	"library/packages/crdt"
+	"library/packages/datatypes/persistent"
 	"library/packages/replica"
-
-	mapset "github.com/deckarep/golang-set/v2"
 )
 
 type Enroll struct {
@@ -14,9 +13,9 @@ type Enroll struct {
 }
 
 type EgameState struct {
-	Tournaments mapset.Set[any]
-	Players     mapset.Set[any]
-	Enrolled    mapset.Set[Enroll]
+	Tournaments *persistent.Set[any]
+	Players     *persistent.Set[any]
+	Enrolled    *persistent.Set[Enroll]
 }
 
 type Egame struct {
@@ -33,17 +32,13 @@ func (e Egame) RemTournament(state EgameState, elem any) EgameState {
 	elem = elem.(communication.Operation).Value
 	state.Tournaments.Remove(elem)
 
-	enrolled := state.Enrolled.ToSlice()
 	//remove all enrolled tournaments
-	for i := 0; i < len(enrolled); i++ {
-		if enrolled[i].Tournament == elem {
-			enrolled = append(enrolled[:i], enrolled[i+1:]...)
-			i--
+	for _, enrolled := range state.Enrolled.ToSlice() {
+		if enrolled.Tournament == elem {
+			state.Enrolled.Remove(enrolled)
 		}
 	}
 
-	state.Enrolled = mapset.NewSet(enrolled...)
-
 	return state
 }
 
@@ -57,18 +52,13 @@ func (e Egame) RemPlayer(state EgameState, elem any) EgameState {
 	elem = elem.(communication.Operation).Value
 	state.Players.Remove(elem)
 
-	enrolled := state.Enrolled.ToSlice()
-
-	//remove all enrolled player
-	for i := 0; i < len(enrolled); i++ {
-		if enrolled[i].Player == elem {
-			enrolled = append(enrolled[:i], enrolled[i+1:]...)
-			i--
+	//remove all enrolled players
+	for _, enrolled := range state.Enrolled.ToSlice() {
+		if enrolled.Player == elem {
+			state.Enrolled.Remove(enrolled)
 		}
 	}
 
-	state.Enrolled = mapset.NewSet(enrolled...)
-
 	return state
 }
 
@@ -79,21 +69,22 @@ func (e Egame) Enroll(state EgameState, elem any) EgameState {
 }
 
 func (e Egame) Apply(state any, operations []communication.Operation) any {
+	st := state.(EgameState).copy()
 	for _, op := range operations {
 		switch op.Type {
 		case "AddTournament":
-			state = e.AddTournament(state.(EgameState), op)
+			st = e.AddTournament(st, op)
 		case "RemTournament":
-			state = e.RemTournament(state.(EgameState), op)
+			st = e.RemTournament(st, op)
 		case "AddPlayer":
-			state = e.AddPlayer(state.(EgameState), op)
+			st = e.AddPlayer(st, op)
 		case "RemPlayer":
-			state = e.RemPlayer(state.(EgameState), op)
+			st = e.RemPlayer(st, op)
 		case "Enroll":
-			state = e.Enroll(state.(EgameState), op)
+			st = e.Enroll(st, op)
 		}
 	}
-	return state
+	return st
 }
 
 func (e Egame) Order(op1 communication.Operation, op2 communication.Operation) bool {
@@ -125,18 +116,40 @@ func (e Egame) Commutes(op1 communication.Operation, op2 communication.Operation
 	return false
 }
 
+// enrollments reference existing players and tournaments
+func (e Egame) Invariants() []crdt.Invariant {
+	return []crdt.Invariant{{Name: "enrollments reference existing players and tournaments", Holds: func(state any) bool {
+		st := state.(EgameState)
+		for _, enrolled := range st.Enrolled.ToSlice() {
+			if !st.Players.Contains(enrolled.Player) || !st.Tournaments.Contains(enrolled.Tournament) {
+				return false
+			}
+		}
+		return true
+	}}}
+}
+
 // initialize counter replica
 func NewEgameReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
 
 	c := crdt.NewEcroCRDT(id, EgameState{
-		Tournaments: mapset.NewSet[any](),
-		Players:     mapset.NewSet[any](),
-		Enrolled:    mapset.NewSet[Enroll](),
+		Tournaments: persistent.NewSet[any](),
+		Players:     persistent.NewSet[any](),
+		Enrolled:    persistent.NewSet[Enroll](),
 	}, Egame{id})
 
 	return replica.NewReplica(id, c, channels, delay)
 }
 
+// copy of egamestate
+func (s EgameState) copy() EgameState {
+	return EgameState{
+		Tournaments: s.Tournaments.Clone(),
+		Players:     s.Players.Clone(),
+		Enrolled:    s.Enrolled.Clone(),
+	}
+}
+
 // compares if two SocialState are equal for test reasons
 func CompareEgameStates(s1 EgameState, s2 EgameState) bool {
 	if !s1.Tournaments.Equal(s2.Tournaments) || !s1.Players.Equal(s2.Players) {
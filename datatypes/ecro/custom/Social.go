@@ -3,9 +3,8 @@ package custom
 import (
 	"library/packages/communication"
 	"library/packages/crdt"
+	"library/packages/datatypes/persistent"
 	"library/packages/replica"
-
-	mapset "github.com/deckarep/golang-set/v2"
 )
 
 type SocialOpValue struct {
@@ -14,8 +13,8 @@ type SocialOpValue struct {
 }
 
 type SocialState struct {
-	Friends    [5]mapset.Set[any] //friends[id] = set of friends of id
-	Requesters [5]mapset.Set[any] //requesters[id] = set of requests made to id
+	Friends    [5]*persistent.Set[any] //friends[id] = set of friends of id
+	Requesters [5]*persistent.Set[any] //requesters[id] = set of requests made to id
 }
 
 type Social struct {
@@ -108,12 +107,27 @@ func (a Social) Commutes(op1 communication.Operation, op2 communication.Operatio
 			op1.Value.(SocialOpValue).To != op2.Value.(SocialOpValue).To
 }
 
+// friendships are symmetric
+func (s Social) Invariants() []crdt.Invariant {
+	return []crdt.Invariant{{Name: "friendships are symmetric", Holds: func(state any) bool {
+		st := state.(SocialState)
+		for user, friends := range st.Friends {
+			for _, friend := range friends.ToSlice() {
+				if !st.Friends[friend.(int)].Contains(user) {
+					return false
+				}
+			}
+		}
+		return true
+	}}}
+}
+
 // initialize counter replica
 func NewSocialReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
 
 	c := crdt.NewEcroCRDT(id, SocialState{
-		Friends:    [5]mapset.Set[any]{mapset.NewSet[any](), mapset.NewSet[any](), mapset.NewSet[any](), mapset.NewSet[any](), mapset.NewSet[any]()},
-		Requesters: [5]mapset.Set[any]{mapset.NewSet[any](), mapset.NewSet[any](), mapset.NewSet[any](), mapset.NewSet[any](), mapset.NewSet[any]()},
+		Friends:    [5]*persistent.Set[any]{persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any]()},
+		Requesters: [5]*persistent.Set[any]{persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any]()},
 	}, Social{id})
 
 	return replica.NewReplica(id, c, channels, delay)
@@ -121,8 +135,8 @@ func NewSocialReplica(id string, channels map[string]chan any, delay int) *repli
 
 // copy of socialstate
 func (s SocialState) copy() SocialState {
-	var friends [5]mapset.Set[any]
-	var requesters [5]mapset.Set[any]
+	var friends [5]*persistent.Set[any]
+	var requesters [5]*persistent.Set[any]
 
 	for i := 0; i < 5; i++ {
 		friends[i] = s.Friends[i].Clone()
@@ -0,0 +1,299 @@
+package datatypes
+
+import (
+	"library/packages/datatypes/persistent"
+	"math"
+	"math/rand"
+)
+
+// space left between the labels of items appended at the end of a labelled tree
+const labelSpacing = 1 << 32
+
+// node of a labelled tree, nodes are never modified after being created
+type labelledNode[T any] struct {
+	item     T
+	label    uint64 // labels increase with the position of the item
+	priority uint32
+	size     int // number of items in the subtree
+	own      int // weight of the item
+	weight   int // sum of the weights of the items in the subtree
+	left     *labelledNode[T]
+	right    *labelledNode[T]
+}
+
+func size[T any](n *labelledNode[T]) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func weight[T any](n *labelledNode[T]) int {
+	if n == nil {
+		return 0
+	}
+	return n.weight
+}
+
+// copy of the node with new children
+func (n *labelledNode[T]) with(left *labelledNode[T], right *labelledNode[T]) *labelledNode[T] {
+	return &labelledNode[T]{item: n.item, label: n.label, priority: n.priority, size: 1 + size(left) + size(right),
+		own: n.own, weight: n.own + weight(left) + weight(right), left: left, right: right}
+}
+
+// labelled is a persistent treap of items ordered by position, where every item has a label that increases with its position,
+// and an index from the keys of the items to their labels, so inserting, removing and finding the position of an item are O(log n)
+// items can have a weight, e.g. their number of visible characters, to be found by the sum of the weights before them
+// updates copy only the path to the changed item, so copies are O(1)
+type labelled[K comparable, T any] struct {
+	root   *labelledNode[T]
+	index  *persistent.Map[K, uint64]
+	key    func(item T) K
+	weight func(item T) int // nil if items have no weight
+}
+
+// returns an empty tree, hash hashes the keys of the items
+func newLabelled[K comparable, T any](key func(item T) K, hash func(k K) uint64, weight func(item T) int) labelled[K, T] {
+	return labelled[K, T]{index: persistent.NewMapWithHash[K, uint64](hash), key: key, weight: weight}
+}
+
+// copy of the tree, O(1) since nodes are never modified
+func (t labelled[K, T]) copy() labelled[K, T] {
+	t.index = t.index.Clone()
+	return t
+}
+
+// number of items in the tree
+func (t *labelled[K, T]) len() int {
+	return size(t.root)
+}
+
+// sum of the weights of the items in the tree
+func (t *labelled[K, T]) totalWeight() int {
+	return weight(t.root)
+}
+
+// returns the item at position i
+func (t *labelled[K, T]) at(i int) T {
+	return t.nodeAt(i).item
+}
+
+func (t *labelled[K, T]) nodeAt(i int) *labelledNode[T] {
+	n := t.root
+	for n != nil {
+		l := size(n.left)
+		if i < l {
+			n = n.left
+		} else if i == l {
+			return n
+		} else {
+			i -= l + 1
+			n = n.right
+		}
+	}
+	panic("sequence index out of range")
+}
+
+// position of the item with the unit of weight w and the offset of the unit in the item, -1 if w is not less than the total weight
+func (t *labelled[K, T]) atWeight(w int) (int, int) {
+	i := 0
+	n := t.root
+	for n != nil {
+		l := weight(n.left)
+		if w < l {
+			n = n.left
+		} else if w < l+n.own {
+			return i + size(n.left), w - l
+		} else {
+			w -= l + n.own
+			i += size(n.left) + 1
+			n = n.right
+		}
+	}
+	return -1, 0
+}
+
+// returns the position of the item with key k, -1 if it is not in the tree
+func (t *labelled[K, T]) indexOf(k K) int {
+	label, ok := t.index.Get(k)
+	if !ok {
+		return -1
+	}
+	i := 0
+	n := t.root
+	for n != nil {
+		if label < n.label {
+			n = n.left
+		} else if label > n.label {
+			i += size(n.left) + 1
+			n = n.right
+		} else {
+			return i + size(n.left)
+		}
+	}
+	return -1
+}
+
+// check if the tree has an item with key k
+func (t *labelled[K, T]) contains(k K) bool {
+	return t.index.Contains(k)
+}
+
+// returns a node for item with a label
+func (t *labelled[K, T]) newNode(item T, label uint64) *labelledNode[T] {
+	w := 0
+	if t.weight != nil {
+		w = t.weight(item)
+	}
+	return &labelledNode[T]{item: item, label: label, priority: rand.Uint32(), size: 1, own: w, weight: w}
+}
+
+// inserts an item at position i
+func (t *labelled[K, T]) insert(i int, item T) {
+	lo, hi := t.labelsAround(i)
+	if hi-lo < 2 {
+		t.relabel(i, item)
+		return
+	}
+	label := lo + (hi-lo)/2
+	if hi == math.MaxUint64 && label-lo > labelSpacing {
+		label = lo + labelSpacing
+	}
+
+	l, r := split(t.root, i)
+	t.root = merge(merge(l, t.newNode(item, label)), r)
+	t.index.Set(t.key(item), label)
+}
+
+// removes the item at position i
+func (t *labelled[K, T]) remove(i int) {
+	l, r := split(t.root, i)
+	m, r := split(r, 1)
+	if m != nil {
+		t.index.Delete(t.key(m.item))
+	}
+	t.root = merge(l, r)
+}
+
+// replaces the item at position i, the key of the item must not change
+func (t *labelled[K, T]) set(i int, item T) {
+	t.root = t.setAt(t.root, i, item)
+}
+
+// copy of a tree with the item at position i replaced
+func (t *labelled[K, T]) setAt(n *labelledNode[T], i int, item T) *labelledNode[T] {
+	if n == nil {
+		panic("sequence index out of range")
+	}
+	l := size(n.left)
+	if i < l {
+		return n.with(t.setAt(n.left, i, item), n.right)
+	} else if i > l {
+		return n.with(n.left, t.setAt(n.right, i-l-1, item))
+	}
+	c := t.newNode(item, n.label)
+	c.priority = n.priority
+	return c.with(n.left, n.right)
+}
+
+// returns the items of the tree in order
+func (t *labelled[K, T]) items() []T {
+	items := make([]T, 0, t.len())
+	walk(t.root, func(n *labelledNode[T]) {
+		items = append(items, n.item)
+	})
+	return items
+}
+
+// labels of the items before and after position i
+func (t *labelled[K, T]) labelsAround(i int) (uint64, uint64) {
+	lo, hi := uint64(0), uint64(math.MaxUint64)
+	if i > 0 {
+		lo = t.nodeAt(i - 1).label
+	}
+	if i < t.len() {
+		hi = t.nodeAt(i).label
+	}
+	return lo, hi
+}
+
+// inserts an item at position i when there is no free label between its neighbours
+// the labels of the smallest range of items around i with enough free labels are spread evenly
+func (t *labelled[K, T]) relabel(i int, item T) {
+	start, end := i, i
+	var lo, hi uint64
+	for w := 2; ; w *= 2 {
+		start, end = i-w/2, i+w/2
+		if start < 0 {
+			start = 0
+		}
+		if end > t.len() {
+			end = t.len()
+		}
+		lo, hi = t.labelsAround(start)
+		if end < t.len() {
+			hi = t.nodeAt(end).label
+		} else {
+			hi = math.MaxUint64
+		}
+		count := uint64(end - start + 1)
+		if (start == 0 && end == t.len()) || (hi-lo)/(count+1) > count {
+			break
+		}
+	}
+
+	l, r := split(t.root, start)
+	m, r := split(r, end-start)
+
+	items := make([]T, 0, end-start+1)
+	walk(m, func(n *labelledNode[T]) {
+		items = append(items, n.item)
+	})
+	items = append(items[:i-start], append([]T{item}, items[i-start:]...)...)
+
+	gap := (hi - lo) / uint64(len(items)+1)
+	var mid *labelledNode[T]
+	for j, u := range items {
+		label := lo + gap*uint64(j+1)
+		mid = merge(mid, t.newNode(u, label))
+		t.index.Set(t.key(u), label)
+	}
+	t.root = merge(merge(l, mid), r)
+}
+
+// calls f for every node of a tree in order
+func walk[T any](n *labelledNode[T], f func(n *labelledNode[T])) {
+	if n == nil {
+		return
+	}
+	walk(n.left, f)
+	f(n)
+	walk(n.right, f)
+}
+
+// splits a tree in the first k items and the rest
+func split[T any](n *labelledNode[T], k int) (*labelledNode[T], *labelledNode[T]) {
+	if n == nil {
+		return nil, nil
+	}
+	if size(n.left) >= k {
+		l, r := split(n.left, k)
+		return l, n.with(r, n.right)
+	}
+	l, r := split(n.right, k-size(n.left)-1)
+	return n.with(n.left, l), r
+}
+
+// merges two trees where all items of a come before the ones of b
+func merge[T any](a *labelledNode[T], b *labelledNode[T]) *labelledNode[T] {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.priority > b.priority {
+		return a.with(a.left, merge(a.right, b))
+	}
+	return b.with(merge(a, b.left), b.right)
+}
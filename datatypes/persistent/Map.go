@@ -0,0 +1,278 @@
+package persistent
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+)
+
+// bits of the hash consumed by each level of the trie
+const (
+	levelBits = 5
+	levelMask = 1<<levelBits - 1
+)
+
+type pair[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// pairs of the map with the same hash
+type leaf[K comparable, V any] struct {
+	hash   uint64
+	bucket []pair[K, V]
+}
+
+// slot of a trie node, either a subtree or a leaf
+type slot[K comparable, V any] struct {
+	child *node[K, V]
+	leaf  *leaf[K, V]
+}
+
+// node of the trie, only the slots set in bitmap are stored
+// nodes are never modified after being created, updates copy the path from the root
+type node[K comparable, V any] struct {
+	bitmap uint32
+	slots  []slot[K, V]
+}
+
+// Map is a persistent hash map (hash array mapped trie)
+// copies share all the nodes and are O(1), updates only allocate the nodes in the path of the key
+type Map[K comparable, V any] struct {
+	root *node[K, V]
+	size int
+	hash func(K) uint64
+}
+
+// returns a new map that hashes keys with Hash
+func NewMap[K comparable, V any]() *Map[K, V] {
+	return NewMapWithHash[K, V](func(k K) uint64 { return Hash(k) })
+}
+
+// returns a new map that hashes keys with the given function
+func NewMapWithHash[K comparable, V any](hash func(K) uint64) *Map[K, V] {
+	return &Map[K, V]{root: &node[K, V]{}, hash: hash}
+}
+
+// hash of a key, keys of other types than integers and strings are hashed by their printed value
+func Hash(k any) uint64 {
+	switch k := k.(type) {
+	case int:
+		return mix(uint64(k))
+	case int32:
+		return mix(uint64(k))
+	case int64:
+		return mix(uint64(k))
+	case uint:
+		return mix(uint64(k))
+	case uint32:
+		return mix(uint64(k))
+	case uint64:
+		return mix(k)
+	case string:
+		return HashString(k)
+	}
+	return HashString(fmt.Sprintf("%T%+v", k, k))
+}
+
+// hash of a string
+func HashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// spreads the bits of an integer so consecutive values do not share trie paths
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// number of keys in the map
+func (m *Map[K, V]) Len() int {
+	return m.size
+}
+
+// returns the value of a key
+func (m *Map[K, V]) Get(k K) (V, bool) {
+	h := m.hash(k)
+	n := m.root
+	for shift := uint(0); ; shift += levelBits {
+		bit := uint32(1) << ((h >> shift) & levelMask)
+		if n.bitmap&bit == 0 {
+			break
+		}
+		s := n.slots[bits.OnesCount32(n.bitmap&(bit-1))]
+		if s.child == nil {
+			if s.leaf.hash == h {
+				for _, p := range s.leaf.bucket {
+					if p.key == k {
+						return p.value, true
+					}
+				}
+			}
+			break
+		}
+		n = s.child
+	}
+	var zero V
+	return zero, false
+}
+
+// check if the map has a key
+func (m *Map[K, V]) Contains(k K) bool {
+	_, ok := m.Get(k)
+	return ok
+}
+
+// sets the value of a key
+func (m *Map[K, V]) Set(k K, v V) {
+	root, added := m.root.set(m.hash(k), 0, pair[K, V]{k, v})
+	m.root = root
+	if added {
+		m.size++
+	}
+}
+
+// removes a key
+func (m *Map[K, V]) Delete(k K) {
+	root, removed := m.root.delete(m.hash(k), 0, k)
+	if removed {
+		m.root = root
+		m.size--
+	}
+}
+
+// copy of the map, O(1) since nodes are shared
+func (m *Map[K, V]) Clone() *Map[K, V] {
+	c := *m
+	return &c
+}
+
+// calls f for every pair of the map until f returns false
+func (m *Map[K, V]) Range(f func(k K, v V) bool) {
+	m.root.walk(f)
+}
+
+// returns the keys of the map
+func (m *Map[K, V]) Keys() []K {
+	keys := make([]K, 0, m.size)
+	m.Range(func(k K, _ V) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+func (n *node[K, V]) set(h uint64, shift uint, p pair[K, V]) (*node[K, V], bool) {
+	bit := uint32(1) << ((h >> shift) & levelMask)
+	i := bits.OnesCount32(n.bitmap & (bit - 1))
+
+	if n.bitmap&bit == 0 {
+		c := &node[K, V]{bitmap: n.bitmap | bit, slots: make([]slot[K, V], len(n.slots)+1)}
+		copy(c.slots, n.slots[:i])
+		c.slots[i] = slot[K, V]{leaf: &leaf[K, V]{hash: h, bucket: []pair[K, V]{p}}}
+		copy(c.slots[i+1:], n.slots[i:])
+		return c, true
+	}
+
+	s := n.slots[i]
+	added := false
+	switch {
+	case s.child != nil:
+		s.child, added = s.child.set(h, shift+levelBits, p)
+	case s.leaf.hash == h || shift+levelBits >= 64:
+		//same hash (or no more bits to tell them apart), replace the key in the bucket or add it
+		bucket := make([]pair[K, V], 0, len(s.leaf.bucket)+1)
+		added = true
+		for _, q := range s.leaf.bucket {
+			if q.key == p.key {
+				added = false
+				continue
+			}
+			bucket = append(bucket, q)
+		}
+		s.leaf = &leaf[K, V]{hash: h, bucket: append(bucket, p)}
+	default:
+		//different hashes in the same position, push both down to a new node
+		child := &node[K, V]{bitmap: uint32(1) << ((s.leaf.hash >> (shift + levelBits)) & levelMask), slots: []slot[K, V]{s}}
+		s = slot[K, V]{}
+		s.child, added = child.set(h, shift+levelBits, p)
+	}
+
+	c := &node[K, V]{bitmap: n.bitmap, slots: make([]slot[K, V], len(n.slots))}
+	copy(c.slots, n.slots)
+	c.slots[i] = s
+	return c, added
+}
+
+func (n *node[K, V]) delete(h uint64, shift uint, k K) (*node[K, V], bool) {
+	bit := uint32(1) << ((h >> shift) & levelMask)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	i := bits.OnesCount32(n.bitmap & (bit - 1))
+
+	s := n.slots[i]
+	if s.child != nil {
+		child, removed := s.child.delete(h, shift+levelBits, k)
+		if !removed {
+			return n, false
+		}
+		if child.bitmap == 0 {
+			return n.without(i, bit), true
+		}
+		s.child = child
+	} else {
+		if s.leaf.hash != h {
+			return n, false
+		}
+		bucket := make([]pair[K, V], 0, len(s.leaf.bucket))
+		for _, q := range s.leaf.bucket {
+			if q.key != k {
+				bucket = append(bucket, q)
+			}
+		}
+		if len(bucket) == len(s.leaf.bucket) {
+			return n, false
+		}
+		if len(bucket) == 0 {
+			return n.without(i, bit), true
+		}
+		s.leaf = &leaf[K, V]{hash: h, bucket: bucket}
+	}
+
+	c := &node[K, V]{bitmap: n.bitmap, slots: make([]slot[K, V], len(n.slots))}
+	copy(c.slots, n.slots)
+	c.slots[i] = s
+	return c, true
+}
+
+// copy of the node without slot i
+func (n *node[K, V]) without(i int, bit uint32) *node[K, V] {
+	c := &node[K, V]{bitmap: n.bitmap &^ bit, slots: make([]slot[K, V], 0, len(n.slots)-1)}
+	c.slots = append(c.slots, n.slots[:i]...)
+	c.slots = append(c.slots, n.slots[i+1:]...)
+	return c
+}
+
+func (n *node[K, V]) walk(f func(k K, v V) bool) bool {
+	for _, s := range n.slots {
+		if s.child != nil {
+			if !s.child.walk(f) {
+				return false
+			}
+			continue
+		}
+		for _, p := range s.leaf.bucket {
+			if !f(p.key, p.value) {
+				return false
+			}
+		}
+	}
+	return true
+}
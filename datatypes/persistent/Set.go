@@ -0,0 +1,80 @@
+package persistent
+
+import "fmt"
+
+// Set is a persistent set, copies are O(1) and share the elements with the original
+type Set[T comparable] struct {
+	m *Map[T, struct{}]
+}
+
+// returns a new set with the given elements
+func NewSet[T comparable](elems ...T) *Set[T] {
+	s := &Set[T]{m: NewMap[T, struct{}]()}
+	for _, e := range elems {
+		s.Add(e)
+	}
+	return s
+}
+
+// adds an element, returns false if it was already in the set
+func (s *Set[T]) Add(e T) bool {
+	if s.m.Contains(e) {
+		return false
+	}
+	s.m.Set(e, struct{}{})
+	return true
+}
+
+// removes an element
+func (s *Set[T]) Remove(e T) {
+	s.m.Delete(e)
+}
+
+// check if the set has all the given elements
+func (s *Set[T]) Contains(elems ...T) bool {
+	for _, e := range elems {
+		if !s.m.Contains(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// number of elements in the set
+func (s *Set[T]) Cardinality() int {
+	return s.m.Len()
+}
+
+// copy of the set, O(1)
+func (s *Set[T]) Clone() *Set[T] {
+	return &Set[T]{m: s.m.Clone()}
+}
+
+// check if two sets have the same elements
+func (s *Set[T]) Equal(other *Set[T]) bool {
+	if s.Cardinality() != other.Cardinality() {
+		return false
+	}
+	equal := true
+	s.m.Range(func(e T, _ struct{}) bool {
+		equal = other.m.Contains(e)
+		return equal
+	})
+	return equal
+}
+
+// calls f for every element of the set until f returns false
+func (s *Set[T]) Each(f func(e T) bool) {
+	s.m.Range(func(e T, _ struct{}) bool {
+		return f(e)
+	})
+}
+
+// returns the elements of the set
+func (s *Set[T]) ToSlice() []T {
+	return s.m.Keys()
+}
+
+func (s *Set[T]) String() string {
+	return "Set" + fmt.Sprint(s.ToSlice())
+}
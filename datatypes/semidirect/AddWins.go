@@ -3,27 +3,27 @@ package datatypes
 import (
 	"library/packages/communication"
 	"library/packages/crdt"
+	"library/packages/datatypes"
+	"library/packages/datatypes/persistent"
 	"library/packages/replica"
-
-	mapset "github.com/deckarep/golang-set/v2"
 )
 
 type AddWins struct {
 	id string
 }
 
-func (a AddWins) Add(state mapset.Set[any], elem any) mapset.Set[any] {
+func (a AddWins) Add(state *persistent.Set[any], elem any) *persistent.Set[any] {
 	state.Add(elem.(communication.Operation).Value)
 	return state
 }
 
-func (a AddWins) Remove(state mapset.Set[any], elem any) mapset.Set[any] {
+func (a AddWins) Remove(state *persistent.Set[any], elem any) *persistent.Set[any] {
 	state.Remove(elem.(communication.Operation).Value)
 	return state
 }
 
 func (a AddWins) Apply(state any, operations []communication.Operation) any {
-	st := state.(mapset.Set[any]).Clone()
+	st := state.(*persistent.Set[any]).Clone()
 	for _, op := range operations {
 		switch op.Type {
 		case "Add":
@@ -37,6 +37,11 @@ func (a AddWins) Apply(state any, operations []communication.Operation) any {
 	return st
 }
 
+// elements added and removed between two states
+func (a AddWins) Delta(from any, to any) any {
+	return datatypes.NewSetDelta(from.(*persistent.Set[any]), to.(*persistent.Set[any]))
+}
+
 func (a AddWins) Repair(op1 communication.Operation, op2 communication.Operation) communication.Operation {
 	//removes come before adds
 	//we have to classes of updates: add and rem, and adds have priority over rems
@@ -61,7 +66,7 @@ func (a AddWins) ArbitrationConstraint(op communication.Operation) bool {
 // initialize counter replica
 func NewAddWinsReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
 
-	c := crdt.SemidirectCRDT{Id: id, Data: AddWins{id}, Unstable_operations: []communication.Operation{}, Unstable_st: mapset.NewSet[any](), N_Ops: 0}
+	c := crdt.SemidirectCRDT{Id: id, Data: AddWins{id}, Unstable_operations: []communication.Operation{}, Unstable_st: persistent.NewSet[any](), N_Ops: 0}
 
 	return replica.NewReplica(id, &c, channels, delay)
 }
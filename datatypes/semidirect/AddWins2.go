@@ -3,52 +3,64 @@ package datatypes
 import (
 	"library/packages/communication"
 	"library/packages/crdt"
+	"library/packages/datatypes/persistent"
 	"library/packages/replica"
 	"strconv"
-
-	mapset "github.com/deckarep/golang-set/v2"
 )
 
+// remove of a value that keeps the adds in T, the ids of the adds repaired against it
 type RemValue struct {
 	Value any
-	T     mapset.Set[int]
-}
-
-type AddValue struct {
-	Value any
-	t     int
+	T     *persistent.Set[int]
 }
 
+// add-wins set whose state maps every element to the ids of its adds that were not removed
 type AddWins2 struct {
 	id string
 }
 
-func (a *AddWins2) Add(state mapset.Set[AddValue], op communication.Operation) mapset.Set[AddValue] {
+func (a *AddWins2) Add(state *persistent.Map[any, *persistent.Set[int]], op communication.Operation) *persistent.Map[any, *persistent.Set[int]] {
 	id, _ := strconv.Atoi(strconv.Itoa(int(op.Version.Sum())) + op.OriginID)
-	state.Add(AddValue{op.Value, id})
+	ids, ok := state.Get(op.Value)
+	if ok {
+		ids = ids.Clone()
+	} else {
+		ids = persistent.NewSet[int]()
+	}
+	ids.Add(id)
+	state.Set(op.Value, ids)
 	return state
 }
 
-func (a AddWins2) Remove(state mapset.Set[AddValue], op communication.Operation) mapset.Set[AddValue] {
+func (a AddWins2) Remove(state *persistent.Map[any, *persistent.Set[int]], op communication.Operation) *persistent.Map[any, *persistent.Set[int]] {
 	opValue, repaired := op.Value.(RemValue)
 	if !repaired {
-		opValue = RemValue{op.Value, mapset.NewSet[int]()}
+		opValue = RemValue{op.Value, persistent.NewSet[int]()}
 	}
 
-	for _, v := range state.ToSlice() {
-
-		if v.Value == opValue.Value && !opValue.T.Contains(v.t) {
-			//remove element from S
-			state.Remove(AddValue{v.Value, v.t})
+	ids, ok := state.Get(opValue.Value)
+	if !ok {
+		return state
+	}
+	//the adds in T were repaired against the remove and stay
+	kept := persistent.NewSet[int]()
+	ids.Each(func(id int) bool {
+		if opValue.T.Contains(id) {
+			kept.Add(id)
 		}
-
+		return true
+	})
+	if kept.Cardinality() == 0 {
+		state.Delete(opValue.Value)
+	} else {
+		state.Set(opValue.Value, kept)
 	}
 
 	return state
 }
 
 func (a AddWins2) Apply(state any, operations []communication.Operation) any {
-	st := state.(mapset.Set[AddValue]).Clone()
+	st := state.(*persistent.Map[any, *persistent.Set[int]]).Clone()
 	for _, op := range operations {
 		switch op.Type {
 		case "Add":
@@ -60,18 +72,29 @@ func (a AddWins2) Apply(state any, operations []communication.Operation) any {
 	return st
 }
 
+// elements of the set
+func (a AddWins2) Query(state any) any {
+	elements := persistent.NewSet[any]()
+	state.(*persistent.Map[any, *persistent.Set[int]]).Range(func(e any, ids *persistent.Set[int]) bool {
+		elements.Add(e)
+		return true
+	})
+	return elements
+}
+
 func (a AddWins2) Repair(op1 communication.Operation, op2 communication.Operation) communication.Operation {
 	if op1.Type == "Add" && op2.Type == "Rem" {
 
 		remValue, repaired := op2.Value.(RemValue)
 		if !repaired {
-			remValue = RemValue{op2.Value, mapset.NewSet[int]()}
+			remValue = RemValue{op2.Value, persistent.NewSet[int]()}
 		}
 
 		if op1.Value == remValue.Value {
 
 			id1, _ := strconv.Atoi(strconv.Itoa(int(op1.Version.Sum())) + op1.OriginID)
-			remValue.T.Add(id1) //adds add timestamp to T of remove
+			remValue.T = remValue.T.Clone() //the remove can be repaired against other adds from the same T
+			remValue.T.Add(id1)             //adds add timestamp to T of remove
 			return communication.Operation{Type: "Rem", Value: remValue, Version: op2.Version}
 
 		}
@@ -87,7 +110,7 @@ func (a AddWins2) ArbitrationConstraint(op communication.Operation) bool {
 // initialize counter replica
 func NewAddWins2Replica(id string, channels map[string]chan any, delay int) *replica.Replica {
 
-	c := crdt.SemidirectCRDT{Id: id, Data: AddWins2{id}, Unstable_operations: []communication.Operation{}, Unstable_st: mapset.NewSet[AddValue](), N_Ops: 0}
+	c := crdt.SemidirectCRDT{Id: id, Data: AddWins2{id}, Unstable_operations: []communication.Operation{}, Unstable_st: persistent.NewMap[any, *persistent.Set[int]](), N_Ops: 0}
 
 	return replica.NewReplica(id, &c, channels, delay)
 }
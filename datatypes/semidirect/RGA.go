@@ -3,61 +3,61 @@ package datatypes
 import (
 	"library/packages/communication"
 	"library/packages/crdt"
+	"library/packages/datatypes"
 	"library/packages/replica"
 	"strconv"
 )
 
-type RGAOpValue struct {
-	V     Vertex //on an insert, the vertex to insert after, on a remove, the vertex to remove
-	Value any
-}
+type RGAOpValue = datatypes.RGAOpValue
 
 // rga definition
-type Vertex struct {
-	Timestamp any
-	Value     any
-	OriginID  string
-}
+type Vertex = datatypes.Vertex
 
 type RGA struct {
 	Id string
 }
 
 func (r RGA) Apply(state any, operations []communication.Operation) any {
-	stCpy := RGACopy(state.([]Vertex))
+	stCpy := state.(*datatypes.Sequence).Copy()
 
 	for _, op := range operations {
 		msg := op
 		switch msg.Type {
 		case "Add":
-			newVertex := Vertex{msg.Version, msg.Value.(RGAOpValue).Value, msg.OriginID}
+			newVertex := Vertex{Timestamp: msg.Version, Value: msg.Value.(RGAOpValue).Value, OriginID: msg.OriginID}
 			newVertexPrev := msg.Value.(RGAOpValue).V
 
 			// find index where predecessor vertex can be found
-			predecessorIdx := indexOfVPtr(newVertexPrev, stCpy)
+			predecessorIdx := stCpy.IndexOf(newVertexPrev)
 
 			// if predecessor vertex is not found, insert on root
 			if predecessorIdx == -1 {
 				predecessorIdx = 0
 			}
 
-			newVertices := append(stCpy[:predecessorIdx+1], append([]Vertex{newVertex}, stCpy[predecessorIdx+1:]...)...)
-
-			stCpy = newVertices
+			stCpy.Insert(predecessorIdx+1, newVertex)
 		case "Rem":
 			removeVertex := msg.Value.(RGAOpValue).V
 			// find index where removed vertex can be found and clear its content to tombstone it
-			index := indexOfVPtr(removeVertex, stCpy)
+			index := stCpy.IndexOf(removeVertex)
 			if index == -1 {
 				continue
 			}
-			newVertices := append(stCpy[:index], stCpy[index+1:]...)
-			stCpy = newVertices
+			stCpy.Remove(index)
 		}
 	}
 	return stCpy
 }
 
+func (r RGA) Query(state any) any {
+	return state.(*datatypes.Sequence).Vertices()
+}
+
+// ranges inserted and deleted between two states
+func (r RGA) Delta(from any, to any) any {
+	return datatypes.NewSequenceDelta(from.([]datatypes.Vertex), to.([]datatypes.Vertex))
+}
+
 func (r RGA) ArbitrationOrder(op1 communication.Operation, op2 communication.Operation, state any) (bool, bool) {
 	//log.Println(r.Id, "ARBITRATIONORDER", op1, op2)
 
@@ -84,7 +84,7 @@ func (r RGA) Repair(op1 communication.Operation, op2 communication.Operation, st
 
 	ordered := true
 	//ef1 := r.effectivePos(op1.Value.(RGAOpValue).V, state.([]Vertex))
-	ef2 := r.effectivePos(op2.Value.(RGAOpValue).V, state.([]Vertex))
+	ef2 := r.effectivePos(op2.Value.(RGAOpValue).V, state.(*datatypes.Sequence))
 	if op1.Value.(RGAOpValue).V.Timestamp.(communication.VClock).Equal(ef2.Timestamp.(communication.VClock)) {
 		//arbitration order by ids
 		id1, _ := strconv.Atoi(strconv.Itoa(int(op1.Version.Sum())) + op1.OriginID)
@@ -99,11 +99,11 @@ func (r RGA) Repair(op1 communication.Operation, op2 communication.Operation, st
 			Type:    op2.Type,
 			Version: op2.Version,
 			Value: RGAOpValue{
-				Vertex{
+				V: Vertex{
 					Timestamp: op1.Version,
 					OriginID:  op1.OriginID,
 				},
-				op2.Value.(RGAOpValue).Value,
+				Value: op2.Value.(RGAOpValue).Value,
 			},
 			OriginID: op2.OriginID,
 		}
@@ -120,10 +120,10 @@ func (r RGA) RepairCausal(op1 communication.Operation, op2 communication.Operati
 			Type:    op2.Type,
 			Version: op2.Version,
 			Value: RGAOpValue{
-				Vertex{
+				V: Vertex{
 					Timestamp: communication.NewVClockFromMap(map[string]uint64{}),
 				},
-				op2.Value.(RGAOpValue).Value,
+				Value: op2.Value.(RGAOpValue).Value,
 			},
 			OriginID: op2.OriginID,
 		}
@@ -138,20 +138,11 @@ func (r RGA) MainOp() string {
 // initialize RGA
 func NewRGAReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
 
-	r := crdt.NewSemidirect2CRDT(id, []Vertex{{communication.NewVClockFromMap(map[string]uint64{}), "", id}}, RGA{id})
+	r := crdt.NewSemidirect2CRDT(id, datatypes.NewSequence(Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: id}), RGA{id})
 
 	return replica.NewReplica(id, r, channels, delay)
 }
 
-func indexOfVPtr(vertex Vertex, vertices []Vertex) int {
-	for i, v := range vertices {
-		if vertex.Timestamp.(communication.VClock).Equal(v.Timestamp.(communication.VClock)) {
-			return i
-		}
-	}
-	return -1
-}
-
 // check if two array of vertices are equal
 func RGAEqual(vertices1 []Vertex, vertices2 []Vertex) bool {
 	if len(vertices1) != len(vertices2) {
@@ -171,22 +162,9 @@ func RGAEqual(vertices1 []Vertex, vertices2 []Vertex) bool {
 	return true
 }
 
-// deep copy state of RGA
-func RGACopy(state []Vertex) []Vertex {
-	stCpy := make([]Vertex, len(state))
-	for i, v := range state {
-		stCpy[i].Value = v.Value
-		stCpy[i].Timestamp = v.Timestamp.(communication.VClock).Copy()
-		stCpy[i].OriginID = v.OriginID
-	}
-	return stCpy
-}
-
-func (r RGA) effectivePos(prevV Vertex, state []Vertex) Vertex {
-	for _, v := range state {
-		if v.Timestamp.(communication.VClock).Equal(prevV.Timestamp.(communication.VClock)) {
-			return prevV
-		}
+func (r RGA) effectivePos(prevV Vertex, state *datatypes.Sequence) Vertex {
+	if state.Contains(prevV) {
+		return prevV
 	}
-	return Vertex{communication.NewVClockFromMap(map[string]uint64{}), "", r.Id}
+	return Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: r.Id}
 }
@@ -0,0 +1,49 @@
+package datatypes
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	"library/packages/datatypes/persistent"
+	"library/packages/replica"
+)
+
+// RemoveWins is a set whose removes win over concurrent adds of the same element
+type RemoveWins struct {
+	AddWins
+}
+
+func (r RemoveWins) Repair(op1 communication.Operation, op2 communication.Operation) communication.Operation {
+	//removes have priority over adds, so the result of repairing an add with a concurrent remove is Nop
+
+	if op1.Type == "Rem" && op2.Type == "Add" && op1.Value == op2.Value {
+		return communication.Operation{Type: "Nop", Value: nil, Version: op2.Version}
+	}
+
+	return op2
+}
+
+func (r RemoveWins) ArbitrationConstraint(op communication.Operation) bool {
+	return op.Type == "Rem"
+}
+
+// returns the set data with the given bias
+func NewSet(id string, bias datatypes.Bias) crdt.SemidirectDataI {
+	if bias == datatypes.RemoveBias {
+		return RemoveWins{AddWins{id}}
+	}
+	return AddWins{id}
+}
+
+// initialize remove-wins set replica
+func NewRemoveWinsReplica(id string, channels map[string]chan any, delay int) *replica.Replica {
+	return NewSetReplica(id, datatypes.RemoveBias, channels, delay)
+}
+
+// initialize set replica with the given bias
+func NewSetReplica(id string, bias datatypes.Bias, channels map[string]chan any, delay int) *replica.Replica {
+
+	c := crdt.SemidirectCRDT{Id: id, Data: NewSet(id, bias), Unstable_operations: []communication.Operation{}, Unstable_st: persistent.NewSet[any](), N_Ops: 0}
+
+	return replica.NewReplica(id, &c, channels, delay)
+}
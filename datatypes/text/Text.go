@@ -0,0 +1,231 @@
+package text
+
+import (
+	"fmt"
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	"library/packages/replica"
+	"strings"
+	"sync"
+)
+
+// kind of change of a text
+type EventType int
+
+const (
+	Insert EventType = iota
+	Delete
+)
+
+// Event is a change of a text at a visible position, every operation inserts or deletes one character
+type Event struct {
+	Type  EventType
+	Index int
+	Text  string
+}
+
+// Engine is an RGA engine whose optimistic state is a sequence of vertices, e.g. crdt.NewEcroCRDT(id, datatypes.NewSequence(root), ecro.RGA{Id: id})
+type Engine interface {
+	replica.CrdtI
+	crdt.StateI
+}
+
+// Text is a string edited by visible positions on top of an RGA engine
+// the engine holds a sequence whose first vertex is the root and whose other vertices hold one character each,
+// Text keeps the vertices of the visible characters indexed to map positions to vertices and turns operations into events for its observers
+type Text struct {
+	engine    Engine
+	replica   *replica.Replica
+	visible   *datatypes.Sequence //vertices of the visible characters after the last operation
+	observers []func(Event)
+	lock      *sync.Mutex
+	editLock  *sync.Mutex //orders the edits of clients of the replica
+}
+
+// returns a text on top of an RGA engine of any type
+func NewText(engine Engine) *Text {
+	t := &Text{engine: engine, visible: datatypes.NewSequence(), lock: new(sync.Mutex), editLock: new(sync.Mutex)}
+	st, _ := engine.Read(replica.Optimistic)
+	for i, v := range characters(st.([]datatypes.Vertex)) {
+		t.visible.Insert(i, v)
+	}
+	return t
+}
+
+// initialize a replica editing the text
+func NewTextReplica(id string, text *Text, channels map[string]chan any, delay int) *replica.Replica {
+	r := replica.NewReplica(id, text, channels, delay)
+	text.replica = r
+	return r
+}
+
+// calls f with the events of every operation delivered to the text, local or remote
+// f is called while the operation is delivered, so it must not edit the text
+func (t *Text) Observe(f func(Event)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.observers = append(t.observers, f)
+}
+
+// inserts s before the character at index, or at the end if index is the length of the text
+func (t *Text) InsertAt(index int, s string) error {
+	t.editLock.Lock()
+	defer t.editLock.Unlock()
+
+	prev, err := t.before(index)
+	if err != nil {
+		return err
+	}
+	for _, c := range s {
+		op := t.replica.Prepare("Add", datatypes.RGAOpValue{Value: string(c), V: prev})
+		prev = datatypes.Vertex{Timestamp: op.Version, Value: string(c), OriginID: op.OriginID}
+	}
+	return nil
+}
+
+// deletes length characters starting at index
+func (t *Text) DeleteRange(index int, length int) error {
+	t.editLock.Lock()
+	defer t.editLock.Unlock()
+
+	removed, err := t.between(index, length)
+	if err != nil {
+		return err
+	}
+	for _, v := range removed {
+		t.replica.Prepare("Rem", datatypes.RGAOpValue{V: v})
+	}
+	return nil
+}
+
+// vertex an insert at index goes after, the root if index is 0
+func (t *Text) before(index int) (datatypes.Vertex, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if n := t.visible.Len(); index < 0 || index > n {
+		return datatypes.Vertex{}, fmt.Errorf("text: insert at %d is out of range of %d characters", index, n)
+	}
+	if index == 0 {
+		return t.root(), nil
+	}
+	return t.visible.At(index - 1), nil
+}
+
+// vertices of the length characters starting at index
+func (t *Text) between(index int, length int) ([]datatypes.Vertex, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if n := t.visible.Len(); index < 0 || length < 0 || index+length > n {
+		return nil, fmt.Errorf("text: delete of %d characters at %d is out of range of %d characters", length, index, n)
+	}
+	vertices := make([]datatypes.Vertex, length)
+	for i := range vertices {
+		vertices[i] = t.visible.At(index + i)
+	}
+	return vertices, nil
+}
+
+// text with all delivered operations
+func (t *Text) String() string {
+	st, _ := t.Read(replica.Optimistic)
+	return st.(string)
+}
+
+// number of characters of the text
+func (t *Text) Len() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.visible.Len()
+}
+
+// the event of an operation is found from the position of its vertex, an insert goes after the closest visible vertex before it in the engine
+func (t *Text) Effect(op communication.Operation) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.engine.Effect(op)
+	value, ok := op.Value.(datatypes.RGAOpValue)
+	if !ok {
+		return
+	}
+
+	var e Event
+	switch op.Type {
+	case "Add":
+		v := datatypes.Vertex{Timestamp: op.Version, Value: value.Value, OriginID: op.OriginID}
+		index := t.visibleBefore(v)
+		t.visible.Insert(index, v)
+		e = Event{Type: Insert, Index: index, Text: value.Value.(string)}
+	case "Rem":
+		index := t.visible.IndexOf(value.V)
+		if index == -1 {
+			return
+		}
+		e = Event{Type: Delete, Index: index, Text: t.visible.At(index).Value.(string)}
+		t.visible.Remove(index)
+	default:
+		return
+	}
+	for _, f := range t.observers {
+		f(e)
+	}
+}
+
+func (t *Text) Stabilize(op communication.Operation) {
+	t.engine.Stabilize(op)
+}
+
+// text at level and the version it reflects
+func (t *Text) Read(level replica.Level) (any, communication.VClock) {
+	st, version := t.engine.Read(level)
+	return join(characters(st.([]datatypes.Vertex))), version
+}
+
+func (t *Text) NumOps() uint64 {
+	return t.engine.NumOps()
+}
+
+func (t *Text) NumSOps() uint64 {
+	return t.engine.NumSOps()
+}
+
+// number of visible characters before a vertex of the sequence of the engine,
+// the tombstones and removed vertices before it are skipped until a visible one
+func (t *Text) visibleBefore(v datatypes.Vertex) int {
+	seq := t.engine.OptimisticState().(*datatypes.Sequence)
+	for i := seq.IndexOf(v) - 1; i > 0; i-- {
+		if index := t.visible.IndexOf(seq.At(i)); index != -1 {
+			return index + 1
+		}
+	}
+	return 0
+}
+
+// root vertex of the sequence of the engine
+func (t *Text) root() datatypes.Vertex {
+	return t.engine.OptimisticState().(*datatypes.Sequence).At(0)
+}
+
+// vertices after the root
+func characters(vertices []datatypes.Vertex) []datatypes.Vertex {
+	if len(vertices) == 0 {
+		return vertices
+	}
+	return vertices[1:]
+}
+
+// characters of the visible vertices
+func join(vertices []datatypes.Vertex) string {
+	var b strings.Builder
+	for _, v := range vertices {
+		if v.Value != nil {
+			b.WriteString(v.Value.(string))
+		}
+	}
+	return b.String()
+}
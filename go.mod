@@ -2,14 +2,16 @@ module library/packages
 
 go 1.20
 
-require github.com/deckarep/golang-set/v2 v2.3.0
+require (
+	github.com/deckarep/golang-set/v2 v2.3.0
+	github.com/dominikbraun/graph v0.22.0
+	github.com/jmcvetta/randutil v0.0.0-20150817122601-2bb1b664bcff
+)
 
 require (
 	github.com/chzyer/readline v1.5.1 // indirect
-	github.com/dominikbraun/graph v0.22.0 // indirect
 	github.com/emicklei/dot v1.4.2 // indirect
 	github.com/google/pprof v0.0.0-20230602150820-91b7bce49751 // indirect
 	github.com/ianlancetaylor/demangle v0.0.0-20230524184225-eabc099b10ab // indirect
-	github.com/jmcvetta/randutil v0.0.0-20150817122601-2bb1b664bcff // indirect
 	golang.org/x/sys v0.1.0 // indirect
 )
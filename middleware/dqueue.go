@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"library/packages/communication"
+)
+
+// Delivery queue of messages that dont have causal predecessors yet, indexed by origin and sequence number
+type DQueue struct {
+	pending map[string]map[uint64]communication.Message // origin -> sequence number -> message
+	waiting map[string][]string                         // origin -> origins whose head waits for a message of that origin
+	size    int
+}
+
+// returns a new empty delivery queue
+func NewDQueue() DQueue {
+	return DQueue{
+		pending: make(map[string]map[uint64]communication.Message),
+		waiting: make(map[string][]string),
+	}
+}
+
+// adds a message to the queue
+func (dq *DQueue) Add(msg communication.Message) {
+	seq := msg.Version.FindTicks(msg.OriginID)
+	if dq.pending[msg.OriginID] == nil {
+		dq.pending[msg.OriginID] = make(map[uint64]communication.Message)
+	}
+	if _, ok := dq.pending[msg.OriginID][seq]; !ok {
+		dq.size++
+	}
+	dq.pending[msg.OriginID][seq] = msg
+}
+
+// returns the message of origin with the given sequence number
+func (dq *DQueue) Get(origin string, seq uint64) (communication.Message, bool) {
+	msg, ok := dq.pending[origin][seq]
+	return msg, ok
+}
+
+// removes the message of origin with the given sequence number
+func (dq *DQueue) Remove(origin string, seq uint64) {
+	if _, ok := dq.pending[origin][seq]; !ok {
+		return
+	}
+	delete(dq.pending[origin], seq)
+	if len(dq.pending[origin]) == 0 {
+		delete(dq.pending, origin)
+	}
+	dq.size--
+}
+
+// registers that the head of origin is blocked until a message of dependency is delivered
+func (dq *DQueue) Wait(origin string, dependency string) {
+	dq.waiting[dependency] = append(dq.waiting[dependency], origin)
+}
+
+// returns and clears the origins whose head was blocked on a message of dependency
+func (dq *DQueue) Woken(dependency string) []string {
+	origins := dq.waiting[dependency]
+	delete(dq.waiting, dependency)
+	return origins
+}
+
+// number of messages in the queue
+func (dq *DQueue) Len() int {
+	return dq.size
+}
@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"library/packages/communication"
-	"library/packages/utils"
 	"math/rand"
 	"sort"
 	"sync"
@@ -29,11 +28,6 @@ type SMap struct {
 	m map[StableDotKey]StableDotValue
 }
 
-type Min struct {
-	*sync.RWMutex
-	m map[string]string
-}
-
 type Middleware struct {
 	replica          string                      // replica id
 	channels         map[string]chan interface{} // all channels of the universe
@@ -42,11 +36,10 @@ type Middleware struct {
 	ReceivedVersion  communication.VClock        // last received vector clock
 	Tcbcast          chan communication.Message  // channel to receive messages from replica
 	DeliverCausal    chan communication.Message  // channel to causal deliver messages to replica
-	DQ               []communication.Message     // Delivery queue to add messages that dont have causal predecessors yet
+	DQ               DQueue                      // Delivery queue to add messages that dont have causal predecessors yet
 	Observed         VClocks                     // vector versions of observed universe
 	StableVersion    communication.VClock        // stable vector version
 	SMap             SMap                        // Messages delivered to replica but not yet stable (stable dots)
-	Min              Min                         // Replicas with the min vector
 	Ctr              uint64                      // order messages on stable delivery
 
 	Delay           int            // number of messages to delay for debug reasons
@@ -68,10 +61,10 @@ func NewMiddleware(id string, ids []string, channels map[string]chan interface{}
 		ReceivedVersion:  communication.InitVClock(ids),
 		Tcbcast:          make(chan communication.Message),
 		DeliverCausal:    make(chan communication.Message),
+		DQ:               NewDQueue(),
 		Observed:         InitVClocks(ids),
 		StableVersion:    communication.InitVClock(ids),
 		SMap:             SMap{RWMutex: new(sync.RWMutex), m: map[StableDotKey]StableDotValue{}},
-		Min:              Min{RWMutex: new(sync.RWMutex), m: utils.InitMin(ids)},
 		Ctr:              0,
 
 		Rand:            rand.New(rand.NewSource(time.Now().UnixNano())),
@@ -151,45 +144,43 @@ func (mw *Middleware) receive() {
 	}
 }
 
-// checks DQ to see if new messages can be delivered
-func (mw *Middleware) deliver() {
-	from := 0
-	to := 0
-	for {
-		if from >= len(mw.DQ) {
-			if to >= from {
-				break
-			}
-			mw.DQ = mw.DQ[:to]
-			if len(mw.DQ) == 0 {
-				break
-			}
-			from = 0
-			to = 0
-		} else {
-			msg := mw.DQ[from]
-			if msg.Version.FindTicks(msg.OriginID) == mw.DeliveredVersion.FindTicks(msg.OriginID)+1 && allCausalPredecessorsDelivered(msg.Version, mw.DeliveredVersion, msg.OriginID) {
-				mw.DeliveredVersion.Tick(msg.OriginID)
-				msg := communication.NewMessage(communication.DLV, msg.Operation.Type, msg.Value, msg.Version, msg.OriginID)
-				mw.DeliverCausal <- msg
-				mw.updatestability(msg)
-			} else {
-				mw.DQ[to] = mw.DQ[from]
-				to++
-			}
-			from++
+// checks DQ to see if new messages can be delivered, starting from the head of origin j
+// after each delivery only the next head of that origin and the heads that were waiting for it are checked
+func (mw *Middleware) deliver(j string) {
+	candidates := []string{j}
+	for len(candidates) > 0 {
+		k := candidates[len(candidates)-1]
+		candidates = candidates[:len(candidates)-1]
+
+		next := mw.DeliveredVersion.FindTicks(k) + 1
+		msg, ok := mw.DQ.Get(k, next)
+		if !ok {
+			continue
+		}
+		if dependency, blocked := missingCausalPredecessor(msg.Version, mw.DeliveredVersion, k); blocked {
+			mw.DQ.Wait(k, dependency)
+			continue
 		}
+
+		mw.DQ.Remove(k, next)
+		mw.DeliveredVersion.Tick(k)
+		msg = communication.NewMessage(communication.DLV, msg.Operation.Type, msg.Value, msg.Version, msg.OriginID)
+		mw.DeliverCausal <- msg
+		mw.updatestability(msg)
+
+		candidates = append(candidates, k)
+		candidates = append(candidates, mw.DQ.Woken(k)...)
 	}
 }
 
-// check if a message has his causal predecessors delivered
-func allCausalPredecessorsDelivered(V_m, V_i communication.VClock, j string) bool {
+// check if a message has his causal predecessors delivered, if not returns the origin of one missing predecessor
+func missingCausalPredecessor(V_m, V_i communication.VClock, j string) (string, bool) {
 	for k, v := range V_m.GetMap() {
 		if k != j && v > V_i.FindTicks(k) {
-			return false
+			return k, true
 		}
 	}
-	return true
+	return "", false
 }
 
 // Updates observed matrix and counter, finds stable version and send stable messages
@@ -205,19 +196,12 @@ func (mw *Middleware) updatestability(msg communication.Message) {
 	mw.SMap.m[StableDotKey{msg.OriginID, msg.Version.FindTicks(msg.OriginID)}] = StableDotValue{msg, mw.Ctr}
 	mw.SMap.Unlock()
 
-	// Min is a map of ids (rows) that contain the id of the replica (column) with the minimum version in that row
-	//check if sender is in Min, if it is not, then the minimums of the columns are the same and SV hasn't change
-	mw.Min.Lock()
-	ok := utils.MapValueExists(mw.Min.m, msg.OriginID)
-	mw.Min.Unlock()
-
-	if ok {
-		var NewStableVersion = mw.calculateStableVersion(msg.OriginID)
-		if !NewStableVersion.Equal(mw.StableVersion) {
-			//StableDots := NewStableVersion.Subtract(mw.StableVersion)
-			mw.stabilize(NewStableVersion)
-			mw.StableVersion = NewStableVersion.Copy()
-		}
+	//the stable version is the minimum of each column of the observed matrix, only the columns affected by the updated rows are recalculated
+	var NewStableVersion = mw.calculateStableVersion()
+	if !NewStableVersion.Equal(mw.StableVersion) {
+		//StableDots := NewStableVersion.Subtract(mw.StableVersion)
+		mw.stabilize(NewStableVersion)
+		mw.StableVersion = NewStableVersion.Copy()
 	}
 }
 
@@ -248,49 +232,25 @@ func (mw *Middleware) stabilize(StableDots communication.VClock) {
 	}
 }
 
-// Calculating the Stable vector every time Observed is updated can become costly, specially when dealing with large groups.
-// To overcome this problem the Min vector was created, by checking if the sender’s id is in it.
-// If it is not, then the minimums of the columns are the same and Min has not changed.
-func (mw *Middleware) calculateStableVersion(j string) communication.VClock {
-	newStableVersion := mw.StableVersion.Copy()
-
-	mw.Min.Lock()
-	for keyMin, _ := range mw.Min.m {
-		//if keyMin == j {
-			min := mw.Observed.GetTick(j, keyMin)
-			minRow := keyMin
-
-			obs := mw.Observed.GetMap()
-
-			mw.Observed.Lock()
-			for keyObs, _ := range obs {
-				if mw.Observed.m[keyObs].FindTicks(keyMin) < min {
-					min = mw.Observed.m[keyObs].FindTicks(keyMin)
-					minRow = keyObs
-				}
-			}
-			mw.Observed.Unlock()
-			newStableVersion.Set(keyMin, min)
-			mw.Min.m[keyMin] = minRow
-		//}
-	}
-	mw.Min.Unlock()
-	return newStableVersion
+// The stable version is the minimum of each column of the Observed matrix.
+// Observed keeps the minimums updated when its rows are set, so the stable version does not need to be recalculated from the whole matrix.
+func (mw *Middleware) calculateStableVersion() communication.VClock {
+	return mw.Observed.MinVClock()
 }
 
 // messageHandler handles the messages received from the network
 func (mw *Middleware) messageHandler(msg communication.Message) {
 	V_m := msg.Version
 	j := msg.OriginID
+	//a message that was already delivered would wait in the delivery queue forever
+	if V_m.FindTicks(j) <= mw.DeliveredVersion.FindTicks(j) {
+		return
+	}
 	//if mw.ReceivedVersion.FindTicks(j) < V_m.FindTicks(j) { // communication.Messages from the same replica cannot be delivered out of order otherwise they are ignored
 	mw.ReceivedVersion.Tick(j)
-	if V_m.FindTicks(j) == mw.DeliveredVersion.FindTicks(j)+1 && allCausalPredecessorsDelivered(V_m, mw.DeliveredVersion, j) {
-		mw.DeliveredVersion.Tick(j)
-		mw.DeliverCausal <- msg
-		mw.updatestability(msg)
-		mw.deliver()
-	} else {
-		mw.DQ = append(mw.DQ, msg)
+	mw.DQ.Add(msg)
+	if V_m.FindTicks(j) == mw.DeliveredVersion.FindTicks(j)+1 {
+		mw.deliver(j)
 	}
 	//}
 }
@@ -309,6 +269,7 @@ func (mw *Middleware) messageDelayerHandler(msg communication.Message) {
 				}
 			}
 		} else {
+			//delayed marks the messages already handled, picking one of them hands it over again and holds the new message back
 			index := rand.Intn(len(mw.MessagesDelay))
 			md := mw.MessagesDelay[index]
 
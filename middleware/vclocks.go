@@ -2,23 +2,32 @@ package middleware
 
 import (
 	"library/packages/communication"
+	"library/packages/utils"
 	"sync"
 )
 
 // Matrix of vector clocks for each replica
+// Calculating the minimum of every column each time a row is updated can become costly, specially when dealing with large groups.
+// To overcome this problem min keeps the row (replica) with the minimum value of each column,
+// so when a row is updated only the columns whose minimum was in that row have to be recalculated.
 type VClocks struct {
 	*sync.RWMutex
-	m map[string]communication.VClock
+	m        map[string]communication.VClock
+	min      map[string]string // column -> row with the minimum value of that column ("" if not calculated yet)
+	minTicks map[string]uint64 // column -> minimum value of that column
 }
 
 // returns a new matrix of vector clocks
 func InitVClocks(ids []string) VClocks {
 	vc := VClocks{
-		RWMutex: new(sync.RWMutex),
-		m:       make(map[string]communication.VClock),
+		RWMutex:  new(sync.RWMutex),
+		m:        make(map[string]communication.VClock),
+		min:      utils.InitMin(ids),
+		minTicks: make(map[string]uint64),
 	}
 	for _, id := range ids {
 		vc.m[id] = communication.InitVClock(ids)
+		vc.minTicks[id] = 0
 	}
 	return vc
 }
@@ -30,13 +39,50 @@ func (vcs VClocks) GetTick(id string, id1 string) uint64 {
 	return vcs.m[id].FindTicks(id1)
 }
 
-// set vclock for a specific position
+// set vclock for a specific position and update the minimum of the columns affected by it
 func (vcs *VClocks) SetVClock(id string, vc communication.VClock) {
 	vcs.Lock()
 	vcs.m[id] = vc
+	for column, row := range vcs.min {
+		ticks := vc.FindTicks(column)
+		if (row == id && ticks > vcs.minTicks[column]) || row == "" {
+			//the minimum of the column was in the updated row and it increased, it has to be recalculated
+			vcs.updateMin(column)
+		} else if ticks < vcs.minTicks[column] {
+			//the updated row has the new minimum of the column
+			vcs.min[column] = id
+			vcs.minTicks[column] = ticks
+		}
+	}
 	vcs.Unlock()
 }
 
+// recalculates the minimum of a column
+func (vcs *VClocks) updateMin(column string) {
+	minRow := ""
+	var min uint64
+	for row, vc := range vcs.m {
+		ticks := vc.FindTicks(column)
+		if minRow == "" || ticks < min {
+			min = ticks
+			minRow = row
+		}
+	}
+	vcs.min[column] = minRow
+	vcs.minTicks[column] = min
+}
+
+// returns the vector with the minimum of each column (stable version)
+func (vcs VClocks) MinVClock() communication.VClock {
+	vcs.Lock()
+	defer vcs.Unlock()
+	min := make(map[string]uint64, len(vcs.minTicks))
+	for column, ticks := range vcs.minTicks {
+		min[column] = ticks
+	}
+	return communication.NewVClockFromMap(min)
+}
+
 // returns map
 func (vcs VClocks) GetMap() map[string]communication.VClock {
 	vcs.Lock()
@@ -0,0 +1,161 @@
+package replica
+
+import (
+	"library/packages/communication"
+	"log"
+	"sync"
+)
+
+// ObjectValue is the value of an operation on a named object of Objects
+type ObjectValue struct {
+	Key   string // name of the object
+	Value any    // value of the operation on the object
+}
+
+// an operation delivered or stabilized before its object was added
+type pendingOp struct {
+	op        communication.Operation
+	stabilize bool
+}
+
+// Objects hosts many named CRDTs, possibly of different engine types, in one replica
+// operations carry the key of their object in an ObjectValue and are routed to its Effect and Stabilize,
+// so all objects share the middleware and the version vector of the replica
+type Objects struct {
+	objects   map[string]CrdtI
+	newObject func(key string) CrdtI //creates objects on their first operation, nil if objects are only added with Add
+	pending   map[string][]pendingOp //operations of objects not added yet, in delivery order
+	lock      *sync.RWMutex
+	delivered communication.VClock //merged versions of the delivered operations of all objects
+	stable    communication.VClock //merged versions of the stable operations of all objects
+
+	N_Ops uint64
+	S_Ops uint64
+}
+
+// returns a container without objects, newObject creates the objects that receive an operation before being added
+func NewObjects(newObject func(key string) CrdtI) *Objects {
+	return &Objects{
+		objects:   make(map[string]CrdtI),
+		newObject: newObject,
+		pending:   make(map[string][]pendingOp),
+		lock:      new(sync.RWMutex),
+		delivered: communication.NewVClock(),
+		stable:    communication.NewVClock(),
+	}
+}
+
+// initialize a replica hosting objects
+func NewObjectsReplica(id string, objects *Objects, channels map[string]chan any, delay int) *Replica {
+	return NewReplica(id, objects, channels, delay)
+}
+
+// adds a named object, operations received for it before are applied to it in delivery order
+func (o *Objects) Add(key string, crdt CrdtI) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.objects[key] = crdt
+	for _, p := range o.pending[key] {
+		if p.stabilize {
+			crdt.Stabilize(p.op)
+		} else {
+			crdt.Effect(p.op)
+		}
+	}
+	delete(o.pending, key)
+}
+
+// returns the object with the given key
+func (o *Objects) Object(key string) (CrdtI, bool) {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	crdt, ok := o.objects[key]
+	return crdt, ok
+}
+
+// keys of the objects
+func (o *Objects) Keys() []string {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	keys := make([]string, 0, len(o.objects))
+	for key := range o.objects {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// routes the operation to the object of its key
+func (o *Objects) Effect(op communication.Operation) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.N_Ops++
+	o.delivered.Merge(op.Version)
+	o.route(op, false)
+}
+
+// routes the stable operation to the object of its key
+func (o *Objects) Stabilize(op communication.Operation) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.S_Ops++
+	o.stable.Merge(op.Version)
+	o.route(op, true)
+}
+
+// calls the Effect or Stabilize of the object of op with the value of op on the object
+func (o *Objects) route(op communication.Operation, stabilize bool) {
+	value, ok := op.Value.(ObjectValue)
+	if !ok {
+		log.Println("[ OBJECTS ] OPERATION WITHOUT OBJECT KEY", op)
+		return
+	}
+	op = communication.Operation{Type: op.Type, Value: value.Value, Version: op.Version, OriginID: op.OriginID}
+
+	crdt, ok := o.objects[value.Key]
+	if !ok && o.newObject != nil {
+		crdt = o.newObject(value.Key)
+		o.objects[value.Key] = crdt
+	} else if !ok {
+		o.pending[value.Key] = append(o.pending[value.Key], pendingOp{op: op, stabilize: stabilize})
+		return
+	}
+
+	if stabilize {
+		crdt.Stabilize(op)
+	} else {
+		crdt.Effect(op)
+	}
+}
+
+// reads of all objects at level by key, with the version of the operations of all objects
+func (o *Objects) Read(level Level) (any, communication.VClock) {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	reads := make(map[string]any, len(o.objects))
+	for key, crdt := range o.objects {
+		reads[key], _ = crdt.Read(level)
+	}
+	if level == Stable {
+		return reads, o.stable.Copy()
+	}
+	return reads, o.delivered.Copy()
+}
+
+func (o *Objects) NumOps() uint64 {
+	return o.N_Ops
+}
+
+func (o *Objects) NumSOps() uint64 {
+	return o.S_Ops
+}
+
+// Update made by a client to the object with the given key of a replica hosting Objects
+func (r *Replica) PrepareObject(key string, operationType string, operationValue any) communication.Operation {
+	return r.Prepare(operationType, ObjectValue{Key: key, Value: operationValue})
+}
@@ -12,6 +12,14 @@ import (
 
 //type ReplicaID string
 
+// stability level of a read
+type Level int
+
+const (
+	Optimistic Level = iota // all delivered operations, their order and effect may still change
+	Stable                  // only causally stable operations, delivered by every replica and never reordered
+)
+
 type CrdtI interface {
 
 	// Effect callback called when a message is ready to be delivered.
@@ -20,8 +28,9 @@ type CrdtI interface {
 	// Stabilize callback function is called when a message is set to stable.
 	Stabilize(msg communication.Operation)
 
-	// Query made by a client to a replica that returns the current state of the CRDT
-	Query() (any, any)
+	// Read made by a client to a replica that returns the state of the CRDT at the given stability level
+	// and the version vector of the operations it reflects
+	Read(level Level) (any, communication.VClock)
 
 	// Returns the number of operations applied to the CRDT for testing purposes
 	NumOps() uint64
@@ -30,6 +39,12 @@ type CrdtI interface {
 	NumSOps() uint64
 }
 
+// CRDTs can implement CheckI to reject operations of clients at Prepare and TryPrepare
+type CheckI interface {
+	// Check returns an error if op, prepared by the replica, must be neither applied nor broadcast
+	Check(op communication.Operation) error
+}
+
 type Replica struct {
 	Crdt          CrdtI
 	id            string
@@ -106,18 +121,46 @@ func (r *Replica) dequeue() {
 
 // Update made by a client to a replica that receives the operation to be applied to the CRDT
 // sends the operation to middleware for broadcast
+// an operation rejected by the Check of the CRDT is neither applied nor broadcast and an empty operation is returned
 func (r *Replica) Prepare(operationType string, operationValue any) communication.Operation {
+	op, _ := r.TryPrepare(operationType, operationValue)
+	return op //for testing purposes
+}
+
+// Update like Prepare that returns the error of the Check of the CRDT if it implements CheckI
+// rejected operations are neither applied nor broadcast and do not tick the version vector
+func (r *Replica) TryPrepare(operationType string, operationValue any) (communication.Operation, error) {
 	r.prepareLock.Lock()
-	r.VersionVector.Tick(r.id)
+	return r.prepare(operationType, operationValue)
+}
+
+// Update like TryPrepare whose value is computed from the optimistic read of the CRDT,
+// no operation is applied between the read and the prepare
+func (r *Replica) Update(operationType string, operationValue func(state any) any) (communication.Operation, error) {
+	r.prepareLock.Lock()
+	st, _ := r.Crdt.Read(Optimistic)
+	return r.prepare(operationType, operationValue(st))
+}
+
+// prepares the operation with the prepare lock held, releases it before broadcasting
+func (r *Replica) prepare(operationType string, operationValue any) (communication.Operation, error) {
 	vv := r.VersionVector.Copy()
+	vv.Tick(r.id)
 	op := communication.Operation{Type: operationType, Value: operationValue, Version: vv, OriginID: r.id}
+	if c, ok := r.Crdt.(CheckI); ok {
+		if err := c.Check(op); err != nil {
+			r.prepareLock.Unlock()
+			return communication.Operation{}, err
+		}
+	}
+	r.VersionVector.Tick(r.id)
 	msg := communication.NewMessage(communication.DLV, op.Type, op.Value, op.Version, op.OriginID)
 	r.Crdt.Effect(msg.Operation)
 	r.prepareLock.Unlock()
 
 	r.TCBcast(msg)
 
-	return op //for testing purposes
+	return op, nil
 }
 
 func (r *Replica) GetID() string {
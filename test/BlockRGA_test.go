@@ -0,0 +1,117 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/crdtcheck"
+	"library/packages/datatypes"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/replica"
+	"strings"
+	"testing"
+)
+
+// random insert of a block or remove of a range of the visible characters of a block sequence
+func blockOperation(state any, choice int) (string, any) {
+	st := state.(*datatypes.BlockSequence)
+	n := st.Len()
+	if n > 0 && choice%3 == 0 {
+		index := choice / 3 % n
+		return "Rem", datatypes.BlockOpValue{Spans: st.Spans(index, 1+choice/7%(n-index))}
+	}
+	index := choice / 3 % (n + 1)
+	return "Add", datatypes.BlockOpValue{After: st.CharAt(index - 1), Text: strings.Repeat(string(letters[choice%len(letters)]), 1+choice%4)}
+}
+
+func blockEqual(st1 any, st2 any) bool {
+	return st1.(*datatypes.BlockSequence).Equal(st2.(*datatypes.BlockSequence))
+}
+
+func TestBlockRGA(t *testing.T) {
+	checkReplicas(t, ecro.NewBlockRGAReplica, prepareFromRead(blockOperation), sameReads(t, blockEqual))
+}
+
+func TestLawsBlockRGA(t *testing.T) {
+	gen := crdtcheck.Generator{State: datatypes.NewBlockSequence(), Operation: blockOperation, Equal: blockEqual}
+
+	if err := crdtcheck.CheckEcro(ecro.BlockRGA{Id: "0"}, gen, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInterleavingsBlockRGA(t *testing.T) {
+	newEngine := func() replica.CrdtI {
+		return crdt.NewEcroCRDT("0", datatypes.NewBlockSequence(), ecro.BlockRGA{Id: "0"})
+	}
+	checkInterleavings(t, newEngine, datatypes.NewBlockSequence(), ecro.BlockRGA{Id: "0"}.Apply, blockOperation, blockEqual, true)
+
+	// replica 0 inserts abc and removes it while replica 1 removes b and replica 2 inserts x after b and removes x once it saw the remove of b
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	insert := communication.Operation{Type: "Add", Value: datatypes.BlockOpValue{Text: "abc"}, Version: version(map[string]uint64{"0": 1}), OriginID: "0"}
+	abc := ecro.BlockRGA{Id: "0"}.Apply(datatypes.NewBlockSequence(), []communication.Operation{insert}).(*datatypes.BlockSequence)
+	addX := communication.Operation{Type: "Add", Value: datatypes.BlockOpValue{After: abc.CharAt(1), Text: "x"}, Version: version(map[string]uint64{"0": 1, "2": 1}), OriginID: "2"}
+	abxc := ecro.BlockRGA{Id: "0"}.Apply(abc, []communication.Operation{addX}).(*datatypes.BlockSequence)
+	ops := []communication.Operation{
+		insert,
+		{Type: "Rem", Value: datatypes.BlockOpValue{Spans: abc.Spans(0, 3)}, Version: version(map[string]uint64{"0": 2}), OriginID: "0"},
+		{Type: "Rem", Value: datatypes.BlockOpValue{Spans: abc.Spans(1, 1)}, Version: version(map[string]uint64{"0": 1, "1": 1}), OriginID: "1"},
+		addX,
+		{Type: "Rem", Value: datatypes.BlockOpValue{Spans: abxc.Spans(2, 1)}, Version: version(map[string]uint64{"0": 1, "1": 1, "2": 2}), OriginID: "2"},
+	}
+	if err := crdtcheck.CheckInterleavings(newEngine, ops, blockEqual, true); err != nil {
+		t.Error(err)
+	}
+}
+
+// a pasted text is one operation and one run, inserts and removes in its middle split it
+func TestBlockRGASplit(t *testing.T) {
+	engine := crdt.NewEcroCRDT("0", datatypes.NewBlockSequence(), ecro.BlockRGA{Id: "0"})
+	version := func(ticks uint64) communication.VClock {
+		return communication.NewVClockFromMap(map[string]uint64{"0": ticks})
+	}
+
+	engine.Effect(communication.Operation{Type: "Add", Value: datatypes.BlockOpValue{Text: strings.Repeat("a", 10000)}, Version: version(1), OriginID: "0"})
+	st, _ := engine.Read(replica.Optimistic)
+	if seq := st.(*datatypes.BlockSequence); seq.Len() != 10000 || seq.NumRuns() != 1 {
+		t.Fatal("Sequence has ", seq.Len(), " characters in ", seq.NumRuns(), " runs, expected 10000 characters in one run")
+	}
+
+	engine.Effect(communication.Operation{Type: "Add", Value: datatypes.BlockOpValue{After: st.(*datatypes.BlockSequence).CharAt(4999), Text: "b"}, Version: version(2), OriginID: "0"})
+	st, _ = engine.Read(replica.Optimistic)
+	engine.Effect(communication.Operation{Type: "Rem", Value: datatypes.BlockOpValue{Spans: st.(*datatypes.BlockSequence).Spans(4000, 2000)}, Version: version(3), OriginID: "0"})
+	st, _ = engine.Read(replica.Optimistic)
+	if seq := st.(*datatypes.BlockSequence); seq.String() != strings.Repeat("a", 4000)+strings.Repeat("a", 4001) || seq.NumRuns() != 5 {
+		t.Error("Sequence has ", seq.Len(), " characters in ", seq.NumRuns(), " runs, expected 8001 characters in 5 runs")
+	}
+}
+
+// the runs of removed characters are dropped from the stable state once the remove and the inserts after them are stable
+func TestBlockRGACollect(t *testing.T) {
+	engine := crdt.NewEcroCRDT("0", datatypes.NewBlockSequence(), ecro.BlockRGA{Id: "0"})
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	text := communication.Operation{Type: "Add", Value: datatypes.BlockOpValue{Text: "hello world"}, Version: version(map[string]uint64{"0": 1}), OriginID: "0"}
+	block := datatypes.NewBlockID(text.Version, "0")
+
+	// replica 0 removes " world" while replica 1 inserts after the o of world
+	remove := communication.Operation{Type: "Rem", Value: datatypes.BlockOpValue{Spans: []datatypes.CharSpan{{Block: block, Offset: 5, Length: 6}}}, Version: version(map[string]uint64{"0": 2}), OriginID: "0"}
+	insert := communication.Operation{Type: "Add", Value: datatypes.BlockOpValue{After: datatypes.CharID{Block: block, Offset: 7}, Text: "!"}, Version: version(map[string]uint64{"0": 1, "1": 1}), OriginID: "1"}
+	ops := []communication.Operation{text, remove, insert}
+	for _, op := range ops {
+		engine.Effect(op)
+	}
+	for _, op := range ops {
+		engine.Stabilize(op)
+	}
+
+	seq := engine.Stable_st.(*datatypes.BlockSequence)
+	if seq.String() != "hello!" || seq.NumRuns() != 2 {
+		t.Error("Stable state is ", seq, " in ", seq.NumRuns(), " runs, expected hello! in 2 runs")
+	}
+	if st, _ := engine.Read(replica.Optimistic); !blockEqual(st, seq) {
+		t.Error("Read is ", st, ", expected ", seq)
+	}
+}
@@ -0,0 +1,177 @@
+package test
+
+import (
+	"errors"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	commutative "library/packages/datatypes/commutative"
+	"library/packages/replica"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// random increment, decrement or transfer to another replica, decrements and transfers are often rejected
+func boundedCounterOperation(id string, numReplicas int, choice int) (string, any) {
+	n := 1 + choice%5
+	switch choice / 5 % 3 {
+	case 0:
+		return "Inc", n
+	case 1:
+		return "Dec", n
+	}
+	from, _ := strconv.Atoi(id)
+	return "Transfer", datatypes.TransferValue{To: strconv.Itoa((from + 1 + choice/15%(numReplicas-1)) % numReplicas), N: n}
+}
+
+func TestBoundedCounter(t *testing.T) {
+
+	// Define property to test
+	property := func(operations int, numReplicas int) bool {
+
+		// Initialize channels
+		channels := map[string]chan interface{}{}
+		for i := 0; i < numReplicas; i++ {
+			channels[strconv.Itoa(i)] = make(chan interface{})
+		}
+
+		// Initialize replicas, rejected operations are not sent so a replica does not know how many remote operations it could hold back
+		replicas := make([]*replica.Replica, numReplicas)
+		for i := 0; i < numReplicas; i++ {
+			replicas[i] = commutative.NewBoundedCounterReplica(strconv.Itoa(i), channels, 0)
+		}
+
+		// Start a goroutine for each replica
+		var wg sync.WaitGroup
+		var accepted uint64
+		for i := range replicas {
+			wg.Add(1)
+			go func(r *replica.Replica) {
+				defer wg.Done()
+				for j := 0; j < operations; j++ {
+					if _, err := r.TryPrepare(boundedCounterOperation(r.GetID(), numReplicas, rand.Intn(1000))); err == nil {
+						atomic.AddUint64(&accepted, 1)
+					} else if rights := new(datatypes.RightsError); !errors.As(err, &rights) {
+						t.Error("Replica ", r.GetID(), " rejected an operation with ", err)
+					}
+				}
+			}(replicas[i])
+		}
+
+		// Wait for all goroutines to finish
+		wg.Wait()
+
+		// Wait for all replicas to receive all messages
+		for {
+			flag := 0
+			for i := 0; i < numReplicas; i++ {
+				if replicas[i].Crdt.NumOps() == accepted {
+					flag += 1
+				}
+			}
+			if flag == numReplicas {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		//Check that all replicas have the same value, which is the sum of the rights and never negative
+		for i := 0; i < numReplicas; i++ {
+			st := replicas[i].Crdt.(*crdt.CommutativeCRDT).Stable_st.(datatypes.BoundedCounter)
+			sum := 0
+			for j := 0; j < numReplicas; j++ {
+				rights := st.Rights(strconv.Itoa(j))
+				if rights < 0 {
+					t.Error("Replica ", i, ": replica ", j, " holds ", rights, " rights")
+					return false
+				}
+				sum += rights
+			}
+			value, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
+			if value != stt || value != sum {
+				t.Error("Replica ", i, ": value ", value, " with rights ", sum, " differs from replica 0: ", stt)
+				return false
+			}
+		}
+		return true
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		vals[0] = reflect.ValueOf(10 + rand.Intn(30))
+		vals[1] = reflect.ValueOf(2 + rand.Intn(2))
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 10,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+// decrements are rejected beyond the rights of their replica until rights are transferred to it
+func TestBoundedCounterRights(t *testing.T) {
+	channels := map[string]chan interface{}{"0": make(chan interface{}), "1": make(chan interface{})}
+	replicas := []*replica.Replica{
+		commutative.NewBoundedCounterReplica("0", channels, 0),
+		commutative.NewBoundedCounterReplica("1", channels, 0),
+	}
+
+	prepare := func(r *replica.Replica, opType string, value any, accepted bool) {
+		t.Helper()
+		_, err := r.TryPrepare(opType, value)
+		if rights := new(datatypes.RightsError); accepted && err != nil || !accepted && !errors.As(err, &rights) {
+			t.Error("Replica ", r.GetID(), " prepared ", opType, " ", value, " with error ", err)
+		}
+	}
+	wait := func(ops uint64) {
+		for replicas[0].Crdt.NumOps() != ops || replicas[1].Crdt.NumOps() != ops {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	prepare(replicas[0], "Inc", 5, true)
+	if _, err := replicas[0].TryPrepare("Transfer", datatypes.TransferValue{To: "0", N: 5}); err == nil {
+		t.Error("Transfer of replica 0 to itself was not rejected")
+	}
+	prepare(replicas[1], "Dec", 1, false)
+	if ticks := replicas[1].VersionVector.FindTicks("1"); ticks != 0 {
+		t.Error("Rejected decrement ticked the version of replica 1 to ", ticks)
+	}
+	prepare(replicas[0], "Transfer", datatypes.TransferValue{To: "1", N: 3}, true)
+	prepare(replicas[0], "Transfer", datatypes.TransferValue{To: "1", N: 3}, false)
+	wait(2)
+
+	prepare(replicas[1], "Dec", 3, true)
+	prepare(replicas[1], "Dec", 1, false)
+	prepare(replicas[0], "Dec", 2, true)
+	prepare(replicas[0], "Dec", 1, false)
+	wait(4)
+
+	for i, r := range replicas {
+		if value, _ := r.Crdt.Read(replica.Optimistic); value != 0 {
+			t.Error("Replica ", i, " has value ", value, ", expected 0")
+		}
+	}
+	if _, err := replicas[0].TryPrepare("Inc", -1); err == nil {
+		t.Error("Negative increment was not rejected")
+	}
+	if _, err := replicas[0].TryPrepare("Dec", "1"); err == nil {
+		t.Error("Decrement of a string was not rejected")
+	}
+	if op := replicas[1].Prepare("Dec", 1); op.Type != "" || replicas[1].Crdt.NumOps() != 4 {
+		t.Error("Prepare applied the rejected decrement ", op)
+	}
+}
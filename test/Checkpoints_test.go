@@ -0,0 +1,85 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	ecro "library/packages/datatypes/ecro"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestCheckpoints(t *testing.T) {
+
+	// Define property to test
+	property := func(predecessors []int, positions []int, interval int) bool {
+		root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+		initial := datatypes.NewSequence(root)
+		checkpoints := crdt.NewCheckpoints(interval, initial)
+
+		vertices := []datatypes.Vertex{root}
+		ops := []communication.Operation{}
+		var st any = initial
+
+		for n, pred := range predecessors {
+			v := newTestVertex(strconv.Itoa(n%3), n+1)
+			op := communication.Operation{Type: "Add", Value: datatypes.RGAOpValue{Value: v.Value, V: vertices[pred%len(vertices)]}, Version: v.Timestamp.(communication.VClock), OriginID: v.OriginID}
+			vertices = append(vertices, v)
+
+			//insert the operation at a random position of the order, as an out of order operation would be
+			i := positions[n] % (len(ops) + 1)
+			if i == len(ops) {
+				ops = append(ops, op)
+				st = ecro.RGA{}.Apply(st, []communication.Operation{op})
+				checkpoints.Add(len(ops), st)
+			} else {
+				ops = append(ops[:i], append([]communication.Operation{op}, ops[i:]...)...)
+				st = checkpoints.Replay(ecro.RGA{}, ops, i)
+			}
+
+			//compare with replaying every operation from the initial state
+			expected := ecro.RGA{}.Apply(initial, ops)
+			if !datatypes.RGAEqual(st.(*datatypes.Sequence).Vertices(), expected.(*datatypes.Sequence).Vertices()) {
+				t.Error("State ", st.(*datatypes.Sequence).Vertices(), " expected ", expected.(*datatypes.Sequence).Vertices())
+				return false
+			}
+		}
+		return true
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		numOperations := 100
+		predecessors := make([]int, numOperations)
+		positions := make([]int, numOperations)
+
+		for i := 0; i < numOperations; i++ {
+			predecessors[i] = rand.Intn(numOperations)
+			//most operations arrive in order
+			positions[i] = numOperations
+			if rand.Intn(4) == 0 {
+				positions[i] = rand.Intn(numOperations)
+			}
+		}
+
+		vals[0] = reflect.ValueOf(predecessors)
+		vals[1] = reflect.ValueOf(positions)
+		vals[2] = reflect.ValueOf(rand.Intn(10))
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 50,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
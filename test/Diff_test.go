@@ -0,0 +1,135 @@
+package test
+
+import (
+	"errors"
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/datatypes/persistent"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestDiffAddWins(t *testing.T) {
+
+	// Define property to test
+	property := func(numOps int, numReplicas int, seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		ops := genCausalOperations(r, numOps, numReplicas, persistent.NewSet[any](), ecro.AddWins{}.Apply, addWinsGenerator.Operation)
+		from := ops[r.Intn(len(ops))].Version.Copy()
+		to := from.Copy()
+		to.Merge(ops[r.Intn(len(ops))].Version)
+
+		engine := crdt.NewEcroCRDT("0", persistent.NewSet[any](), ecro.AddWins{}, crdt.HistoryReads)
+		for _, op := range ops {
+			engine.Effect(op)
+		}
+		diff, err := engine.Diff(from, to)
+		if err != nil {
+			t.Error("Diff from ", from.ReturnVCString(), " to ", to.ReturnVCString(), " returned error ", err)
+			return false
+		}
+
+		// the operations of the diff are the operations dominated by to and not by from
+		expected := []communication.Operation{}
+		for _, op := range ops {
+			if to.Descends(op.Version) && !from.Descends(op.Version) {
+				expected = append(expected, op)
+			}
+		}
+		if len(diff.Operations) != len(expected) {
+			t.Error("Diff from ", from.ReturnVCString(), " to ", to.ReturnVCString(), " has operations ", diff.Operations, ", expected ", expected)
+			return false
+		}
+		for i := range expected {
+			if !diff.Operations[i].Equals(expected[i]) {
+				t.Error("Diff from ", from.ReturnVCString(), " to ", to.ReturnVCString(), " has operations ", diff.Operations, ", expected ", expected)
+				return false
+			}
+		}
+
+		// the delta turns the state at from into the state at to
+		fromSt, _ := engine.QueryAt(from)
+		toSt, _ := engine.QueryAt(to)
+		st := fromSt.(*persistent.Set[any]).Clone()
+		delta := diff.Delta.(datatypes.SetDelta)
+		for _, e := range delta.Removed {
+			st.Remove(e)
+		}
+		for _, e := range delta.Added {
+			st.Add(e)
+		}
+		if !st.Equal(toSt.(*persistent.Set[any])) {
+			t.Error("Delta ", delta, " from ", fromSt, " gives ", st, ", expected ", toSt)
+			return false
+		}
+		return true
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		vals[0] = reflect.ValueOf(1 + rand.Intn(20))
+		vals[1] = reflect.ValueOf(2 + rand.Intn(3))
+		vals[2] = reflect.ValueOf(rand.Int63())
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 50,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+// inserting after a vertex and removing another one gives one inserted and one deleted range
+func TestDiffRGA(t *testing.T) {
+	root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+	engine := crdt.NewEcroCRDT("0", datatypes.NewSequence(root), ecro.RGA{Id: "0"}, crdt.HistoryReads)
+
+	version := func(ticks uint64) communication.VClock {
+		return communication.NewVClockFromMap(map[string]uint64{"0": ticks})
+	}
+	a := datatypes.Vertex{Timestamp: version(1), Value: "a", OriginID: "0"}
+	b := datatypes.Vertex{Timestamp: version(2), Value: "b", OriginID: "0"}
+	c := datatypes.Vertex{Timestamp: version(3), Value: "c", OriginID: "0"}
+	d := datatypes.Vertex{Timestamp: version(5), Value: "d", OriginID: "0"}
+	ops := []communication.Operation{
+		{Type: "Add", Value: datatypes.RGAOpValue{V: root, Value: "a"}, Version: version(1), OriginID: "0"},
+		{Type: "Add", Value: datatypes.RGAOpValue{V: a, Value: "b"}, Version: version(2), OriginID: "0"},
+		{Type: "Add", Value: datatypes.RGAOpValue{V: b, Value: "c"}, Version: version(3), OriginID: "0"},
+		{Type: "Rem", Value: datatypes.RGAOpValue{V: b}, Version: version(4), OriginID: "0"},
+		{Type: "Add", Value: datatypes.RGAOpValue{V: c, Value: "d"}, Version: version(5), OriginID: "0"},
+	}
+	for _, op := range ops {
+		engine.Effect(op)
+	}
+
+	diff, err := engine.Diff(version(3), version(5))
+	if err != nil {
+		t.Fatal("Diff returned error ", err)
+	}
+	delta := diff.Delta.(datatypes.SequenceDelta)
+	if len(diff.Operations) != 2 || len(delta.Inserted) != 1 || len(delta.Deleted) != 1 ||
+		delta.Inserted[0].Index != 3 || !datatypes.RGAEqual(delta.Inserted[0].Vertices, []datatypes.Vertex{d}) ||
+		delta.Deleted[0].Index != 2 || !datatypes.RGAEqual(delta.Deleted[0].Vertices, []datatypes.Vertex{b}) {
+		t.Error("Diff has operations ", diff.Operations, " and delta ", delta, ", expected d inserted at 3 and b deleted at 2")
+	}
+
+	// once all operations are stable the log is compacted
+	for _, op := range ops {
+		engine.Stabilize(op)
+	}
+	var compacted *crdt.CompactedError
+	if _, err := engine.Diff(version(3), version(5)); !errors.As(err, &compacted) {
+		t.Error("Diff after compaction returned error ", err, ", expected it to be compacted away")
+	}
+}
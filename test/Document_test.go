@@ -0,0 +1,243 @@
+package test
+
+import (
+	"encoding/json"
+	"library/packages/communication"
+	"library/packages/datatypes/document"
+	"library/packages/replica"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// random edit of a document with scalars, an object and a list, edits of paths removed concurrently fail and are skipped
+func documentEdit(doc *document.Document, choice int) {
+	st, _ := doc.Read(replica.Optimistic)
+	root := st.(map[string]any)
+	key := "k" + strconv.Itoa(choice%3)
+	list, isList := root["list"].([]any)
+	object, isObject := root["obj"].(map[string]any)
+
+	switch choice / 3 % 8 {
+	case 0:
+		doc.Delete(key)
+	case 1:
+		doc.Set("list", []any{float64(choice)})
+	case 2:
+		doc.Set("obj", map[string]any{"x": float64(choice), "y": []any{"a"}})
+	case 3:
+		if isList {
+			doc.Insert("list["+strconv.Itoa(choice%(len(list)+1))+"]", float64(choice))
+			return
+		}
+		doc.Set(key, float64(choice))
+	case 4:
+		if isList && len(list) > 0 {
+			doc.Delete("list[" + strconv.Itoa(choice%len(list)) + "]")
+			return
+		}
+		doc.Set(key, strconv.Itoa(choice))
+	case 5:
+		if isObject {
+			doc.Set("obj.x", float64(choice))
+			return
+		}
+		doc.Set(key, true)
+	case 6:
+		if _, ok := object["y"].([]any); ok {
+			doc.Insert("obj.y[0]", strconv.Itoa(choice))
+			return
+		}
+		doc.Set(key, nil)
+	default:
+		doc.Set(key, float64(choice))
+	}
+}
+
+func TestDocument(t *testing.T) {
+
+	// Define property to test
+	property := func(operations int, numReplicas int) bool {
+
+		// Initialize channels
+		channels := map[string]chan interface{}{}
+		for i := 0; i < numReplicas; i++ {
+			channels[strconv.Itoa(i)] = make(chan interface{})
+		}
+
+		// Initialize replicas, edits prepare a variable number of operations so a replica does not know how many remote operations it could hold back
+		docs := make([]*document.Document, numReplicas)
+		replicas := make([]*replica.Replica, numReplicas)
+		for i := 0; i < numReplicas; i++ {
+			docs[i] = document.NewDocument(strconv.Itoa(i))
+			replicas[i] = document.NewDocumentReplica(strconv.Itoa(i), docs[i], channels, 0)
+		}
+
+		// Start a goroutine for each replica
+		var wg sync.WaitGroup
+		for i := range replicas {
+			wg.Add(1)
+			go func(doc *document.Document) {
+				defer wg.Done()
+				for j := 0; j < operations; j++ {
+					documentEdit(doc, rand.Intn(1000))
+				}
+			}(docs[i])
+		}
+
+		// Wait for all goroutines to finish
+		wg.Wait()
+
+		// Wait for all replicas to receive all messages, edits prepare a variable number of operations
+		total := uint64(0)
+		for i := range replicas {
+			total += replicas[i].VersionVector.FindTicks(replicas[i].GetID())
+		}
+		for {
+			flag := 0
+			for i := 0; i < numReplicas; i++ {
+				if replicas[i].Crdt.NumOps() == total {
+					flag += 1
+				}
+			}
+			if flag == numReplicas {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		//Check that all replicas have the same document
+		for i := 1; i < numReplicas; i++ {
+			st, _ := json.Marshal(docs[i])
+			stt, _ := json.Marshal(docs[0])
+			if string(st) != string(stt) {
+				t.Error("Replica ", i, ": ", string(st), " differs from replica 0: ", string(stt))
+				return false
+			}
+		}
+		return true
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		vals[0] = reflect.ValueOf(10 + rand.Intn(30))
+		vals[1] = reflect.ValueOf(2 + rand.Intn(2))
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 10,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+// edits by path of a document of one replica and their JSON
+func TestDocumentPaths(t *testing.T) {
+	doc := document.NewDocument("0")
+	document.NewDocumentReplica("0", doc, map[string]chan interface{}{"0": make(chan interface{})}, 0)
+
+	check := func(expected string) {
+		t.Helper()
+		if st, _ := json.Marshal(doc); string(st) != expected {
+			t.Error("Document is ", string(st), ", expected ", expected)
+		}
+	}
+
+	doc.Set("a", map[string]any{"b": []any{1, map[string]any{"c": "x"}}})
+	check(`{"a":{"b":[1,{"c":"x"}]}}`)
+	doc.Set("a.b[1].c", "y")
+	doc.Insert("a.b[0]", true)
+	doc.Insert("a.b[3]", nil)
+	check(`{"a":{"b":[true,1,{"c":"y"},null]}}`)
+	doc.Delete("a.b[1]")
+	doc.Set("a.b[0]", []any{"z"})
+	doc.Set("d", 2)
+	check(`{"a":{"b":[["z"],{"c":"y"},null]},"d":2}`)
+	doc.Delete("a")
+	check(`{"d":2}`)
+
+	for _, path := range []string{"", "a.b", "d[0]", "d.e", "x[", "x[1]z"} {
+		if err := doc.Set(path+".f", 1); err == nil {
+			t.Error("Setting ", path+".f", " does not fail")
+		}
+	}
+	if err := doc.Insert("d", 1); err == nil {
+		t.Error("Inserting in a scalar does not fail")
+	}
+}
+
+// records the operations delivered to a document so they can be delivered to another one
+type documentRecorder struct {
+	*document.Document
+	replica *replica.Replica
+	ops     []communication.Operation
+}
+
+// delivers the operations prepared by other
+func (r *documentRecorder) deliver(other *documentRecorder) {
+	for _, op := range other.ops {
+		r.replica.VersionVector.Merge(op.Version)
+		r.Document.Effect(op)
+	}
+}
+
+func (r *documentRecorder) Effect(op communication.Operation) {
+	r.ops = append(r.ops, op)
+	r.Document.Effect(op)
+}
+
+// operations are exchanged by hand between the replicas and never stabilized
+func (r *documentRecorder) Stabilize(op communication.Operation) {}
+
+// concurrent assignments of a key keep both values ordered by their stamps and every replica picks the last one,
+// a later assignment replaces them
+func TestDocumentConflicts(t *testing.T) {
+	docs := make([]*documentRecorder, 2)
+	for i := range docs {
+		id := strconv.Itoa(i)
+		doc := document.NewDocument(id)
+		r := document.NewDocumentReplica(id, doc, map[string]chan interface{}{id: make(chan interface{})}, 0)
+		docs[i] = &documentRecorder{Document: doc, replica: r}
+		r.Crdt = docs[i]
+	}
+
+	// replica 1 does not see the list of replica 0 before assigning a scalar to it
+	docs[0].Set("k", "a")
+	docs[0].Set("l", []any{"x"})
+	docs[0].Set("m", 1)
+	docs[1].Set("k", "b")
+	docs[1].Set("l", 1)
+	docs[1].Set("m", "a")
+	docs[1].deliver(docs[0])
+	docs[0].deliver(docs[1])
+
+	for i, doc := range docs {
+		if conflicts, _ := doc.Conflicts("k"); !reflect.DeepEqual(conflicts, []any{"a", "b"}) {
+			t.Error("Replica ", i, " has values ", conflicts, ", expected [a b]")
+		}
+		if conflicts, _ := doc.Conflicts("l"); !reflect.DeepEqual(conflicts, []any{[]any{"x"}, 1}) {
+			t.Error("Replica ", i, " has values ", conflicts, ", expected [[x] 1]")
+		}
+		if conflicts, _ := doc.Conflicts("m"); !reflect.DeepEqual(conflicts, []any{"a", 1}) {
+			t.Error("Replica ", i, " has values ", conflicts, ", expected [a 1]")
+		}
+		if st, _ := json.Marshal(doc.Document); string(st) != `{"k":"b","l":1,"m":1}` {
+			t.Error("Replica ", i, " is ", string(st), `, expected {"k":"b","l":1,"m":1}`)
+		}
+	}
+
+	docs[0].Set("k", "c")
+	if conflicts, _ := docs[0].Conflicts("k"); !reflect.DeepEqual(conflicts, []any{"c"}) {
+		t.Error("Replica 0 has values ", conflicts, " after a new assignment, expected [c]")
+	}
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes/ecro/custom"
+	"library/packages/datatypes/persistent"
+	"testing"
+)
+
+// engine of a social network starting from the state set up by init
+func newSocialEngine(init func(st custom.SocialState)) *crdt.EcroCRDT {
+	sets := func() [5]*persistent.Set[any] {
+		return [5]*persistent.Set[any]{persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any]()}
+	}
+	st := custom.SocialState{Friends: sets(), Requesters: sets()}
+	init(st)
+	return crdt.NewEcroCRDT("0", st, custom.Social{})
+}
+
+// user 0 asks user 1 to be friends, replica 1 accepts and then breaks up while replica 2 accepts too,
+// the breakup is ordered before the concurrent accept but after the accept it has seen, whichever accept the replicas receive first
+func TestEcroSortedAfterSeen(t *testing.T) {
+	accept := communication.Operation{Type: "accept", Value: custom.SocialOpValue{From: 1, To: 0}, Version: communication.NewVClockFromMap(map[string]uint64{"1": 1}), OriginID: "1"}
+	concurrent := communication.Operation{Type: "accept", Value: custom.SocialOpValue{From: 1, To: 0}, Version: communication.NewVClockFromMap(map[string]uint64{"2": 1}), OriginID: "2"}
+	breakup := communication.Operation{Type: "breakup", Value: custom.SocialOpValue{From: 0, To: 1}, Version: communication.NewVClockFromMap(map[string]uint64{"1": 2}), OriginID: "1"}
+
+	requested := func(st custom.SocialState) {
+		st.Requesters[1].Add(0)
+	}
+	engines := []*crdt.EcroCRDT{newSocialEngine(requested), newSocialEngine(requested)}
+	for _, op := range []communication.Operation{accept, concurrent, breakup} {
+		engines[0].Effect(op)
+	}
+	for _, op := range []communication.Operation{concurrent, accept, breakup} {
+		engines[1].Effect(op)
+	}
+
+	for i, engine := range engines {
+		if engine.Unstable_st.(custom.SocialState).Friends[1].Contains(0) {
+			t.Error("Replica ", i, " applies the breakup before the accept it has seen, users 0 and 1 are friends")
+		}
+	}
+}
+
+// a breakup and a request between the same friends conflict but the social network does not order them,
+// replicas that receive them in different orders still apply them in the same order
+func TestEcroUnorderedConflicts(t *testing.T) {
+	breakup := communication.Operation{Type: "breakup", Value: custom.SocialOpValue{From: 0, To: 1}, Version: communication.NewVClockFromMap(map[string]uint64{"0": 1}), OriginID: "0"}
+	request := communication.Operation{Type: "request", Value: custom.SocialOpValue{From: 0, To: 1}, Version: communication.NewVClockFromMap(map[string]uint64{"1": 1}), OriginID: "1"}
+
+	friends := func(st custom.SocialState) {
+		st.Friends[0].Add(1)
+		st.Friends[1].Add(0)
+	}
+	engines := []*crdt.EcroCRDT{newSocialEngine(friends), newSocialEngine(friends)}
+	engines[0].Effect(breakup)
+	engines[0].Effect(request)
+	engines[1].Effect(request)
+	engines[1].Effect(breakup)
+
+	if !custom.CompareSocialStates(engines[0].Unstable_st.(custom.SocialState), engines[1].Unstable_st.(custom.SocialState)) {
+		t.Error("Replicas that received the breakup and the request in different orders have states ", engines[0].Unstable_st, " and ", engines[1].Unstable_st)
+	}
+}
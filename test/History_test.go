@@ -0,0 +1,155 @@
+package test
+
+import (
+	"errors"
+	"library/packages/communication"
+	"library/packages/crdt"
+	commutative "library/packages/datatypes/commutative"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/datatypes/persistent"
+	semidirect "library/packages/datatypes/semidirect"
+	"library/packages/replica"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// engines with historical reads
+type historyCrdtI interface {
+	replica.CrdtI
+	QueryAt(version communication.VClock) (any, error)
+}
+
+// the state at the version of an operation is the state of an engine that only received the operations it dominates,
+// once all operations are stable earlier versions are compacted away
+func checkQueryAt(t *testing.T, newEngine func() historyCrdtI, state any, apply func(state any, operations []communication.Operation) any,
+	newOp func(state any, choice int) (string, any), equal func(q1 any, q2 any) bool) {
+
+	// Define property to test
+	property := func(numOps int, numReplicas int, seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		ops := genCausalOperations(r, numOps, numReplicas, state, apply, newOp)
+		version := ops[r.Intn(len(ops))].Version
+
+		engine := newEngine()
+		past := newEngine()
+		for _, op := range ops {
+			engine.Effect(op)
+			if version.Descends(op.Version) {
+				past.Effect(op)
+			}
+		}
+
+		st, err := engine.QueryAt(version)
+		expected, _ := past.Read(replica.Optimistic)
+		if err != nil || !equal(st, expected) {
+			t.Error("State at ", version.ReturnVCString(), " of ", ops, " is ", st, " with error ", err, ", expected ", expected)
+			return false
+		}
+
+		for _, op := range ops {
+			engine.Stabilize(op)
+		}
+		latest, delivered := engine.Read(replica.Optimistic)
+		if st, err := engine.QueryAt(delivered); err != nil || !equal(st, latest) {
+			t.Error("State at ", delivered.ReturnVCString(), " after compaction is ", st, " with error ", err, ", expected ", latest)
+			return false
+		}
+		var compacted *crdt.CompactedError
+		if _, err := engine.QueryAt(version); !version.Descends(delivered) && !errors.As(err, &compacted) {
+			t.Error("State at ", version.ReturnVCString(), " after compaction returned error ", err, ", expected it to be compacted away")
+			return false
+		}
+		return true
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		vals[0] = reflect.ValueOf(1 + rand.Intn(20))
+		vals[1] = reflect.ValueOf(2 + rand.Intn(3))
+		vals[2] = reflect.ValueOf(rand.Int63())
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 50,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQueryAtEcro(t *testing.T) {
+	newEngine := func() historyCrdtI {
+		return crdt.NewEcroCRDT("0", persistent.NewSet[any](), ecro.AddWins{}, crdt.HistoryReads)
+	}
+	checkQueryAt(t, newEngine, persistent.NewSet[any](), ecro.AddWins{}.Apply, addWinsGenerator.Operation, setEqual)
+}
+
+func TestQueryAtSemidirect(t *testing.T) {
+	newEngine := func() historyCrdtI {
+		return &crdt.SemidirectCRDT{Id: "0", Data: semidirect.AddWins{}, Unstable_operations: []communication.Operation{}, Unstable_st: persistent.NewSet[any](), Options: crdt.HistoryReads}
+	}
+	checkQueryAt(t, newEngine, persistent.NewSet[any](), semidirect.AddWins{}.Apply, addWinsGenerator.Operation, setEqual)
+}
+
+func TestQueryAtCommutative(t *testing.T) {
+	newEngine := func() historyCrdtI {
+		return &crdt.CommutativeCRDT{Data: commutative.Counter{}, Stable_st: 0, Options: crdt.HistoryReads}
+	}
+	newOp := func(state any, choice int) (string, any) {
+		return "Add", choice % 10
+	}
+	checkQueryAt(t, newEngine, 0, commutative.Counter{}.Apply, newOp, reflect.DeepEqual)
+}
+
+// stable operations that no unstable operation is concurrent with are compacted while other operations are unstable
+func TestQueryAtStableCut(t *testing.T) {
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	engine := &crdt.CommutativeCRDT{Data: commutative.Counter{}, Stable_st: 0, Options: crdt.HistoryReads}
+
+	// b and c have seen a and are concurrent
+	a := communication.Operation{Type: "Add", Value: 1, Version: version(map[string]uint64{"0": 1}), OriginID: "0"}
+	b := communication.Operation{Type: "Add", Value: 10, Version: version(map[string]uint64{"0": 1, "1": 1}), OriginID: "1"}
+	c := communication.Operation{Type: "Add", Value: 100, Version: version(map[string]uint64{"0": 2}), OriginID: "0"}
+	for _, op := range []communication.Operation{a, b, c} {
+		engine.Effect(op)
+	}
+	engine.Stabilize(a)
+
+	var compacted *crdt.CompactedError
+	if _, err := engine.QueryAt(communication.NewVClock()); !errors.As(err, &compacted) {
+		t.Error("State before a returned error ", err, ", expected it to be compacted away")
+	}
+	for _, expected := range []struct {
+		version communication.VClock
+		value   int
+	}{{b.Version, 11}, {c.Version, 101}, {version(map[string]uint64{"0": 2, "1": 1}), 111}} {
+		if st, err := engine.QueryAt(expected.version); err != nil || st != expected.value {
+			t.Error("State at ", expected.version.ReturnVCString(), " is ", st, " with error ", err, ", expected ", expected.value)
+		}
+	}
+}
+
+// without HistoryReads no log is kept and every version is compacted away
+func TestQueryAtWithoutHistory(t *testing.T) {
+	engine := crdt.NewEcroCRDT("0", persistent.NewSet[any](), ecro.AddWins{})
+	op := communication.Operation{Type: "Add", Value: "a", Version: communication.NewVClockFromMap(map[string]uint64{"0": 1}), OriginID: "0"}
+	engine.Effect(op)
+
+	var compacted *crdt.CompactedError
+	if _, err := engine.QueryAt(op.Version); !errors.As(err, &compacted) {
+		t.Error("State at ", op.Version.ReturnVCString(), " returned error ", err, ", expected it to be compacted away")
+	}
+	if _, err := engine.Diff(communication.NewVClock(), op.Version); !errors.As(err, &compacted) {
+		t.Error("Diff returned error ", err, ", expected it to be compacted away")
+	}
+}
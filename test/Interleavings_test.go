@@ -0,0 +1,221 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/crdtcheck"
+	"library/packages/datatypes"
+	crdtECRO "library/packages/datatypes/crdtECRO"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/datatypes/ecro/custom"
+	"library/packages/datatypes/persistent"
+	semidirect "library/packages/datatypes/semidirect"
+	"library/packages/replica"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// generates operations of replicas that receive the operations known by another replica at random moments,
+// the versions of the operations give their causal order
+func genCausalOperations(rand *rand.Rand, numOps int, numReplicas int, state any,
+	apply func(state any, operations []communication.Operation) any, newOp func(state any, choice int) (string, any)) []communication.Operation {
+	return genOperations(rand, numOps, numReplicas, state, apply, newOp, nil)
+}
+
+// generates operations like genCausalOperations after a first operation of replica 0 that every replica received,
+// so the others depend on it, e.g. a create or an insert followed by moves and removes of what it created
+func genChainOperations(rand *rand.Rand, numOps int, numReplicas int, state any,
+	apply func(state any, operations []communication.Operation) any, newOp func(state any, choice int) (string, any)) []communication.Operation {
+	opType, opValue := newOp(state, rand.Intn(1000))
+	first := communication.Operation{Type: opType, Value: opValue, Version: communication.NewVClockFromMap(map[string]uint64{"0": 1}), OriginID: "0"}
+	return append([]communication.Operation{first}, genOperations(rand, numOps-1, numReplicas, state, apply, newOp, &first)...)
+}
+
+// generates operations of replicas that all received first if it is not nil
+func genOperations(rand *rand.Rand, numOps int, numReplicas int, state any,
+	apply func(state any, operations []communication.Operation) any, newOp func(state any, choice int) (string, any), first *communication.Operation) []communication.Operation {
+	type node struct {
+		clock map[string]uint64
+		known []communication.Operation //in delivery order
+		state any
+	}
+	nodes := make([]node, numReplicas)
+	for i := range nodes {
+		nodes[i] = node{clock: map[string]uint64{}, state: state}
+		if first != nil {
+			nodes[i] = node{clock: map[string]uint64{first.OriginID: 1}, known: []communication.Operation{*first}, state: apply(state, []communication.Operation{*first})}
+		}
+	}
+
+	ops := []communication.Operation{}
+	for len(ops) < numOps {
+		i := rand.Intn(numReplicas)
+		if j := rand.Intn(numReplicas); rand.Intn(2) == 0 {
+			for _, op := range nodes[j].known {
+				if op.Version.FindTicks(op.OriginID) > nodes[i].clock[op.OriginID] {
+					nodes[i].clock[op.OriginID] = op.Version.FindTicks(op.OriginID)
+					nodes[i].known = append(nodes[i].known, op)
+					nodes[i].state = apply(nodes[i].state, []communication.Operation{op})
+				}
+			}
+		}
+
+		id := strconv.Itoa(i)
+		nodes[i].clock[id]++
+		version := map[string]uint64{}
+		for k, v := range nodes[i].clock {
+			version[k] = v
+		}
+		opType, opValue := newOp(nodes[i].state, rand.Intn(1000))
+		op := communication.Operation{Type: opType, Value: opValue, Version: communication.NewVClockFromMap(version), OriginID: id}
+		nodes[i].known = append(nodes[i].known, op)
+		nodes[i].state = apply(nodes[i].state, []communication.Operation{op})
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// checks every interleaving of small random scenarios, half of them start with an operation the others depend on
+func checkInterleavings(t *testing.T, newEngine func() replica.CrdtI, state any, apply func(state any, operations []communication.Operation) any,
+	newOp func(state any, choice int) (string, any), equal func(q1 any, q2 any) bool, stabilize bool) {
+
+	// Define property to test
+	property := func(numOps int, numReplicas int, seed int64, chain bool) bool {
+		generate := genCausalOperations
+		if chain {
+			generate = genChainOperations
+		}
+		ops := generate(rand.New(rand.NewSource(seed)), numOps, numReplicas, state, apply, newOp)
+		if err := crdtcheck.CheckInterleavings(newEngine, ops, equal, stabilize); err != nil {
+			t.Error(err)
+			return false
+		}
+		return true
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		vals[0] = reflect.ValueOf(2 + rand.Intn(4))
+		vals[1] = reflect.ValueOf(2 + rand.Intn(2))
+		vals[2] = reflect.ValueOf(rand.Int63())
+		vals[3] = reflect.ValueOf(rand.Intn(2) == 0)
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 30,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+func setEqual(q1 any, q2 any) bool {
+	return q1.(*persistent.Set[any]).Equal(q2.(*persistent.Set[any]))
+}
+
+func TestInterleavingsAddWins(t *testing.T) {
+	newEcro := func() replica.CrdtI {
+		return crdt.NewEcroCRDT("0", persistent.NewSet[any](), ecro.AddWins{})
+	}
+	checkInterleavings(t, newEcro, persistent.NewSet[any](), ecro.AddWins{}.Apply, addWinsGenerator.Operation, setEqual, true)
+
+	newSemidirect := func() replica.CrdtI {
+		return &crdt.SemidirectCRDT{Id: "0", Data: semidirect.AddWins{}, Unstable_operations: []communication.Operation{}, Unstable_st: persistent.NewSet[any]()}
+	}
+	checkInterleavings(t, newSemidirect, persistent.NewSet[any](), semidirect.AddWins{}.Apply, addWinsGenerator.Operation, setEqual, false)
+}
+
+func TestInterleavingsRemoveWins(t *testing.T) {
+	newEcro := func() replica.CrdtI {
+		return crdt.NewEcroCRDT("0", persistent.NewSet[any](), ecro.RemoveWins{})
+	}
+	checkInterleavings(t, newEcro, persistent.NewSet[any](), ecro.RemoveWins{}.Apply, addWinsGenerator.Operation, setEqual, true)
+
+	newSemidirect := func() replica.CrdtI {
+		return &crdt.SemidirectCRDT{Id: "0", Data: semidirect.RemoveWins{}, Unstable_operations: []communication.Operation{}, Unstable_st: persistent.NewSet[any]()}
+	}
+	checkInterleavings(t, newSemidirect, persistent.NewSet[any](), semidirect.RemoveWins{}.Apply, addWinsGenerator.Operation, setEqual, false)
+}
+
+func TestInterleavingsRGA(t *testing.T) {
+	root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+	newEngine := func() replica.CrdtI {
+		return crdt.NewEcroCRDT("0", datatypes.NewSequence(root), ecro.RGA{Id: "0"})
+	}
+	newOp := func(state any, choice int) (string, any) {
+		vertices := state.(*datatypes.Sequence).Vertices()
+		v := vertices[choice%len(vertices)]
+		if choice%2 == 0 && v.Value != "" && v.Value != nil {
+			return "Rem", datatypes.RGAOpValue{V: v}
+		}
+		return "Add", datatypes.RGAOpValue{Value: strconv.Itoa(choice), V: v}
+	}
+	equal := func(q1 any, q2 any) bool {
+		return datatypes.RGAEqual(q1.([]datatypes.Vertex), q2.([]datatypes.Vertex))
+	}
+
+	checkInterleavings(t, newEngine, datatypes.NewSequence(root), ecro.RGA{Id: "0"}.Apply, newOp, equal, true)
+}
+
+func TestInterleavingsSocial(t *testing.T) {
+	state := custom.SocialState{
+		Friends:    [5]*persistent.Set[any]{persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any]()},
+		Requesters: [5]*persistent.Set[any]{persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any]()},
+	}
+	newEngine := func() replica.CrdtI {
+		return crdt.NewEcroCRDT("0", state, custom.Social{})
+	}
+	types := []string{"accept", "breakup", "request", "reject"}
+	newOp := func(state any, choice int) (string, any) {
+		return types[choice%4], custom.SocialOpValue{From: choice / 4 % 3, To: choice / 12 % 3}
+	}
+	equal := func(q1 any, q2 any) bool {
+		return custom.CompareSocialStates(q1.(custom.SocialState), q2.(custom.SocialState))
+	}
+
+	checkInterleavings(t, newEngine, state, custom.Social{}.Apply, newOp, equal, true)
+}
+
+func TestInterleavingsSocialSEMIECRO(t *testing.T) {
+	state := crdtECRO.SocialState{
+		Friends:    [5]*persistent.Set[any]{persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any]()},
+		Requesters: [5]*persistent.Set[any]{persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any]()},
+	}
+	newEngine := func() replica.CrdtI {
+		return crdt.NewSemidirectECRO("0", state, crdtECRO.Social{})
+	}
+	types := []string{"accept", "breakup", "request", "reject"}
+	newOp := func(state any, choice int) (string, any) {
+		return types[choice%4], crdtECRO.SocialOpValue{From: choice / 4 % 3, To: choice / 12 % 3}
+	}
+	equal := func(q1 any, q2 any) bool {
+		return crdtECRO.CompareSocialStates(q1.(crdtECRO.SocialState), q2.(crdtECRO.SocialState))
+	}
+
+	checkInterleavings(t, newEngine, state, crdtECRO.Social{}.Apply, newOp, equal, true)
+}
+
+// an engine whose datatype claims that an add and a remove of the same element commute diverges
+func TestInterleavingsDivergence(t *testing.T) {
+	newEngine := func() replica.CrdtI {
+		return crdt.NewEcroCRDT("0", persistent.NewSet[any](), commutingAddWins{})
+	}
+	ops := []communication.Operation{
+		{Type: "Add", Value: 1, Version: communication.NewVClockFromMap(map[string]uint64{"1": 1}), OriginID: "1"},
+		{Type: "Rem", Value: 1, Version: communication.NewVClockFromMap(map[string]uint64{"2": 1}), OriginID: "2"},
+	}
+
+	err := crdtcheck.CheckInterleavings(newEngine, ops, setEqual, false)
+	if _, ok := err.(*crdtcheck.Divergence); !ok {
+		t.Error("Expected a divergence, got ", err)
+	}
+}
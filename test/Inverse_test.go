@@ -0,0 +1,211 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/datatypes/persistent"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// hides the Inverse of a data interface so the engine replays operations
+type replayOnly struct {
+	crdt.EcroDataI
+}
+
+// replica with an engine that undoes operations and one that replays them, both receive the same operations
+type inverseReplica struct {
+	inverse *crdt.EcroCRDT
+	replay  *crdt.EcroCRDT
+	clock   map[string]uint64
+	pending []communication.Operation //received but not delivered
+}
+
+type inverseEvent struct {
+	Replica  int
+	Generate bool
+	Choice   int
+}
+
+// runs the events on replicas without a network, delivering operations in causal order,
+// and checks after every operation that both engines of a replica have the same state
+func runInverseEvents(t *testing.T, events []inverseEvent, numReplicas int, newReplica func(id string) inverseReplica,
+	newOp func(state any, choice int) (string, any), equal func(st1 any, st2 any) bool) bool {
+	ids := make([]string, numReplicas)
+	replicas := make([]inverseReplica, numReplicas)
+	for i := 0; i < numReplicas; i++ {
+		ids[i] = strconv.Itoa(i)
+		replicas[i] = newReplica(ids[i])
+	}
+
+	effect := func(r *inverseReplica, op communication.Operation) bool {
+		r.inverse.Effect(op)
+		r.replay.Effect(op)
+		if !equal(r.inverse.Unstable_st, r.replay.Unstable_st) {
+			t.Error("Undo and redo ", r.inverse.Unstable_st, " replay ", r.replay.Unstable_st, " after ", op)
+			return false
+		}
+		return true
+	}
+
+	deliver := func(r *inverseReplica, choice int) (bool, bool) {
+		deliverable := []int{}
+		for i, op := range r.pending {
+			ok := op.Version.FindTicks(op.OriginID) == r.clock[op.OriginID]+1
+			for _, id := range ids {
+				if id != op.OriginID && op.Version.FindTicks(id) > r.clock[id] {
+					ok = false
+				}
+			}
+			if ok {
+				deliverable = append(deliverable, i)
+			}
+		}
+		if len(deliverable) == 0 {
+			return false, true
+		}
+		i := deliverable[choice%len(deliverable)]
+		op := r.pending[i]
+		r.pending = append(r.pending[:i], r.pending[i+1:]...)
+		r.clock[op.OriginID]++
+		return true, effect(r, op)
+	}
+
+	for _, e := range events {
+		r := &replicas[e.Replica]
+		if !e.Generate {
+			if _, ok := deliver(r, e.Choice); !ok {
+				return false
+			}
+			continue
+		}
+
+		opType, opValue := newOp(r.inverse.Unstable_st, e.Choice)
+		r.clock[ids[e.Replica]]++
+		version := communication.NewVClockFromMap(map[string]uint64{})
+		for id, ticks := range r.clock {
+			version.Set(id, ticks)
+		}
+		op := communication.Operation{Type: opType, Value: opValue, Version: version, OriginID: ids[e.Replica]}
+		if !effect(r, op) {
+			return false
+		}
+		for i := range replicas {
+			if i != e.Replica {
+				replicas[i].pending = append(replicas[i].pending, op)
+			}
+		}
+	}
+
+	//deliver the remaining operations
+	for i := range replicas {
+		for {
+			delivered, ok := deliver(&replicas[i], 0)
+			if !ok {
+				return false
+			}
+			if !delivered {
+				break
+			}
+		}
+	}
+	return true
+}
+
+func genInverseEvents(vals []reflect.Value, rand *rand.Rand) {
+	numReplicas := 3
+	events := make([]inverseEvent, 80)
+	for i := range events {
+		events[i] = inverseEvent{Replica: rand.Intn(numReplicas), Generate: rand.Intn(2) == 0, Choice: rand.Intn(1000)}
+	}
+
+	vals[0] = reflect.ValueOf(events)
+	vals[1] = reflect.ValueOf(numReplicas)
+}
+
+func TestInverseRGA(t *testing.T) {
+
+	newReplica := func(id string) inverseReplica {
+		root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: id}
+		return inverseReplica{
+			inverse: crdt.NewEcroCRDT(id, datatypes.NewSequence(root), ecro.RGA{Id: id}),
+			replay:  crdt.NewEcroCRDT(id, datatypes.NewSequence(root), replayOnly{ecro.RGA{Id: id}}),
+			clock:   map[string]uint64{},
+		}
+	}
+
+	newOp := func(state any, choice int) (string, any) {
+		vertices := state.(*datatypes.Sequence).Vertices()
+		v := vertices[choice%len(vertices)]
+		if choice%2 == 0 && v.Value != "" {
+			return "Rem", datatypes.RGAOpValue{V: v}
+		}
+		return "Add", datatypes.RGAOpValue{Value: strconv.Itoa(choice), V: v}
+	}
+
+	equal := func(st1 any, st2 any) bool {
+		return datatypes.RGAEqual(st1.(*datatypes.Sequence).Vertices(), st2.(*datatypes.Sequence).Vertices())
+	}
+
+	// Define property to test
+	property := func(events []inverseEvent, numReplicas int) bool {
+		return runInverseEvents(t, events, numReplicas, newReplica, newOp, equal)
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 20,
+		Values:   genInverseEvents,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInverseAddWins(t *testing.T) {
+
+	newReplica := func(id string) inverseReplica {
+		return inverseReplica{
+			inverse: crdt.NewEcroCRDT(id, persistent.NewSet[any](), ecro.AddWins{}),
+			replay:  crdt.NewEcroCRDT(id, persistent.NewSet[any](), replayOnly{ecro.AddWins{}}),
+			clock:   map[string]uint64{},
+		}
+	}
+
+	newOp := func(state any, choice int) (string, any) {
+		if choice%2 == 0 {
+			return "Rem", choice / 2 % 10
+		}
+		return "Add", choice / 2 % 10
+	}
+
+	equal := func(st1 any, st2 any) bool {
+		return st1.(*persistent.Set[any]).Equal(st2.(*persistent.Set[any]))
+	}
+
+	// Define property to test
+	property := func(events []inverseEvent, numReplicas int) bool {
+		return runInverseEvents(t, events, numReplicas, newReplica, newOp, equal)
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 20,
+		Values:   genInverseEvents,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
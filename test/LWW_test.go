@@ -0,0 +1,159 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	commutative "library/packages/datatypes/commutative"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/replica"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// operations of a trace that a multi-value register shows once they are all written to it, ordered by their stamps
+// the trace is collected from concurrent replicas, it is written in causal order, the order of the sums of the versions
+func mvReplay(trace []communication.Operation) []any {
+	ordered := append([]communication.Operation{}, trace...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Version.Sum() < ordered[j].Version.Sum() })
+
+	register := &crdt.CommutativeStableCRDT{Data: ecro.MVRegister[any]{}, Stable_st: datatypes.NewMVRegister[any]()}
+	for _, op := range ordered {
+		register.Effect(communication.Operation{Type: "Write", Value: op, Version: op.Version, OriginID: op.OriginID})
+	}
+	st, _ := register.Read(replica.Optimistic)
+	return st.([]any)
+}
+
+// the write a last-writer-wins register shows, the one with the greatest stamp of the values of a multi-value register
+func lwwWrite(trace []communication.Operation) communication.Operation {
+	values := mvReplay(trace)
+	return values[len(values)-1].(communication.Operation)
+}
+
+// random set or remove of one of a few keys, operations do not depend on the state
+func lwwMapOperation(state any, choice int) (string, any) {
+	key := "k" + strconv.Itoa(choice%3)
+	if choice/3%3 == 0 {
+		return "Rem", datatypes.LWWOpValue{Key: key}
+	}
+	return "Set", datatypes.LWWOpValue{Key: key, Value: choice}
+}
+
+// values of the keys of a last-writer-wins map after the operations of a trace, by the newest write of each key
+func lwwMapValues(trace []communication.Operation) map[string]any {
+	byKey := map[string][]communication.Operation{}
+	for _, op := range trace {
+		key := op.Value.(datatypes.LWWOpValue).Key
+		byKey[key] = append(byKey[key], op)
+	}
+	values := map[string]any{}
+	for key, ops := range byKey {
+		if op := lwwWrite(ops); op.Type == "Set" {
+			values[key] = op.Value.(datatypes.LWWOpValue).Value
+		}
+	}
+	return values
+}
+
+// runs operations on connected replicas, collects the trace and checks that every replica reads expected(trace)
+func checkLWW(t *testing.T, newReplica func(id string, channels map[string]chan any, delay int) *replica.Replica,
+	newOp func(state any, choice int) (string, any), expected func(trace []communication.Operation) any) {
+
+	prepare := func(r *replica.Replica, choice int) communication.Operation {
+		return r.Prepare(newOp(nil, choice))
+	}
+
+	//Check that all replicas read the newest write of the trace
+	check := func(replicas []*replica.Replica, trace []communication.Operation) bool {
+		for i := range replicas {
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			if !reflect.DeepEqual(st, expected(trace)) {
+				t.Error("Replica ", i, ": ", st, " differs from the newest writes: ", expected(trace))
+				return false
+			}
+		}
+		return true
+	}
+	checkReplicas(t, newReplica, prepare, check)
+}
+
+func TestLWWRegister(t *testing.T) {
+	write := func(state any, choice int) (string, any) {
+		return "Write", choice
+	}
+	expected := func(trace []communication.Operation) any {
+		return lwwWrite(trace).Value
+	}
+	checkLWW(t, commutative.NewLWWRegisterReplica, write, expected)
+	checkLWW(t, commutative.NewStableLWWRegisterReplica, write, expected)
+}
+
+func TestLWWMap(t *testing.T) {
+	expected := func(trace []communication.Operation) any {
+		return lwwMapValues(trace)
+	}
+	checkLWW(t, commutative.NewLWWMapReplica, lwwMapOperation, expected)
+	checkLWW(t, commutative.NewStableLWWMapReplica, lwwMapOperation, expected)
+}
+
+func TestInterleavingsLWWMap(t *testing.T) {
+	apply := func(state any, operations []communication.Operation) any {
+		return state
+	}
+	newEngine := func() replica.CrdtI {
+		return &crdt.CommutativeCRDT{Data: commutative.LWWMap{}, Stable_st: datatypes.NewLWWMap()}
+	}
+	checkInterleavings(t, newEngine, nil, apply, lwwMapOperation, nil, true)
+
+	newStableEngine := func() replica.CrdtI {
+		return &crdt.CommutativeStableCRDT{Data: commutative.StableLWWMap{}, Stable_st: datatypes.NewLWWMap()}
+	}
+	checkInterleavings(t, newStableEngine, nil, apply, lwwMapOperation, nil, true)
+}
+
+// once their writes are stable the register has no stamp and the map keeps no removed keys
+func TestStableLWW(t *testing.T) {
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	register := &crdt.CommutativeStableCRDT{Data: commutative.StableLWWRegister{}, Stable_st: datatypes.LWWRegister{}}
+	m := &crdt.CommutativeStableCRDT{Data: commutative.StableLWWMap{}, Stable_st: datatypes.NewLWWMap()}
+	writes := []communication.Operation{
+		{Type: "Write", Value: "a", Version: version(map[string]uint64{"0": 1}), OriginID: "0"},
+		{Type: "Write", Value: "b", Version: version(map[string]uint64{"1": 1}), OriginID: "1"},
+	}
+	ops := []communication.Operation{
+		{Type: "Set", Value: datatypes.LWWOpValue{Key: "x", Value: 1}, Version: version(map[string]uint64{"0": 1}), OriginID: "0"},
+		{Type: "Set", Value: datatypes.LWWOpValue{Key: "y", Value: 2}, Version: version(map[string]uint64{"0": 2}), OriginID: "0"},
+		{Type: "Rem", Value: datatypes.LWWOpValue{Key: "x"}, Version: version(map[string]uint64{"0": 2, "1": 1}), OriginID: "1"},
+		{Type: "Set", Value: datatypes.LWWOpValue{Key: "x", Value: 3}, Version: version(map[string]uint64{"0": 1, "2": 1}), OriginID: "2"},
+	}
+	for _, op := range writes {
+		register.Effect(op)
+	}
+	for _, op := range ops {
+		m.Effect(op)
+	}
+	if st := m.Stable_st.(*datatypes.LWWMap); st.Entries() != 2 {
+		t.Error("Map keeps ", st.Entries(), " keys before stabilizing, expected 2 with the removed one")
+	}
+
+	for _, op := range writes {
+		register.Stabilize(op)
+	}
+	for _, op := range ops {
+		m.Stabilize(op)
+	}
+	if st := register.Stable_st.(datatypes.LWWRegister); st.Value != "b" || st.Stamp != (datatypes.Stamp{}) {
+		t.Error("Register is ", st, " after stabilizing, expected b without stamp")
+	}
+	if st := m.Stable_st.(*datatypes.LWWMap); st.Entries() != 1 || !reflect.DeepEqual(st.Values(), map[string]any{"y": 2}) {
+		t.Error("Map keeps ", st.Entries(), " keys with values ", st.Values(), " after stabilizing, expected only y")
+	}
+	if st, _ := m.Read(replica.Stable); !reflect.DeepEqual(st, map[string]any{"y": 2}) {
+		t.Error("Stable read is ", st, ", expected map[y:2]")
+	}
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdtcheck"
+	"library/packages/datatypes"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/datatypes/ecro/custom"
+	"library/packages/datatypes/persistent"
+	semidirect "library/packages/datatypes/semidirect"
+	"strconv"
+	"testing"
+)
+
+var addWinsGenerator = crdtcheck.Generator{
+	State: persistent.NewSet[any](),
+	Operation: func(state any, choice int) (string, any) {
+		if choice%2 == 0 {
+			return "Rem", choice / 2 % 5
+		}
+		return "Add", choice / 2 % 5
+	},
+	Equal: func(st1 any, st2 any) bool {
+		return st1.(*persistent.Set[any]).Equal(st2.(*persistent.Set[any]))
+	},
+}
+
+func TestLawsAddWins(t *testing.T) {
+	if err := crdtcheck.CheckEcro(ecro.AddWins{}, addWinsGenerator, nil); err != nil {
+		t.Error(err)
+	}
+	if err := crdtcheck.CheckSemidirect(semidirect.AddWins{}, addWinsGenerator, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLawsRemoveWins(t *testing.T) {
+	if err := crdtcheck.CheckEcro(ecro.RemoveWins{}, addWinsGenerator, nil); err != nil {
+		t.Error(err)
+	}
+	if err := crdtcheck.CheckSemidirect(semidirect.RemoveWins{}, addWinsGenerator, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLawsRGA(t *testing.T) {
+	root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+	gen := crdtcheck.Generator{
+		State: datatypes.NewSequence(root),
+		Operation: func(state any, choice int) (string, any) {
+			vertices := state.(*datatypes.Sequence).Vertices()
+			v := vertices[choice%len(vertices)]
+			if choice%2 == 0 && v.Value != "" && v.Value != nil {
+				return "Rem", datatypes.RGAOpValue{V: v}
+			}
+			return "Add", datatypes.RGAOpValue{Value: strconv.Itoa(choice), V: v}
+		},
+		Equal: func(st1 any, st2 any) bool {
+			return datatypes.RGAEqual(st1.(*datatypes.Sequence).Vertices(), st2.(*datatypes.Sequence).Vertices())
+		},
+	}
+
+	if err := crdtcheck.CheckEcro(ecro.RGA{Id: "0"}, gen, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLawsSocial(t *testing.T) {
+	types := []string{"accept", "breakup", "request", "reject"}
+	gen := crdtcheck.Generator{
+		State: custom.SocialState{
+			Friends:    [5]*persistent.Set[any]{persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any]()},
+			Requesters: [5]*persistent.Set[any]{persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any]()},
+		},
+		Operation: func(state any, choice int) (string, any) {
+			return types[choice%4], custom.SocialOpValue{From: choice / 4 % 5, To: choice / 20 % 5}
+		},
+		Equal: func(st1 any, st2 any) bool {
+			return custom.CompareSocialStates(st1.(custom.SocialState), st2.(custom.SocialState))
+		},
+	}
+
+	if err := crdtcheck.CheckEcro(custom.Social{}, gen, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// AddWins that claims every pair of operations commutes
+type commutingAddWins struct {
+	ecro.AddWins
+}
+
+func (a commutingAddWins) Commutes(op1 communication.Operation, op2 communication.Operation) bool {
+	return true
+}
+
+// the checker finds that an add and a remove of the same element do not commute, and shrinks the counterexample to them
+func TestLawsShrink(t *testing.T) {
+	err := crdtcheck.CheckEcro(commutingAddWins{}, addWinsGenerator, &crdtcheck.Config{Concurrent: 4})
+	c, ok := err.(*crdtcheck.Counterexample)
+	if !ok {
+		t.Fatal("Expected a counterexample, got ", err)
+	}
+	if c.Law != "commuting operations commute" || len(c.History) != 0 || len(c.Operations) != 2 || c.Operations[0].Value != c.Operations[1].Value {
+		t.Error("Counterexample was not shrunk: ", c)
+	}
+}
@@ -1,106 +1,188 @@
 package test
 
 import (
-	datatypes "library/packages/datatypes/ecro"
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	ecro "library/packages/datatypes/ecro"
 	"library/packages/replica"
-	"math/rand"
 	"reflect"
-	"strconv"
-	"sync"
+	"sort"
+	"strings"
 	"testing"
-	"testing/quick"
+	"time"
 )
 
-func TestMVRegister(t *testing.T) {
+// merge of the values of a multi-value register used by the tests
+func mvSum(values []int) int {
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
 
-	// Define property to test
-	property := func(adds []int, numReplicas int) bool {
+// random write, or resolve of the current values by their sum
+func mvPrepare(r *replica.Replica, choice int) communication.Operation {
+	if choice%5 == 0 {
+		op, _ := ecro.Resolve(r, mvSum)
+		return op
+	}
+	return r.Prepare("Write", choice)
+}
 
-		// Initialize channels
-		channels := map[string]chan interface{}{}
-		for i := 0; i < numReplicas; i++ {
-			channels[strconv.Itoa(i)] = make(chan interface{})
+// writes of a trace that no other write of the trace has seen, the values a multi-value register shows
+func mvWrites(trace []communication.Operation) []communication.Operation {
+	writes := []communication.Operation{}
+	for _, op := range trace {
+		seen := false
+		for _, other := range trace {
+			if op.Version.Compare(other.Version) == communication.Descendant {
+				seen = true
+				break
+			}
 		}
-
-		// Initialize replicas
-		replicas := make([]*replica.Replica, numReplicas)
-		for i := 0; i < numReplicas; i++ {
-			replicas[i] = datatypes.NewMVRegisterReplica(strconv.Itoa(i), channels, (numReplicas-1)*len(adds))
+		if !seen {
+			writes = append(writes, op)
 		}
+	}
+	return writes
+}
 
-		// Start a goroutine for each replica
-		var wg sync.WaitGroup
-		for i := range replicas {
-			wg.Add(1)
-			go func(r *replica.Replica, adds []int) {
-				defer wg.Done()
-				// Perform random number of add operations with random delays
-				for j := 0; j < len(adds); j++ {
-					k, _ := strconv.Atoi(r.GetID())
-					r.Prepare("Add", k*5+j)
-				}
-			}(replicas[i], adds)
-		}
+// values a multi-value register shows after the operations of a trace, ordered by their stamps
+func mvValues(trace []communication.Operation) []int {
+	writes := mvWrites(trace)
+	sort.Slice(writes, func(i, j int) bool {
+		return datatypes.NewStamp(writes[i].Version, writes[i].OriginID).Less(datatypes.NewStamp(writes[j].Version, writes[j].OriginID))
+	})
+	values := []int{}
+	for _, op := range writes {
+		values = append(values, op.Value.(int))
+	}
+	return values
+}
 
-		// Wait for all goroutines to finish
-		wg.Wait()
-
-		// Wait for all replicas to receive all messages
-		for {
-			flag := false
-			for i := 0; i < numReplicas; i++ {
-				if replicas[i].Crdt.NumOps() == uint64(numReplicas*(len(adds))) {
-					flag = true
-				} else {
-					flag = false
-					break
-				}
-			}
-			if flag {
-				break
-			}
-		}
+func TestMVRegister(t *testing.T) {
 
-		//Check that all replicas have the same state
-		for i := 1; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
-			stt, _ := replicas[0].Crdt.Query()
-			if !reflect.DeepEqual(st, stt) {
-				for i := 0; i < numReplicas; i++ {
-					st, _ := replicas[i].Crdt.Query()
-					t.Error("Replica ", i, ": ", st)
-				}
+	//Check that all replicas show the writes of the trace that no other write has seen
+	check := func(replicas []*replica.Replica, trace []communication.Operation) bool {
+		for i := range replicas {
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			if !reflect.DeepEqual(st, mvValues(trace)) {
+				t.Error("Replica ", i, ": ", st, " differs from the concurrent writes: ", mvValues(trace))
 				return false
 			}
 		}
-		for i := 0; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
-			t.Log("Replica ", i, ": ", st)
-		}
 		return true
 	}
+	checkReplicas(t, ecro.NewMVRegisterReplica[int], mvPrepare, check)
+}
 
-	// Define generator to limit input size
-	gen := func(vals []reflect.Value, rand *rand.Rand) {
-		numAdds := 5
-		adds := make([]int, numAdds)
+func TestInterleavingsMVRegister(t *testing.T) {
+	apply := func(state any, operations []communication.Operation) any {
+		return state
+	}
+	write := func(state any, choice int) (string, any) {
+		return "Write", choice
+	}
+	newEngine := func() replica.CrdtI {
+		return &crdt.CommutativeStableCRDT{Data: ecro.MVRegister[int]{}, Stable_st: datatypes.NewMVRegister[int]()}
+	}
+	checkInterleavings(t, newEngine, nil, apply, write, nil, true)
+}
 
-		for i := 0; i < numAdds; i++ {
-			adds[i] = rand.Intn(10)
+// a write replaces the concurrent writes it has seen and stable writes keep no version
+func TestMVRegisterStabilize(t *testing.T) {
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+
+	// replica 0 writes ab after a and b while replica 1 writes d after b
+	ops := []communication.Operation{
+		{Type: "Write", Value: "a", Version: version(map[string]uint64{"0": 1}), OriginID: "0"},
+		{Type: "Write", Value: "b", Version: version(map[string]uint64{"1": 1}), OriginID: "1"},
+		{Type: "Write", Value: "ab", Version: version(map[string]uint64{"0": 2, "1": 1}), OriginID: "0"},
+		{Type: "Write", Value: "d", Version: version(map[string]uint64{"1": 2}), OriginID: "1"},
+	}
+	registers := make([]*crdt.CommutativeStableCRDT, 2)
+	for i := range registers {
+		registers[i] = &crdt.CommutativeStableCRDT{Data: ecro.MVRegister[string]{}, Stable_st: datatypes.NewMVRegister[string]()}
+	}
+	for _, op := range ops {
+		registers[0].Effect(op)
+	}
+	for _, i := range []int{0, 1, 3, 2} {
+		registers[1].Effect(ops[i])
+	}
+
+	for i, register := range registers {
+		if st, _ := register.Read(replica.Optimistic); !reflect.DeepEqual(st, []string{"d", "ab"}) {
+			t.Error("Replica ", i, " has values ", st, ", expected [d ab]")
 		}
+	}
 
-		vals[0] = reflect.ValueOf(adds)
-		vals[1] = reflect.ValueOf(3)
+	for _, op := range ops {
+		registers[0].Stabilize(op)
+	}
+	for _, w := range registers[0].Stable_st.(datatypes.MVRegister[string]).Writes() {
+		if w.Version.Sum() != 0 {
+			t.Error("Write ", w.Value, " keeps version ", w.Version.ReturnVCString(), " after stabilizing")
+		}
 	}
+	if st, _ := registers[0].Read(replica.Stable); !reflect.DeepEqual(st, []string{"d", "ab"}) {
+		t.Error("Stable read is ", st, ", expected [d ab]")
+	}
+}
 
-	// Define config for quick.Check
-	config := &quick.Config{
-		MaxCount: 1,
-		Values:   gen,
+// a resolve broadcasts the merge of the values its replica shows as a plain write
+func TestMVRegisterResolve(t *testing.T) {
+	channels := map[string]chan interface{}{"0": make(chan interface{}), "1": make(chan interface{})}
+	replicas := []*replica.Replica{
+		ecro.NewMVRegisterReplica[string]("0", channels, 0),
+		ecro.NewMVRegisterReplica[string]("1", channels, 0),
+	}
+	wait := func(ops uint64) {
+		for replicas[0].Crdt.NumOps() != ops || replicas[1].Crdt.NumOps() != ops {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	replicas[0].Prepare("Write", "a")
+	replicas[0].Prepare("Write", "b")
+	wait(2)
+	op, err := ecro.Resolve(replicas[1], func(values []string) string {
+		return strings.Join(values, "") + "!"
+	})
+	if err != nil || op.Type != "Write" || op.Value != "b!" {
+		t.Error("Resolve prepared ", op, " with error ", err, ", expected a write of b!")
+	}
+	wait(3)
+
+	for i, r := range replicas {
+		if st, _ := r.Crdt.Read(replica.Optimistic); !reflect.DeepEqual(st, []string{"b!"}) {
+			t.Error("Replica ", i, " has values ", st, ", expected [b!]")
+		}
+	}
+}
+
+// writes of values of another type are rejected at prepare, nil is only written to registers of interfaces
+func TestMVRegisterCheck(t *testing.T) {
+	channels := map[string]chan interface{}{"0": make(chan interface{})}
+	ints := ecro.NewMVRegisterReplica[int]("0", channels, 0)
+	for _, value := range []any{"1", nil, 1.5} {
+		if _, err := ints.TryPrepare("Write", value); err == nil {
+			t.Error("Write of ", value, " to a register of int returned no error")
+		}
+	}
+	if _, err := ints.TryPrepare("Write", 1); err != nil {
+		t.Error("Write of 1 to a register of int returned ", err)
+	}
+	if st, _ := ints.Crdt.Read(replica.Optimistic); !reflect.DeepEqual(st, []int{1}) {
+		t.Error("Register of int has values ", st, ", expected [1]")
 	}
 
-	// Generate and test random inputs
-	if err := quick.Check(property, config); err != nil {
-		t.Error(err)
+	anys := ecro.NewMVRegisterReplica[any]("0", map[string]chan interface{}{"0": make(chan interface{})}, 0)
+	if _, err := anys.TryPrepare("Write", nil); err != nil {
+		t.Error("Write of nil to a register of any returned ", err)
 	}
 }
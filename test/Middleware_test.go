@@ -0,0 +1,36 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/middleware"
+	"testing"
+)
+
+// a message received again, as the delay simulator can hand it over twice, is dropped instead of waiting in the delivery queue
+func TestMiddlewareDuplicates(t *testing.T) {
+	channels := map[string]chan interface{}{"0": make(chan interface{}), "1": make(chan interface{})}
+	mw := middleware.NewMiddleware("0", []string{"0", "1"}, channels, 0)
+
+	delivered := make(chan communication.Message)
+	go func() {
+		for msg := range mw.DeliverCausal {
+			if msg.Type == communication.DLV {
+				delivered <- msg
+			}
+		}
+	}()
+	send := func(ticks uint64) {
+		channels["0"] <- communication.NewMessage(communication.DLV, "Add", ticks, communication.NewVClockFromMap(map[string]uint64{"1": ticks}), "1")
+	}
+
+	send(1)
+	<-delivered
+	send(1)
+	send(2)
+	if msg := <-delivered; msg.Value != uint64(2) {
+		t.Error("Delivered ", msg.Value, " after the duplicate, expected 2")
+	}
+	if n := mw.DQ.Len(); n != 0 {
+		t.Error("Delivery queue keeps ", n, " messages, expected the duplicate to be dropped")
+	}
+}
@@ -0,0 +1,201 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/crdtcheck"
+	"library/packages/datatypes"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/replica"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// random insert, move, set or remove on the visible items of a list
+func moveOperation(items []datatypes.ListItem, root datatypes.Vertex, choice int) (string, any) {
+	prev := root
+	if n := choice / 4 % (len(items) + 1); n > 0 {
+		prev = items[n-1].Position
+	}
+	if len(items) == 0 || choice%4 == 0 {
+		return "Add", datatypes.MoveOpValue{V: prev, Value: strconv.Itoa(choice)}
+	}
+
+	element := items[choice/7%len(items)].Element
+	switch choice % 4 {
+	case 1:
+		return "Move", datatypes.MoveOpValue{Element: element, V: prev}
+	case 2:
+		return "Set", datatypes.MoveOpValue{Element: element, Value: strconv.Itoa(choice)}
+	}
+	return "Rem", datatypes.MoveOpValue{Element: element}
+}
+
+func moveListOperation(state any, choice int) (string, any) {
+	st := state.(*datatypes.MoveList)
+	return moveOperation(st.Items(), st.Positions()[0], choice)
+}
+
+func moveListEqual(st1 any, st2 any) bool {
+	return st1.(*datatypes.MoveList).Equal(st2.(*datatypes.MoveList))
+}
+
+func TestMoveRGA(t *testing.T) {
+	prepare := func(r *replica.Replica, choice int) communication.Operation {
+		root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: r.GetID()}
+		items, _ := r.Crdt.Read(replica.Optimistic)
+		return r.Prepare(moveOperation(items.([]datatypes.ListItem), root, choice))
+	}
+
+	//Check that all replicas have the same items and that no element is in two positions
+	check := func(replicas []*replica.Replica, trace []communication.Operation) bool {
+		stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
+		for i := range replicas {
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			items, items0 := st.([]datatypes.ListItem), stt.([]datatypes.ListItem)
+			if !reflect.DeepEqual(itemKeys(items), itemKeys(items0)) {
+				t.Error("Replica ", i, ": ", itemKeys(items), " differs from replica 0: ", itemKeys(items0))
+				return false
+			}
+			elements := map[any]bool{}
+			for _, k := range itemKeys(items) {
+				if elements[k[0]] {
+					t.Error("Replica ", i, " has element ", k[0], " twice: ", itemKeys(items))
+					return false
+				}
+				elements[k[0]] = true
+			}
+		}
+		return true
+	}
+	checkReplicas(t, ecro.NewMoveRGAReplica, prepare, check)
+}
+
+// element, position and value of the items
+func itemKeys(items []datatypes.ListItem) [][3]any {
+	keys := make([][3]any, len(items))
+	for i, item := range items {
+		keys[i] = [3]any{item.Element.Timestamp.(communication.VClock).ReturnVCString() + item.Element.OriginID,
+			item.Position.Timestamp.(communication.VClock).ReturnVCString() + item.Position.OriginID, item.Value}
+	}
+	return keys
+}
+
+func TestLawsMoveRGA(t *testing.T) {
+	root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+	gen := crdtcheck.Generator{State: datatypes.NewMoveList(root), Operation: moveListOperation, Equal: moveListEqual}
+
+	if err := crdtcheck.CheckEcro(ecro.MoveRGA{Id: "0"}, gen, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInterleavingsMoveRGA(t *testing.T) {
+	root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+	newEngine := func() replica.CrdtI {
+		return crdt.NewEcroCRDT("0", datatypes.NewMoveList(root), ecro.MoveRGA{Id: "0"})
+	}
+	equal := func(q1 any, q2 any) bool {
+		return reflect.DeepEqual(itemKeys(q1.([]datatypes.ListItem)), itemKeys(q2.([]datatypes.ListItem)))
+	}
+	checkInterleavings(t, newEngine, datatypes.NewMoveList(root), ecro.MoveRGA{Id: "0"}.Apply, moveListOperation, equal, true)
+
+	// replica 0 inserts a and b, then replica 1 moves a after b and back after the root once it saw replica 3 remove b,
+	// while replica 2 sets a
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	a := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 1}), OriginID: "0"}
+	b := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 2}), OriginID: "0"}
+	ops := []communication.Operation{
+		{Type: "Add", Value: datatypes.MoveOpValue{V: root, Value: "a"}, Version: a.Timestamp.(communication.VClock), OriginID: "0"},
+		{Type: "Add", Value: datatypes.MoveOpValue{V: a, Value: "b"}, Version: b.Timestamp.(communication.VClock), OriginID: "0"},
+		{Type: "Move", Value: datatypes.MoveOpValue{Element: a, V: b}, Version: version(map[string]uint64{"0": 2, "1": 1}), OriginID: "1"},
+		{Type: "Set", Value: datatypes.MoveOpValue{Element: a, Value: "A"}, Version: version(map[string]uint64{"0": 2, "2": 1}), OriginID: "2"},
+		{Type: "Rem", Value: datatypes.MoveOpValue{Element: b}, Version: version(map[string]uint64{"0": 2, "3": 1}), OriginID: "3"},
+		{Type: "Move", Value: datatypes.MoveOpValue{Element: a, V: root}, Version: version(map[string]uint64{"0": 2, "1": 2, "3": 1}), OriginID: "1"},
+	}
+	if err := crdtcheck.CheckInterleavings(newEngine, ops, equal, true); err != nil {
+		t.Error(err)
+	}
+}
+
+// concurrent moves of an element keep it once at the position of the greatest move, a concurrent set keeps its value
+func TestMoveRGAConcurrentMoves(t *testing.T) {
+	root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	a := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 1}), OriginID: "0"}
+	b := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 2}), OriginID: "0"}
+	c := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 3}), OriginID: "0"}
+	ops := []communication.Operation{
+		{Type: "Add", Value: datatypes.MoveOpValue{V: root, Value: "a"}, Version: a.Timestamp.(communication.VClock), OriginID: "0"},
+		{Type: "Add", Value: datatypes.MoveOpValue{V: a, Value: "b"}, Version: b.Timestamp.(communication.VClock), OriginID: "0"},
+		{Type: "Add", Value: datatypes.MoveOpValue{V: b, Value: "c"}, Version: c.Timestamp.(communication.VClock), OriginID: "0"},
+		// replica 1 moves a after c, replica 2 moves a after b and replica 3 renames a
+		{Type: "Move", Value: datatypes.MoveOpValue{Element: a, V: c}, Version: version(map[string]uint64{"0": 3, "1": 1}), OriginID: "1"},
+		{Type: "Move", Value: datatypes.MoveOpValue{Element: a, V: b}, Version: version(map[string]uint64{"0": 3, "2": 1}), OriginID: "2"},
+		{Type: "Set", Value: datatypes.MoveOpValue{Element: a, Value: "A"}, Version: version(map[string]uint64{"0": 3, "3": 1}), OriginID: "3"},
+	}
+
+	checkOrder := func(order []int) {
+		engine := crdt.NewEcroCRDT("0", datatypes.NewMoveList(root), ecro.MoveRGA{Id: "0"})
+		for _, i := range order {
+			engine.Effect(ops[i])
+		}
+		st, _ := engine.Read(replica.Optimistic)
+		values := []any{}
+		for _, item := range st.([]datatypes.ListItem) {
+			values = append(values, item.Value)
+		}
+		if !reflect.DeepEqual(values, []any{"b", "A", "c"}) {
+			t.Error("Delivering ", order, " gives ", values, ", expected [b A c]")
+		}
+	}
+	checkOrder([]int{0, 1, 2, 3, 4, 5})
+	checkOrder([]int{0, 1, 2, 5, 4, 3})
+}
+
+// once the moves and removes are stable only the current positions of the elements are kept
+func TestMoveRGACollect(t *testing.T) {
+	root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	a := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 1}), OriginID: "0"}
+	b := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 2}), OriginID: "0"}
+	c := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 3}), OriginID: "0"}
+	ops := []communication.Operation{
+		{Type: "Add", Value: datatypes.MoveOpValue{V: root, Value: "a"}, Version: a.Timestamp.(communication.VClock), OriginID: "0"},
+		{Type: "Add", Value: datatypes.MoveOpValue{V: a, Value: "b"}, Version: b.Timestamp.(communication.VClock), OriginID: "0"},
+		{Type: "Add", Value: datatypes.MoveOpValue{V: b, Value: "c"}, Version: c.Timestamp.(communication.VClock), OriginID: "0"},
+		// replicas 1 and 2 move a concurrently, replica 1 then removes b while replica 3 inserts d after it
+		{Type: "Move", Value: datatypes.MoveOpValue{Element: a, V: c}, Version: version(map[string]uint64{"0": 3, "1": 1}), OriginID: "1"},
+		{Type: "Move", Value: datatypes.MoveOpValue{Element: a, V: b}, Version: version(map[string]uint64{"0": 3, "2": 1}), OriginID: "2"},
+		{Type: "Rem", Value: datatypes.MoveOpValue{Element: b}, Version: version(map[string]uint64{"0": 3, "1": 2}), OriginID: "1"},
+		{Type: "Add", Value: datatypes.MoveOpValue{V: b, Value: "d"}, Version: version(map[string]uint64{"0": 3, "3": 1}), OriginID: "3"},
+	}
+	engine := crdt.NewEcroCRDT("0", datatypes.NewMoveList(root), ecro.MoveRGA{Id: "0"})
+	for _, op := range ops {
+		engine.Effect(op)
+	}
+	expected, _ := engine.Read(replica.Optimistic)
+
+	// the position of b is kept while the insert after it is unstable
+	for _, op := range ops[:6] {
+		engine.Stabilize(op)
+	}
+	if n := len(engine.Stable_st.(*datatypes.MoveList).Positions()); n > 6 {
+		t.Error("Stable state has ", n, " positions with the insert after b unstable, expected at most 6")
+	}
+
+	engine.Stabilize(ops[6])
+	if n := len(engine.Stable_st.(*datatypes.MoveList).Positions()); n != 4 {
+		t.Error("Stable state has ", n, " positions with all operations stable, expected 4 for the root, a, c and d")
+	}
+	if st, _ := engine.Read(replica.Optimistic); !reflect.DeepEqual(itemKeys(st.([]datatypes.ListItem)), itemKeys(expected.([]datatypes.ListItem))) {
+		t.Error("Read ", itemKeys(st.([]datatypes.ListItem)), " after collecting, expected ", itemKeys(expected.([]datatypes.ListItem)))
+	}
+}
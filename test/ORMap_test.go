@@ -0,0 +1,285 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	commutative "library/packages/datatypes/commutative"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/datatypes/persistent"
+	"library/packages/replica"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// root vertex of the RGAs of the maps
+var mapRoot = datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+
+// counters for the keys starting with c, multi-value registers for m, RGAs for r and add-wins sets for the others
+func newMapValue(key string) replica.CrdtI {
+	switch key[0] {
+	case 'c':
+		return &crdt.CommutativeCRDT{Data: commutative.Counter{}, Stable_st: 0}
+	case 'm':
+		return &crdt.CommutativeStableCRDT{Data: ecro.MVRegister[int]{}, Stable_st: datatypes.NewMVRegister[int]()}
+	case 'r':
+		return crdt.NewEcroCRDT("0", datatypes.NewSequence(mapRoot), ecro.RGA{Id: "0"})
+	}
+	return crdt.NewEcroCRDT("0", persistent.NewSet[any](), ecro.AddWins{})
+}
+
+// random update of a counter, a set, a register or an RGA, or remove of a key, read is the read of the map or nil
+// RGA inserts go after a vertex of the read and removes remove one of them
+func mapOperation(read any, choice int) (string, any) {
+	key := []string{"c0", "c1", "s0", "s1", "m0", "r0"}[choice%6]
+	switch {
+	case choice/6%4 == 0:
+		return crdt.MapRemove(key)
+	case key[0] == 'c':
+		return crdt.MapUpdate(key, "Inc", 1+choice%5)
+	case key[0] == 'm':
+		return crdt.MapUpdate(key, "Write", choice%10)
+	case key[0] == 'r':
+		vertices := []datatypes.Vertex{mapRoot}
+		if m, ok := read.(map[string]any); ok && m[key] != nil {
+			vertices = m[key].([]datatypes.Vertex)
+		}
+		v := vertices[choice%len(vertices)]
+		if choice/6%4 == 1 && v.Value != "" {
+			return crdt.MapUpdate(key, "Rem", datatypes.RGAOpValue{V: v})
+		}
+		return crdt.MapUpdate(key, "Add", datatypes.RGAOpValue{Value: strconv.Itoa(choice), V: v})
+	case choice/6%4 == 1:
+		return crdt.MapUpdate(key, "Rem", choice%3)
+	}
+	return crdt.MapUpdate(key, "Add", choice%3)
+}
+
+// check if two reads of a map have the same keys with equal counters, sets, registers and RGAs
+func mapEqual(q1 any, q2 any) bool {
+	m1, m2 := q1.(map[string]any), q2.(map[string]any)
+	if len(m1) != len(m2) {
+		return false
+	}
+	for key, v1 := range m1 {
+		v2, ok := m2[key]
+		if !ok {
+			return false
+		}
+		switch v1 := v1.(type) {
+		case *persistent.Set[any]:
+			if s2, isSet := v2.(*persistent.Set[any]); !isSet || !v1.Equal(s2) {
+				return false
+			}
+		case []datatypes.Vertex:
+			if s2, isRGA := v2.([]datatypes.Vertex); !isRGA || !datatypes.RGAEqual(v1, s2) {
+				return false
+			}
+		default:
+			if !reflect.DeepEqual(v1, v2) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestORMap(t *testing.T) {
+
+	// Define property to test
+	property := func(operations int, numReplicas int, delay int) bool {
+
+		// Initialize channels
+		channels := map[string]chan interface{}{}
+		for i := 0; i < numReplicas; i++ {
+			channels[strconv.Itoa(i)] = make(chan interface{})
+		}
+
+		// Initialize replicas
+		replicas := make([]*replica.Replica, numReplicas)
+		for i := 0; i < numReplicas; i++ {
+			replicas[i] = crdt.NewORMapReplica(strconv.Itoa(i), newMapValue, channels, delay)
+		}
+
+		// Start a goroutine for each replica
+		var wg sync.WaitGroup
+		for i := range replicas {
+			wg.Add(1)
+			go func(r *replica.Replica) {
+				defer wg.Done()
+				for j := 0; j < operations; j++ {
+					st, _ := r.Crdt.Read(replica.Optimistic)
+					r.Prepare(mapOperation(st, rand.Intn(1000)))
+				}
+			}(replicas[i])
+		}
+
+		// Wait for all goroutines to finish
+		wg.Wait()
+
+		// Wait for all replicas to receive all messages
+		for {
+			flag := 0
+			for i := 0; i < numReplicas; i++ {
+				if replicas[i].Crdt.NumOps() == uint64(numReplicas*operations) {
+					flag += 1
+				}
+			}
+			if flag == numReplicas {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		//Check that all replicas have the same state
+		for i := 1; i < numReplicas; i++ {
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
+			if !mapEqual(st, stt) {
+				t.Error("Replica ", i, ": ", st, " differs from replica 0: ", stt)
+				return false
+			}
+		}
+		return true
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		operations, numReplicas := 10+rand.Intn(30), 2+rand.Intn(2)
+		vals[0] = reflect.ValueOf(operations)
+		vals[1] = reflect.ValueOf(numReplicas)
+		//half of the runs hold back all the remote operations of a replica, the delay simulator delivers them in random order
+		vals[2] = reflect.ValueOf(rand.Intn(2) * (numReplicas - 1) * operations)
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 10,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInterleavingsORMap(t *testing.T) {
+	newEngine := func() replica.CrdtI {
+		return crdt.NewORMap(newMapValue)
+	}
+	// the state of a replica is the operations it received, operations are chosen from the read of a map with them
+	apply := func(state any, operations []communication.Operation) any {
+		return append(append([]communication.Operation{}, state.([]communication.Operation)...), operations...)
+	}
+	newOp := func(state any, choice int) (string, any) {
+		st, _ := newEngineWith(newEngine, state.([]communication.Operation)).Read(replica.Optimistic)
+		return mapOperation(st, choice)
+	}
+	checkInterleavings(t, newEngine, []communication.Operation{}, apply, newOp, mapEqual, true)
+}
+
+// a remove resets the operations it observed, a concurrent update keeps its key
+func TestORMapRemove(t *testing.T) {
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	op := func(opType string, value any, clock map[string]uint64, originID string) communication.Operation {
+		return communication.Operation{Type: opType, Value: value, Version: version(clock), OriginID: originID}
+	}
+	update := func(key string, opType string, value any) any {
+		_, v := crdt.MapUpdate(key, opType, value)
+		return v
+	}
+	remove := func(key string) any {
+		_, v := crdt.MapRemove(key)
+		return v
+	}
+	ops := []communication.Operation{
+		op("Update", update("c", "Inc", 2), map[string]uint64{"0": 1}, "0"),
+		op("Update", update("s", "Add", "x"), map[string]uint64{"0": 2}, "0"),
+		// replica 1 removes both keys while replica 2 increments the counter
+		op("Remove", remove("c"), map[string]uint64{"0": 2, "1": 1}, "1"),
+		op("Remove", remove("s"), map[string]uint64{"0": 2, "1": 2}, "1"),
+		op("Update", update("c", "Inc", 5), map[string]uint64{"0": 2, "2": 1}, "2"),
+	}
+
+	for _, order := range [][]int{{0, 1, 2, 3, 4}, {0, 1, 4, 2, 3}} {
+		m := crdt.NewORMap(newMapValue)
+		for _, i := range order {
+			m.Effect(ops[i])
+		}
+		st, _ := m.Read(replica.Optimistic)
+		if !reflect.DeepEqual(st, map[string]any{"c": 5}) {
+			t.Error("Delivering ", order, " gives ", st, ", expected map[c:5]")
+		}
+
+		for _, i := range order {
+			m.Stabilize(ops[i])
+		}
+		stable, _ := m.Read(replica.Stable)
+		if !reflect.DeepEqual(stable, map[string]any{"c": 5}) {
+			t.Error("Stabilizing ", order, " gives ", stable, ", expected map[c:5]")
+		}
+		if n := m.NumUnstable(); n != 0 {
+			t.Error("Stabilizing ", order, " keeps ", n, " operations to rebuild the keys, expected 0")
+		}
+	}
+}
+
+// a remove of a register or an RGA rebuilds it from the concurrent updates,
+// an insert after a vertex whose insert was removed goes after the root
+func TestORMapRebuild(t *testing.T) {
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	op := func(opType string, value any, clock map[string]uint64, originID string) communication.Operation {
+		return communication.Operation{Type: opType, Value: value, Version: version(clock), OriginID: originID}
+	}
+	update := func(key string, opType string, value any) any {
+		_, v := crdt.MapUpdate(key, opType, value)
+		return v
+	}
+	remove := func(key string) any {
+		_, v := crdt.MapRemove(key)
+		return v
+	}
+	x := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 1}), Value: "x", OriginID: "0"}
+	y := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 3}), Value: "y", OriginID: "0"}
+	ops := []communication.Operation{
+		op("Update", update("r", "Add", datatypes.RGAOpValue{V: mapRoot, Value: "x"}), map[string]uint64{"0": 1}, "0"),
+		op("Update", update("m", "Write", 1), map[string]uint64{"0": 2}, "0"),
+		// replica 1 removes both keys while replica 0 inserts y after x and writes 2
+		op("Remove", remove("r"), map[string]uint64{"0": 2, "1": 1}, "1"),
+		op("Remove", remove("m"), map[string]uint64{"0": 2, "1": 2}, "1"),
+		op("Update", update("r", "Add", datatypes.RGAOpValue{V: x, Value: "y"}), map[string]uint64{"0": 3}, "0"),
+		op("Update", update("m", "Write", 2), map[string]uint64{"0": 4}, "0"),
+	}
+	expected := map[string]any{"r": []datatypes.Vertex{mapRoot, y}, "m": []int{2}}
+
+	for _, order := range [][]int{{0, 1, 2, 3, 4, 5}, {0, 1, 4, 5, 2, 3}, {0, 1, 4, 2, 5, 3}} {
+		m := crdt.NewORMap(newMapValue)
+		for _, i := range order {
+			m.Effect(ops[i])
+		}
+		if st, _ := m.Read(replica.Optimistic); !mapEqual(st, expected) {
+			t.Error("Delivering ", order, " gives ", st, ", expected ", expected)
+		}
+
+		for _, i := range order {
+			m.Stabilize(ops[i])
+		}
+		if stable, _ := m.Read(replica.Stable); !mapEqual(stable, expected) {
+			t.Error("Stabilizing ", order, " gives ", stable, ", expected ", expected)
+		}
+		if n := m.NumUnstable(); n != 0 {
+			t.Error("Stabilizing ", order, " keeps ", n, " operations to rebuild the keys, expected 0")
+		}
+	}
+}
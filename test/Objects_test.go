@@ -0,0 +1,149 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	commutative "library/packages/datatypes/commutative"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/datatypes/persistent"
+	"library/packages/replica"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestObjects(t *testing.T) {
+
+	// Define property to test
+	property := func(operations int, numReplicas int, numSets int) bool {
+
+		// Initialize channels
+		channels := map[string]chan interface{}{}
+		for i := 0; i < numReplicas; i++ {
+			channels[strconv.Itoa(i)] = make(chan interface{})
+		}
+
+		// Initialize replicas, each one with a counter, a sequence and sets created on their first operation
+		replicas := make([]*replica.Replica, numReplicas)
+		for i := 0; i < numReplicas; i++ {
+			id := strconv.Itoa(i)
+			objects := replica.NewObjects(func(key string) replica.CrdtI {
+				return crdt.NewEcroCRDT(id, persistent.NewSet[any](), ecro.AddWins{})
+			})
+			objects.Add("counter", &crdt.CommutativeCRDT{Data: commutative.Counter{}, Stable_st: 0})
+			root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: id}
+			objects.Add("sequence", crdt.NewEcroCRDT(id, datatypes.NewSequence(root), ecro.RGA{Id: id}))
+			replicas[i] = replica.NewObjectsReplica(id, objects, channels, 0)
+		}
+
+		// Start a goroutine for each replica
+		var wg sync.WaitGroup
+		for i := range replicas {
+			wg.Add(1)
+			go func(r *replica.Replica) {
+				defer wg.Done()
+				objects := r.Crdt.(*replica.Objects)
+				for j := 0; j < operations; j++ {
+					switch rand.Intn(3) {
+					case 0:
+						r.PrepareObject("counter", "Add", rand.Intn(10))
+					case 1:
+						sequence, _ := objects.Object("sequence")
+						q, _ := sequence.Read(replica.Optimistic)
+						vertices := q.([]datatypes.Vertex)
+						v := vertices[rand.Intn(len(vertices))]
+						r.PrepareObject("sequence", "Add", datatypes.RGAOpValue{Value: strconv.Itoa(j), V: v})
+					case 2:
+						if rand.Intn(2) == 0 {
+							r.PrepareObject("set"+strconv.Itoa(rand.Intn(numSets)), "Add", rand.Intn(5))
+						} else {
+							r.PrepareObject("set"+strconv.Itoa(rand.Intn(numSets)), "Rem", rand.Intn(5))
+						}
+					}
+				}
+			}(replicas[i])
+		}
+
+		// Wait for all goroutines to finish
+		wg.Wait()
+
+		// Wait for all replicas to receive all messages
+		for {
+			flag := 0
+			for i := 0; i < numReplicas; i++ {
+				if replicas[i].Crdt.NumOps() == uint64(numReplicas*operations) {
+					flag += 1
+				}
+			}
+			if flag == numReplicas {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		//Check that every object has the same state in all replicas
+		for i := 1; i < numReplicas; i++ {
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
+			objects, objects0 := st.(map[string]any), stt.(map[string]any)
+			if len(objects) != len(objects0) {
+				t.Error("Replica ", i, " has objects ", objects, " and replica 0 has ", objects0)
+				return false
+			}
+			for key, q := range objects {
+				var equal bool
+				switch key {
+				case "counter":
+					equal = q == objects0[key]
+				case "sequence":
+					equal = datatypes.RGAEqual(q.([]datatypes.Vertex), objects0[key].([]datatypes.Vertex))
+				default:
+					equal = objects0[key] != nil && q.(*persistent.Set[any]).Equal(objects0[key].(*persistent.Set[any]))
+				}
+				if !equal {
+					t.Error("Object ", key, " of replica ", i, ": ", q, " differs from replica 0: ", objects0[key])
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		vals[0] = reflect.ValueOf(10 + rand.Intn(20))
+		vals[1] = reflect.ValueOf(2 + rand.Intn(2))
+		vals[2] = reflect.ValueOf(1 + rand.Intn(3))
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 10,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+// operations of an object that was not added yet wait for it
+func TestObjectsPending(t *testing.T) {
+	objects := replica.NewObjects(nil)
+	op := communication.Operation{Type: "Add", Value: replica.ObjectValue{Key: "set", Value: 3}, Version: communication.NewVClockFromMap(map[string]uint64{"1": 1}), OriginID: "1"}
+	objects.Effect(op)
+	objects.Stabilize(op)
+
+	set := crdt.NewEcroCRDT("0", persistent.NewSet[any](), ecro.AddWins{})
+	objects.Add("set", set)
+	if q, _ := set.Read(replica.Optimistic); !q.(*persistent.Set[any]).Contains(3) || set.NumOps() != 1 || set.NumSOps() != 1 {
+		t.Error("Set has state ", q, " after adding it, expected it to contain 3")
+	}
+}
@@ -0,0 +1,167 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+type graphEdge struct {
+	Source int
+	Target int
+	Label  string
+}
+
+func TestOpGraph(t *testing.T) {
+
+	// Define property to test
+	property := func(numOps int, edges []graphEdge, seed int64) bool {
+		ops := make([]communication.Operation, numOps)
+		for i := range ops {
+			id := strconv.Itoa(i % 3)
+			ops[i] = communication.Operation{Type: "Add", Version: communication.NewVClockFromMap(map[string]uint64{id: uint64(i + 1)}), OriginID: id}
+		}
+		hash := func(i int) string {
+			return strconv.FormatUint(ops[i].Version.Sum(), 10) + ops[i].OriginID
+		}
+
+		// build the same graph adding vertices and edges in two different orders
+		g1, g2 := crdt.NewOpGraph(), crdt.NewOpGraph()
+		for _, op := range ops {
+			g1.AddVertex(op)
+		}
+		for _, e := range edges {
+			g1.AddEdge(hash(e.Source), hash(e.Target), e.Label)
+		}
+		r := rand.New(rand.NewSource(seed))
+		for _, i := range r.Perm(numOps) {
+			g2.AddVertex(ops[i])
+		}
+		for _, i := range r.Perm(len(edges)) {
+			g2.AddEdge(hash(edges[i].Source), hash(edges[i].Target), edges[i].Label)
+		}
+		shuffled := make([]communication.Operation, numOps)
+		for i, j := range r.Perm(numOps) {
+			shuffled[i] = ops[j]
+		}
+
+		order1, removed1 := g1.TopologicalSort(ops)
+		order2, removed2 := g2.TopologicalSort(shuffled)
+
+		// the order does not depend on the order vertices and edges were added
+		if len(order1) != numOps || !reflect.DeepEqual(order1, order2) || !reflect.DeepEqual(removed1, removed2) {
+			t.Error("Orders ", order1, " and ", order2, " differ")
+			return false
+		}
+
+		// every edge that was not removed to break a cycle is respected
+		position := map[string]int{}
+		for i, op := range order1 {
+			position[strconv.FormatUint(op.Version.Sum(), 10)+op.OriginID] = i
+		}
+		cut := map[[2]string]bool{}
+		for _, e := range removed1 {
+			cut[[2]string{e.Source, e.Target}] = true
+		}
+		for _, e := range edges {
+			if !cut[[2]string{hash(e.Source), hash(e.Target)}] && position[hash(e.Source)] > position[hash(e.Target)] {
+				t.Error("Edge ", e, " is not respected by ", order1)
+				return false
+			}
+		}
+
+		// removing a vertex removes its edges, removed edges are kept until both endpoints are removed
+		for i := 0; i < numOps; i++ {
+			g1.RemoveVertex(hash(i))
+			for _, e := range g1.RemovedEdges() {
+				_, ok1 := g1.Vertex(e.Source)
+				_, ok2 := g1.Vertex(e.Target)
+				if !ok1 && !ok2 {
+					t.Error("Removed edge ", e, " is kept after removing both endpoints")
+					return false
+				}
+			}
+			for j := i + 1; j < numOps; j++ {
+				inDegree := 0
+				for _, e := range edges {
+					if e.Target == j && e.Source > i {
+						inDegree++
+					}
+				}
+				if g1.InDegree(hash(j)) != inDegree {
+					t.Error("Vertex ", j, " has ", g1.InDegree(hash(j)), " incoming edges, expected ", inDegree)
+					return false
+				}
+			}
+		}
+		return g1.Len() == 0 && len(g1.RemovedEdges()) == 0
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		numOps := 2 + rand.Intn(30)
+		edges := []graphEdge{}
+		added := map[[2]int]bool{}
+
+		for i := 0; i < numOps*2; i++ {
+			source, target := rand.Intn(numOps), rand.Intn(numOps)
+			if source == target || added[[2]int{source, target}] {
+				continue
+			}
+			added[[2]int{source, target}] = true
+			// happened before edges follow the creation order, arbitration edges can go both ways and make cycles
+			if source < target && rand.Intn(2) == 0 {
+				edges = append(edges, graphEdge{Source: source, Target: target, Label: "hb"})
+			} else {
+				edges = append(edges, graphEdge{Source: source, Target: target, Label: "ao"})
+			}
+		}
+
+		vals[0] = reflect.ValueOf(numOps)
+		vals[1] = reflect.ValueOf(edges)
+		vals[2] = reflect.ValueOf(rand.Int63())
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 200,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+// x and y are ordered both ways and a, ordered before b, comes after y,
+// only an edge of the cycle between x and y is removed even if the edge from a to b has a smaller id
+func TestOpGraphCycle(t *testing.T) {
+	op := func(ticks uint64, id string) communication.Operation {
+		return communication.Operation{Type: "Add", Version: communication.NewVClockFromMap(map[string]uint64{id: ticks}), OriginID: id}
+	}
+	a, b, x, y := op(1, "0"), op(2, "1"), op(3, "0"), op(4, "1")
+
+	g := crdt.NewOpGraph()
+	for _, o := range []communication.Operation{a, b, x, y} {
+		g.AddVertex(o)
+	}
+	g.AddEdge("30", "41", "ao")
+	g.AddEdge("41", "30", "ao")
+	g.AddEdge("41", "10", "hb")
+	g.AddEdge("10", "21", "ao")
+
+	order, removed := g.TopologicalSort([]communication.Operation{a, b, x, y})
+	if len(removed) != 1 || removed[0].Source != "30" || removed[0].Target != "41" {
+		t.Error("Removed edges ", removed, ", expected the edge from x to y")
+	}
+	if !reflect.DeepEqual(order, []communication.Operation{y, a, b, x}) {
+		t.Error("Order ", order, ", expected [y a b x]")
+	}
+}
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/datatypes/persistent"
+	semidirect "library/packages/datatypes/semidirect"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+type mapUpdate struct {
+	Key    int
+	Value  int
+	Delete bool
+}
+
+func TestPersistentMap(t *testing.T) {
+
+	// Define property to test
+	property := func(updates []mapUpdate) bool {
+		m := persistent.NewMap[int, int]()
+		model := map[int]int{}
+
+		//every version of the map is kept with a copy of its model
+		versions := []*persistent.Map[int, int]{}
+		models := []map[int]int{}
+
+		for _, u := range updates {
+			if u.Delete {
+				m.Delete(u.Key)
+				delete(model, u.Key)
+			} else {
+				m.Set(u.Key, u.Value)
+				model[u.Key] = u.Value
+			}
+
+			versions = append(versions, m.Clone())
+			modelCpy := map[int]int{}
+			for k, v := range model {
+				modelCpy[k] = v
+			}
+			models = append(models, modelCpy)
+		}
+
+		//updates after a copy do not change the copy
+		for i, version := range versions {
+			if version.Len() != len(models[i]) {
+				t.Error("Version ", i, " has ", version.Len(), " keys, expected ", len(models[i]))
+				return false
+			}
+			for k, v := range models[i] {
+				if value, ok := version.Get(k); !ok || value != v {
+					t.Error("Version ", i, " key ", k, " has ", value, " expected ", v)
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		numUpdates := 300
+		updates := make([]mapUpdate, numUpdates)
+
+		for i := 0; i < numUpdates; i++ {
+			updates[i] = mapUpdate{Key: rand.Intn(100), Value: rand.Int(), Delete: rand.Intn(3) == 0}
+		}
+
+		vals[0] = reflect.ValueOf(updates)
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 50,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+// an add is kept by a concurrent remove repaired against it, and applying operations leaves the state they are applied to unchanged
+func TestPersistentAddWins2(t *testing.T) {
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	set := semidirect.AddWins2{}
+	add := communication.Operation{Type: "Add", Value: "x", Version: version(map[string]uint64{"0": 1}), OriginID: "0"}
+	addAgain := communication.Operation{Type: "Add", Value: "x", Version: version(map[string]uint64{"1": 1}), OriginID: "1"}
+	rem := communication.Operation{Type: "Rem", Value: "x", Version: version(map[string]uint64{"0": 1, "2": 1}), OriginID: "2"}
+
+	empty := persistent.NewMap[any, *persistent.Set[int]]()
+	added := set.Apply(empty, []communication.Operation{add, addAgain})
+	removed := set.Apply(added, []communication.Operation{set.Repair(addAgain, rem)})
+	if st := set.Query(removed).(*persistent.Set[any]); !st.Equal(persistent.NewSet[any]("x")) {
+		t.Error("Set is ", st, " after a remove concurrent with an add, expected [x]")
+	}
+	if st := set.Query(set.Apply(removed, []communication.Operation{rem})).(*persistent.Set[any]); st.Cardinality() != 0 {
+		t.Error("Set is ", st, " after a remove that has seen every add, expected []")
+	}
+	if st := set.Query(added).(*persistent.Set[any]); !st.Equal(persistent.NewSet[any]("x")) || empty.Len() != 0 {
+		t.Error("Applying operations changed the states they were applied to")
+	}
+}
+
+// copy a set and add an element to the copy, as datatypes do when operations are replayed
+func BenchmarkPersistentSet(b *testing.B) {
+	for _, numElems := range []int{1000, 10000, 100000} {
+		b.Run("elems="+strconv.Itoa(numElems), func(b *testing.B) {
+			s := persistent.NewSet[any]()
+			for i := 0; i < numElems; i++ {
+				s.Add(i)
+			}
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				cpy := s.Clone()
+				cpy.Add(numElems + n)
+			}
+		})
+	}
+}
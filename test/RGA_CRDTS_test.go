@@ -1,6 +1,9 @@
 package test
 
 import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/crdtcheck"
 	"library/packages/datatypes"
 	commutative "library/packages/datatypes/commutative"
 	crdtECRO "library/packages/datatypes/crdtECRO"
@@ -46,7 +49,7 @@ func TestRGACRDTS(t *testing.T) {
 
 				for j := 0; j < operations; j++ {
 					//choose a predecessor or a vertex to remove randomly from query
-					rgaState, _ := r.Crdt.Query()
+					rgaState, _ := r.Crdt.Read(replica.Optimistic)
 					v := rgaState.([]datatypes.Vertex)[rand.Intn(len(rgaState.([]datatypes.Vertex)))]
 
 					//choose random leter to add
@@ -97,12 +100,12 @@ func TestRGACRDTS(t *testing.T) {
 
 		//Check that all replicas have the same state
 		for i := 1; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
-			stt, _ := replicas[0].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
 			if !datatypes.RGAEqual(st.([]datatypes.Vertex), stt.([]datatypes.Vertex)) {
 				for i := 0; i < numReplicas; i++ {
 					t.Error("Replica ", i)
-					q, _ := replicas[i].Crdt.Query()
+					q, _ := replicas[i].Crdt.Read(replica.Optimistic)
 					for j := 0; j < len(q.([]datatypes.Vertex)); j++ {
 						log.Println(q.([]datatypes.Vertex)[j])
 					}
@@ -139,3 +142,211 @@ func TestRGACRDTS(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// replica 1 removes a while replica 2 inserts b after it, the remove is ordered first
+// and b is still placed where a was instead of at the start of the sequence
+func TestRGARemoveConcurrentInsert(t *testing.T) {
+	root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	c := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 1}), Value: "c", OriginID: "0"}
+	a := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 2}), Value: "a", OriginID: "0"}
+	b := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 2, "2": 1}), Value: "b", OriginID: "2"}
+
+	addC := communication.Operation{Type: "Add", Value: datatypes.RGAOpValue{V: root, Value: "c"}, Version: c.Timestamp.(communication.VClock), OriginID: "0"}
+	addA := communication.Operation{Type: "Add", Value: datatypes.RGAOpValue{V: c, Value: "a"}, Version: a.Timestamp.(communication.VClock), OriginID: "0"}
+	remA := communication.Operation{Type: "Rem", Value: datatypes.RGAOpValue{V: a}, Version: version(map[string]uint64{"0": 2, "1": 1}), OriginID: "1"}
+	addB := communication.Operation{Type: "Add", Value: datatypes.RGAOpValue{V: a, Value: "b"}, Version: b.Timestamp.(communication.VClock), OriginID: "2"}
+
+	engine := crdt.NewEcroCRDT("0", datatypes.NewSequence(root), ecro.RGA{})
+	for _, op := range []communication.Operation{addC, addA, addB, remA} {
+		engine.Effect(op)
+	}
+	if st := (ecro.RGA{}).Query(engine.Unstable_st); !datatypes.RGAEqual(st.([]datatypes.Vertex), []datatypes.Vertex{root, c, b}) {
+		t.Error("Read ", st, ", expected [root c b]")
+	}
+}
+
+// the tombstone of a stable remove is kept while a concurrent insert after it is unstable and dropped once it is stable
+func TestRGATombstones(t *testing.T) {
+	root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+	engine := crdt.NewEcroCRDT("0", datatypes.NewSequence(root), ecro.RGA{Id: "0"})
+
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	a := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 1}), Value: "a", OriginID: "0"}
+	b := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 2}), Value: "b", OriginID: "0"}
+
+	// replica 1 removes a while replica 0 inserts b after it
+	addA := communication.Operation{Type: "Add", Value: datatypes.RGAOpValue{V: root, Value: "a"}, Version: a.Timestamp.(communication.VClock), OriginID: "0"}
+	remA := communication.Operation{Type: "Rem", Value: datatypes.RGAOpValue{V: a}, Version: version(map[string]uint64{"0": 1, "1": 1}), OriginID: "1"}
+	addB := communication.Operation{Type: "Add", Value: datatypes.RGAOpValue{V: a, Value: "b"}, Version: b.Timestamp.(communication.VClock), OriginID: "0"}
+	for _, op := range []communication.Operation{addA, remA, addB} {
+		engine.Effect(op)
+	}
+
+	tombstones := func() int {
+		n := 0
+		for _, v := range engine.Stable_st.(*datatypes.Sequence).Vertices() {
+			if v.Value == nil {
+				n++
+			}
+		}
+		return n
+	}
+
+	engine.Stabilize(addA)
+	engine.Stabilize(remA)
+	if st, _ := engine.Read(replica.Optimistic); !datatypes.RGAEqual(st.([]datatypes.Vertex), []datatypes.Vertex{root, b}) {
+		t.Error("Read ", st, " with the insert unstable, expected [root b]")
+	}
+	if n := tombstones(); n > 1 {
+		t.Error("Stable state has ", n, " tombstones with the insert unstable, expected at most 1")
+	}
+
+	engine.Stabilize(addB)
+	if st, _ := engine.Read(replica.Optimistic); !datatypes.RGAEqual(st.([]datatypes.Vertex), []datatypes.Vertex{root, b}) {
+		t.Error("Read ", st, " with all operations stable, expected [root b]")
+	}
+	if n, l := tombstones(), engine.Stable_st.(*datatypes.Sequence).Len(); n != 0 || l != 2 {
+		t.Error("Stable state has ", l, " vertices and ", n, " tombstones with all operations stable, expected 2 and 0")
+	}
+	if l := engine.Unstable_st.(*datatypes.Sequence).Len(); l != 2 {
+		t.Error("Unstable state has ", l, " vertices with all operations stable, expected 2")
+	}
+}
+
+// a stable insert left after the stable prefix is placed by the tombstones of the inserts it is concurrent with,
+// so they are kept until it is in the stable state
+func TestRGATombstonePlacement(t *testing.T) {
+	root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+	engine := crdt.NewEcroCRDT("0", datatypes.NewSequence(root), ecro.RGA{Id: "0"})
+
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	c := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 1}), Value: "c", OriginID: "0"}
+	r := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 2}), Value: "r", OriginID: "0"}
+	p := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 3}), Value: "p", OriginID: "0"}
+	x := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 4, "2": 1}), Value: "x", OriginID: "2"}
+	u := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 1, "1": 1}), Value: "u", OriginID: "1"}
+
+	add := func(v datatypes.Vertex, after datatypes.Vertex) communication.Operation {
+		return communication.Operation{Type: "Add", Value: datatypes.RGAOpValue{V: after, Value: v.Value}, Version: v.Timestamp.(communication.VClock), OriginID: v.OriginID}
+	}
+	// replica 0 inserts c, r after c and p after r and removes r, replica 2 inserts x after p,
+	// while replica 1 inserts u after c concurrently with r, u has a greater id than r and a smaller one than p
+	remR := communication.Operation{Type: "Rem", Value: datatypes.RGAOpValue{V: r}, Version: version(map[string]uint64{"0": 4}), OriginID: "0"}
+	ops := []communication.Operation{add(c, root), add(r, c), add(p, r), remR, add(x, p), add(u, c)}
+	for _, op := range ops {
+		engine.Effect(op)
+	}
+	expected := []datatypes.Vertex{root, c, u, p, x}
+	if st, _ := engine.Read(replica.Optimistic); !datatypes.RGAEqual(st.([]datatypes.Vertex), expected) {
+		t.Error("Read ", st, ", expected [root c u p x]")
+	}
+
+	// everything but x is stable, the remove is in the stable prefix and u is after x
+	for _, op := range append(ops[:4:4], ops[5]) {
+		engine.Stabilize(op)
+	}
+	if st, _ := engine.Read(replica.Optimistic); !datatypes.RGAEqual(st.([]datatypes.Vertex), expected) {
+		t.Error("Read ", st, " with x unstable, expected [root c u p x]")
+	}
+
+	engine.Stabilize(ops[4])
+	if st, _ := engine.Read(replica.Optimistic); !datatypes.RGAEqual(st.([]datatypes.Vertex), expected) {
+		t.Error("Read ", st, " with all operations stable, expected [root c u p x]")
+	}
+	if l := engine.Stable_st.(*datatypes.Sequence).Len(); l != 5 {
+		t.Error("Stable state has ", l, " vertices with all operations stable, expected 5 without the tombstone")
+	}
+}
+
+// a stable remove keeps the tombstone of a vertex followed by an insert after it, since later inserts are placed by comparing with it,
+// so a replica that stabilizes the remove before an insert that has seen it places the insert like one that stabilizes it after
+func TestCommutativeRGATombstones(t *testing.T) {
+	root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	a := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 1}), Value: "a", OriginID: "0"}
+	b := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 1, "2": 1}), Value: "b", OriginID: "2"}
+	c := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 1, "2": 2}), Value: "c", OriginID: "2"}
+	x := datatypes.Vertex{Timestamp: version(map[string]uint64{"0": 2, "1": 1}), Value: "x", OriginID: "1"}
+
+	// replica 0 inserts a and removes it while replica 2 inserts b and c after a, then replica 1 inserts x at the start
+	addA := communication.Operation{Type: "Add", Value: datatypes.RGAOpValue{V: root, Value: "a"}, Version: a.Timestamp.(communication.VClock), OriginID: "0"}
+	remA := communication.Operation{Type: "Rem", Value: datatypes.RGAOpValue{V: a}, Version: version(map[string]uint64{"0": 2}), OriginID: "0"}
+	addB := communication.Operation{Type: "Add", Value: datatypes.RGAOpValue{V: a, Value: "b"}, Version: b.Timestamp.(communication.VClock), OriginID: "2"}
+	addC := communication.Operation{Type: "Add", Value: datatypes.RGAOpValue{V: a, Value: "c"}, Version: c.Timestamp.(communication.VClock), OriginID: "2"}
+	addX := communication.Operation{Type: "Add", Value: datatypes.RGAOpValue{V: root, Value: "x"}, Version: x.Timestamp.(communication.VClock), OriginID: "1"}
+
+	engines := make([]*crdt.CommutativeStableCRDT, 2)
+	for i := range engines {
+		engines[i] = &crdt.CommutativeStableCRDT{Data: &commutative.RGA{Id: "0"}, Stable_st: datatypes.NewSequence(root)}
+		for _, op := range []communication.Operation{addA, remA, addB, addC} {
+			engines[i].Effect(op)
+		}
+	}
+	engines[0].Stabilize(addA)
+	engines[0].Stabilize(remA)
+	engines[0].Effect(addX)
+	engines[1].Effect(addX)
+	engines[1].Stabilize(addA)
+	engines[1].Stabilize(addB)
+	engines[1].Stabilize(addC)
+	engines[1].Stabilize(remA)
+
+	for i, engine := range engines {
+		if st, _ := engine.Read(replica.Optimistic); !datatypes.RGAEqual(st.([]datatypes.Vertex), []datatypes.Vertex{root, x, c, b}) {
+			t.Error("Replica ", i, " reads ", st, ", expected [root x c b]")
+		}
+	}
+
+	// the tombstone is dropped once the inserts after it are stable, whether they are stabilized before or after the remove
+	engines[0].Stabilize(addB)
+	engines[0].Stabilize(addC)
+	for i, engine := range engines {
+		engine.Stabilize(addX)
+		if l := engine.Stable_st.(*datatypes.Sequence).Len(); l != 4 {
+			t.Error("Replica ", i, " keeps ", l, " vertices with all operations stable, expected 4")
+		}
+	}
+}
+
+// inserts whose vertices before them are missing, as after a remove of the map that holds the RGA, are all placed at the root,
+// they are ordered by their ids in every delivery order, also when the ids of the replicas are not numbers
+func TestRGAOrphanInserts(t *testing.T) {
+	root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "a"}
+	newEngine := func() replica.CrdtI {
+		return crdt.NewEcroCRDT("a", datatypes.NewSequence(root), ecro.RGA{Id: "a"})
+	}
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	p := datatypes.Vertex{Timestamp: version(map[string]uint64{"a": 1}), Value: "p", OriginID: "a"}
+	q := datatypes.Vertex{Timestamp: version(map[string]uint64{"b": 1}), Value: "q", OriginID: "b"}
+	x := datatypes.Vertex{Timestamp: version(map[string]uint64{"a": 2}), Value: "x", OriginID: "a"}
+	y := datatypes.Vertex{Timestamp: version(map[string]uint64{"b": 2}), Value: "y", OriginID: "b"}
+	ops := []communication.Operation{
+		{Type: "Add", Value: datatypes.RGAOpValue{V: p, Value: "x"}, Version: x.Timestamp.(communication.VClock), OriginID: "a"},
+		{Type: "Add", Value: datatypes.RGAOpValue{V: q, Value: "y"}, Version: y.Timestamp.(communication.VClock), OriginID: "b"},
+	}
+	equal := func(q1 any, q2 any) bool {
+		return datatypes.RGAEqual(q1.([]datatypes.Vertex), q2.([]datatypes.Vertex))
+	}
+	if err := crdtcheck.CheckInterleavings(newEngine, ops, equal, true); err != nil {
+		t.Error(err)
+	}
+
+	engine := newEngine()
+	for _, op := range ops {
+		engine.Effect(op)
+	}
+	if st, _ := engine.Read(replica.Optimistic); !equal(st, []datatypes.Vertex{root, y, x}) {
+		t.Error("Read ", st, ", expected [root y x]")
+	}
+}
@@ -1,7 +1,7 @@
 package test
 
 import (
-	"library/packages/communication"
+	"library/packages/crdt"
 	datatypes "library/packages/datatypes/semidirect"
 	"library/packages/replica"
 	"log"
@@ -96,12 +96,12 @@ func TestRGASEMI(t *testing.T) {
 
 		//Check that all replicas have the same state
 		for i := 1; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
-			stt, _ := replicas[0].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
 			if !datatypes.RGAEqual(st.([]datatypes.Vertex), stt.([]datatypes.Vertex)) {
 				for i := 0; i < numReplicas; i++ {
 					t.Error("Replica ", i)
-					q, _ := replicas[i].Crdt.Query()
+					q, _ := replicas[i].Crdt.Read(replica.Optimistic)
 					for j := 0; j < len(q.([]datatypes.Vertex)); j++ {
 						log.Println(q.([]datatypes.Vertex)[j])
 					}
@@ -138,11 +138,12 @@ func TestRGASEMI(t *testing.T) {
 }
 
 func generateRandomVertexSEMI(r replica.Replica) datatypes.Vertex {
-	rgaState, rgaDeletedState := r.Crdt.Query()
+	rgaState, _ := r.Crdt.Read(replica.Optimistic)
+	rgaDeletedState := r.Crdt.(*crdt.Semidirect2CRDT).NonMainOperations()
 
 	v := datatypes.Vertex{}
-	if len(rgaDeletedState.([]communication.Operation)) != 0 {
-		v = rgaDeletedState.([]communication.Operation)[rand.Intn(len(rgaDeletedState.([]communication.Operation)))].Value.(datatypes.RGAOpValue).V
+	if len(rgaDeletedState) != 0 {
+		v = rgaDeletedState[rand.Intn(len(rgaDeletedState))].Value.(datatypes.RGAOpValue).V
 	} else {
 		v = rgaState.([]datatypes.Vertex)[rand.Intn(len(rgaState.([]datatypes.Vertex)))]
 	}
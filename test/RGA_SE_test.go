@@ -91,12 +91,12 @@ import (
 
 // 		//Check that all replicas have the same state
 // 		for i := 1; i < numReplicas; i++ {
-// 			st, _ := replicas[i].Crdt.Query()
-// 			stt, _ := replicas[0].Crdt.Query()
+// 			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+// 			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
 // 			if !datatypes.RGAEqual(st.([]datatypes.Vertex), stt.([]datatypes.Vertex)) {
 // 				for i := 0; i < numReplicas; i++ {
 // 					t.Error("Replica ", i)
-// 					q, _ := replicas[i].Crdt.Query()
+// 					q, _ := replicas[i].Crdt.Read(replica.Optimistic)
 // 					for j := 0; j < len(q.([]datatypes.Vertex)); j++ {
 // 						log.Println(q.([]datatypes.Vertex)[j])
 // 					}
@@ -127,7 +127,7 @@ import (
 // 			}
 
 // 			//check that the state is the same as the state of the replicas
-// 			st, _ := replicas[0].Crdt.Query()
+// 			st, _ := replicas[0].Crdt.Read(replica.Optimistic)
 // 			if datatypes.RGAEqual(state, st.([]datatypes.Vertex)) {
 // 				log.Println("State has a sequential execution")
 // 				return true
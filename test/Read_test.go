@@ -0,0 +1,152 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	commutative "library/packages/datatypes/commutative"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/datatypes/persistent"
+	semidirect "library/packages/datatypes/semidirect"
+	"library/packages/replica"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// the stable read of an engine with a causally closed prefix of the operations stable is the optimistic read
+// of an engine that only received that prefix, and the versions of the reads are the merged versions of the operations
+// if lags is set the stable read can have only the stable operations dominated by its version until all operations are stable,
+// like engines without StableReads whose state mixes stable and unstable operations
+func checkReads(t *testing.T, newEngine func() replica.CrdtI, state any, apply func(state any, operations []communication.Operation) any,
+	newOp func(state any, choice int) (string, any), equal func(q1 any, q2 any) bool, lags bool) {
+
+	// Define property to test
+	property := func(numOps int, numReplicas int, seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		ops := genCausalOperations(r, numOps, numReplicas, state, apply, newOp)
+		numStable := r.Intn(numOps + 1)
+
+		engine := newEngine()
+		for _, op := range ops {
+			engine.Effect(op)
+		}
+		for _, op := range ops[:numStable] {
+			engine.Stabilize(op)
+		}
+
+		delivered, stable := communication.NewVClock(), communication.NewVClock()
+		for i, op := range ops {
+			delivered.Merge(op.Version)
+			if i < numStable {
+				stable.Merge(op.Version)
+			}
+		}
+
+		st, version := engine.Read(replica.Stable)
+		if lags && numStable < numOps && stable.Descends(version) {
+			stable = version
+		}
+		prefix := newEngine()
+		for _, op := range ops {
+			if stable.Descends(op.Version) {
+				prefix.Effect(op)
+			}
+		}
+		expected, _ := prefix.Read(replica.Optimistic)
+		if !equal(st, expected) || !version.Equal(stable) {
+			t.Error("Stable read ", st, " with version ", version.ReturnVCString(), " of ", ops, " with ", numStable, " stable operations, expected ", expected, " with version ", stable.ReturnVCString())
+			return false
+		}
+
+		st, version = engine.Read(replica.Optimistic)
+		expected, _ = newEngineWith(newEngine, ops).Read(replica.Optimistic)
+		if !equal(st, expected) || !version.Equal(delivered) {
+			t.Error("Optimistic read ", st, " with version ", version.ReturnVCString(), " of ", ops, ", expected ", expected, " with version ", delivered.ReturnVCString())
+			return false
+		}
+		return true
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		vals[0] = reflect.ValueOf(1 + rand.Intn(20))
+		vals[1] = reflect.ValueOf(2 + rand.Intn(3))
+		vals[2] = reflect.ValueOf(rand.Int63())
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 50,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+// returns a new engine with the operations delivered
+func newEngineWith(newEngine func() replica.CrdtI, ops []communication.Operation) replica.CrdtI {
+	engine := newEngine()
+	for _, op := range ops {
+		engine.Effect(op)
+	}
+	return engine
+}
+
+func TestReadEcro(t *testing.T) {
+	newEngine := func() replica.CrdtI {
+		return crdt.NewEcroCRDT("0", persistent.NewSet[any](), ecro.AddWins{})
+	}
+	checkReads(t, newEngine, persistent.NewSet[any](), ecro.AddWins{}.Apply, addWinsGenerator.Operation, setEqual, true)
+}
+
+func TestReadSemidirect(t *testing.T) {
+	for _, options := range []crdt.ReadOption{0, crdt.StableReads} {
+		newEngine := func() replica.CrdtI {
+			return &crdt.SemidirectCRDT{Id: "0", Data: semidirect.AddWins{}, Unstable_operations: []communication.Operation{}, Unstable_st: persistent.NewSet[any](), Options: options}
+		}
+		checkReads(t, newEngine, persistent.NewSet[any](), semidirect.AddWins{}.Apply, addWinsGenerator.Operation, setEqual, options&crdt.StableReads == 0)
+	}
+}
+
+func TestReadCommutative(t *testing.T) {
+	newOp := func(state any, choice int) (string, any) {
+		return "Add", choice % 10
+	}
+	for _, options := range []crdt.ReadOption{0, crdt.StableReads} {
+		newEngine := func() replica.CrdtI {
+			return &crdt.CommutativeCRDT{Data: commutative.Counter{}, Stable_st: 0, Options: options}
+		}
+		checkReads(t, newEngine, 0, commutative.Counter{}.Apply, newOp, reflect.DeepEqual, options&crdt.StableReads == 0)
+	}
+}
+
+// without StableReads the stable read is the last state whose operations were all stable
+func TestReadSettled(t *testing.T) {
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	engine := &crdt.CommutativeCRDT{Data: commutative.Counter{}, Stable_st: 0}
+	a := communication.Operation{Type: "Add", Value: 1, Version: version(map[string]uint64{"0": 1}), OriginID: "0"}
+	b := communication.Operation{Type: "Add", Value: 10, Version: version(map[string]uint64{"0": 1, "1": 1}), OriginID: "1"}
+	c := communication.Operation{Type: "Add", Value: 100, Version: version(map[string]uint64{"0": 2}), OriginID: "0"}
+
+	engine.Effect(a)
+	engine.Stabilize(a)
+	engine.Effect(b)
+	engine.Effect(c)
+	engine.Stabilize(b)
+	if st, v := engine.Read(replica.Stable); st != 1 || !v.Equal(a.Version) {
+		t.Error("Stable read is ", st, " with version ", v.ReturnVCString(), ", expected 1 with version ", a.Version.ReturnVCString())
+	}
+
+	engine.Stabilize(c)
+	if st, v := engine.Read(replica.Stable); st != 111 || !v.Equal(version(map[string]uint64{"0": 2, "1": 1})) {
+		t.Error("Stable read is ", st, " with version ", v.ReturnVCString(), ", expected 111 with version [0:2 1:1]")
+	}
+}
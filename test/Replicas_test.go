@@ -0,0 +1,115 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/replica"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// runs connected replicas that prepare random operations concurrently, collects the trace of the operations
+// and calls check once every replica received all of them
+func checkReplicas(t *testing.T, newReplica func(id string, channels map[string]chan any, delay int) *replica.Replica,
+	prepare func(r *replica.Replica, choice int) communication.Operation, check func(replicas []*replica.Replica, trace []communication.Operation) bool) {
+
+	// Define property to test
+	property := func(operations int, numReplicas int, delay int) bool {
+
+		// Initialize channels
+		channels := map[string]chan interface{}{}
+		for i := 0; i < numReplicas; i++ {
+			channels[strconv.Itoa(i)] = make(chan interface{})
+		}
+
+		// Initialize replicas, the delay simulator delivers remote operations in random order
+		replicas := make([]*replica.Replica, numReplicas)
+		for i := 0; i < numReplicas; i++ {
+			replicas[i] = newReplica(strconv.Itoa(i), channels, delay)
+		}
+
+		// Start a goroutine for each replica
+		var wg sync.WaitGroup
+		var lock sync.Mutex
+		trace := []communication.Operation{}
+		for i := range replicas {
+			wg.Add(1)
+			go func(r *replica.Replica) {
+				defer wg.Done()
+				for j := 0; j < operations; j++ {
+					op := prepare(r, rand.Intn(1000))
+					lock.Lock()
+					trace = append(trace, op)
+					lock.Unlock()
+				}
+			}(replicas[i])
+		}
+
+		// Wait for all goroutines to finish
+		wg.Wait()
+
+		// Wait for all replicas to receive all messages
+		for {
+			flag := 0
+			for i := 0; i < numReplicas; i++ {
+				if replicas[i].Crdt.NumOps() == uint64(numReplicas*operations) {
+					flag += 1
+				}
+			}
+			if flag == numReplicas {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		return check(replicas, trace)
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		operations, numReplicas := 10+rand.Intn(30), 2+rand.Intn(2)
+		vals[0] = reflect.ValueOf(operations)
+		vals[1] = reflect.ValueOf(numReplicas)
+		//half of the runs hold back all the remote operations of a replica, the delay simulator delivers them in random order
+		vals[2] = reflect.ValueOf(rand.Intn(2) * (numReplicas - 1) * operations)
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 10,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+// prepares operations chosen from the optimistic read of the replica
+func prepareFromRead(newOp func(read any, choice int) (string, any)) func(r *replica.Replica, choice int) communication.Operation {
+	return func(r *replica.Replica, choice int) communication.Operation {
+		st, _ := r.Crdt.Read(replica.Optimistic)
+		return r.Prepare(newOp(st, choice))
+	}
+}
+
+// checks that every replica reads the same as replica 0
+func sameReads(t *testing.T, equal func(q1 any, q2 any) bool) func(replicas []*replica.Replica, trace []communication.Operation) bool {
+	return func(replicas []*replica.Replica, trace []communication.Operation) bool {
+		stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
+		for i := 1; i < len(replicas); i++ {
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			if !equal(st, stt) {
+				t.Error("Replica ", i, ": ", st, " differs from replica 0: ", stt)
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/datatypes"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// vertex created by the n-th operation of replica id
+func newTestVertex(id string, n int) datatypes.Vertex {
+	return datatypes.Vertex{
+		Timestamp: communication.NewVClockFromMap(map[string]uint64{id: uint64(n)}),
+		Value:     strconv.Itoa(n),
+		OriginID:  id,
+	}
+}
+
+func TestSequence(t *testing.T) {
+
+	// Define property to test
+	property := func(positions []int, removes []bool) bool {
+		root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+		seq := datatypes.NewSequence(root)
+		model := []datatypes.Vertex{root}
+
+		for n, pos := range positions {
+			if removes[n] && len(model) > 1 {
+				i := 1 + pos%(len(model)-1)
+				seq.Remove(i)
+				model = append(model[:i], model[i+1:]...)
+			} else {
+				i := 1 + pos%len(model)
+				v := newTestVertex(strconv.Itoa(n%3), n+1)
+				seq.Insert(i, v)
+				model = append(model[:i], append([]datatypes.Vertex{v}, model[i:]...)...)
+			}
+
+			//compare sequence with the slice model
+			if seq.Len() != len(model) || !datatypes.RGAEqual(seq.Vertices(), model) {
+				t.Error("Sequence ", seq.Vertices(), " expected ", model)
+				return false
+			}
+			for i, v := range model {
+				if seq.IndexOf(v) != i || !datatypes.RGAEqual([]datatypes.Vertex{seq.At(i)}, []datatypes.Vertex{v}) {
+					t.Error("Vertex ", v, " expected at ", i, " found at ", seq.IndexOf(v))
+					return false
+				}
+			}
+		}
+
+		//copies do not share changes
+		cpy := seq.Copy()
+		cpy.Insert(1, newTestVertex("0", len(positions)+1))
+		return datatypes.RGAEqual(seq.Vertices(), model) && cpy.Len() == len(model)+1
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		numOperations := 200
+		positions := make([]int, numOperations)
+		removes := make([]bool, numOperations)
+
+		for i := 0; i < numOperations; i++ {
+			positions[i] = rand.Intn(numOperations)
+			removes[i] = rand.Intn(3) == 0
+		}
+
+		vals[0] = reflect.ValueOf(positions)
+		vals[1] = reflect.ValueOf(removes)
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 50,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+// insert after a random vertex and find its position in sequences of increasing size
+func BenchmarkSequence(b *testing.B) {
+	for _, numVertices := range []int{1000, 10000, 100000} {
+		b.Run("vertices="+strconv.Itoa(numVertices), func(b *testing.B) {
+			seq := datatypes.NewSequence(datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"})
+			vertices := make([]datatypes.Vertex, numVertices)
+			for i := 0; i < numVertices; i++ {
+				vertices[i] = newTestVertex("0", i+1)
+				seq.Insert(seq.Len(), vertices[i])
+			}
+			r := rand.New(rand.NewSource(1))
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				v := newTestVertex("1", n+1)
+				seq.Insert(seq.IndexOf(vertices[r.Intn(numVertices)])+1, v)
+				seq.Remove(seq.IndexOf(v))
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/crdtcheck"
+	"library/packages/datatypes"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/datatypes/persistent"
+	semidirect "library/packages/datatypes/semidirect"
+	"library/packages/replica"
+	"testing"
+)
+
+// set engines of both biases, the semidirect engine has no stable reads to compare
+var setEngines = []struct {
+	name      string
+	newEngine func(bias datatypes.Bias) replica.CrdtI
+	stabilize bool
+}{
+	{"ECRO", func(bias datatypes.Bias) replica.CrdtI {
+		return crdt.NewEcroCRDT("0", persistent.NewSet[any](), ecro.NewSet(bias))
+	}, true},
+	{"Semidirect", func(bias datatypes.Bias) replica.CrdtI {
+		return &crdt.SemidirectCRDT{Id: "0", Data: semidirect.NewSet("0", bias), Unstable_operations: []communication.Operation{}, Unstable_st: persistent.NewSet[any]()}
+	}, false},
+}
+
+// concurrent adds and removes give the elements of their bias in every delivery order
+func TestSetBias(t *testing.T) {
+	op := func(opType string, value any, originID string, version map[string]uint64) communication.Operation {
+		return communication.Operation{Type: opType, Value: value, Version: communication.NewVClockFromMap(version), OriginID: originID}
+	}
+	scenarios := []struct {
+		name     string
+		ops      []communication.Operation
+		expected map[datatypes.Bias][]any
+	}{
+		{"remove concurrent with an add", []communication.Operation{
+			op("Add", "x", "0", map[string]uint64{"0": 1}),
+			op("Add", "x", "0", map[string]uint64{"0": 2}),
+			op("Rem", "x", "1", map[string]uint64{"0": 1, "1": 1}),
+		}, map[datatypes.Bias][]any{datatypes.AddBias: {"x"}, datatypes.RemoveBias: {}}},
+		{"remove seeing one of two concurrent adds", []communication.Operation{
+			op("Add", "x", "0", map[string]uint64{"0": 1}),
+			op("Add", "x", "1", map[string]uint64{"1": 1}),
+			op("Rem", "x", "2", map[string]uint64{"0": 1, "2": 1}),
+		}, map[datatypes.Bias][]any{datatypes.AddBias: {"x"}, datatypes.RemoveBias: {}}},
+		{"add after the remove", []communication.Operation{
+			op("Add", "x", "0", map[string]uint64{"0": 1}),
+			op("Rem", "x", "1", map[string]uint64{"0": 1, "1": 1}),
+			op("Add", "x", "0", map[string]uint64{"0": 2, "1": 1}),
+		}, map[datatypes.Bias][]any{datatypes.AddBias: {"x"}, datatypes.RemoveBias: {"x"}}},
+		{"remove concurrent with an add of another element", []communication.Operation{
+			op("Add", "x", "0", map[string]uint64{"0": 1}),
+			op("Rem", "x", "1", map[string]uint64{"0": 1, "1": 1}),
+			op("Add", "y", "0", map[string]uint64{"0": 2}),
+		}, map[datatypes.Bias][]any{datatypes.AddBias: {"y"}, datatypes.RemoveBias: {"y"}}},
+	}
+
+	for _, engine := range setEngines {
+		for _, bias := range []datatypes.Bias{datatypes.AddBias, datatypes.RemoveBias} {
+			newEngine := func() replica.CrdtI {
+				return engine.newEngine(bias)
+			}
+			for _, s := range scenarios {
+				if err := crdtcheck.CheckInterleavings(newEngine, s.ops, setEqual, engine.stabilize); err != nil {
+					t.Error(engine.name, " set with bias ", bias, ", ", s.name, ": ", err)
+					continue
+				}
+
+				e := newEngine()
+				for _, op := range s.ops {
+					e.Effect(op)
+				}
+				expected := persistent.NewSet[any]()
+				for _, v := range s.expected[bias] {
+					expected.Add(v)
+				}
+				if st, _ := e.Read(replica.Optimistic); !setEqual(st, expected) {
+					t.Error(engine.name, " set with bias ", bias, ", ", s.name, ": ", st.(*persistent.Set[any]).ToSlice(), ", expected ", s.expected[bias])
+				}
+			}
+		}
+	}
+}
+
+func TestRemoveWins(t *testing.T) {
+	prepare := func(r *replica.Replica, choice int) communication.Operation {
+		return r.Prepare(addWinsGenerator.Operation(nil, choice))
+	}
+	checkReplicas(t, ecro.NewRemoveWinsReplica, prepare, sameReads(t, setEqual))
+	checkReplicas(t, semidirect.NewRemoveWinsReplica, prepare, sameReads(t, setEqual))
+}
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"library/packages/crdt"
 	"library/packages/datatypes/ecro/custom"
 	"library/packages/replica"
 	"math/rand"
@@ -29,6 +30,11 @@ func TestSocialECRO(t *testing.T) {
 			replicas[i] = custom.NewSocialReplica(strconv.Itoa(i), channels, numOperations-operations[i])
 		}
 
+		// Check the invariants on every replica
+		for i := 0; i < numReplicas; i++ {
+			replicas[i].Crdt.(*crdt.EcroCRDT).Debug = crdt.NewDebug()
+		}
+
 		// Start a goroutine for each replica
 		var wg sync.WaitGroup
 		for i := range replicas {
@@ -47,7 +53,7 @@ func TestSocialECRO(t *testing.T) {
 						r.Prepare(OPType, OPValue)
 					case 1:
 						OPType = "breakup"
-						q, _ := r.Crdt.Query()
+						q, _ := r.Crdt.Read(replica.Optimistic)
 
 						//choose a random USER and a random friend of that user
 						user := rand.Intn(len(q.(custom.SocialState).Friends))
@@ -68,7 +74,7 @@ func TestSocialECRO(t *testing.T) {
 						r.Prepare(OPType, OPValue)
 					case 3:
 						OPType = "reject"
-						q, _ := r.Crdt.Query()
+						q, _ := r.Crdt.Read(replica.Optimistic)
 
 						//choose a random USER and a random request of that user
 						user := rand.Intn(len(q.(custom.SocialState).Requesters))
@@ -107,20 +113,28 @@ func TestSocialECRO(t *testing.T) {
 			}
 		}
 
+		//Check that no replica broke the invariants
+		for i := 0; i < numReplicas; i++ {
+			if violations := replicas[i].Crdt.(*crdt.EcroCRDT).Debug.Violations(); len(violations) > 0 {
+				t.Error("Replica ", i, " broke the invariants: ", violations)
+				return false
+			}
+		}
+
 		//Check that all replicas have the same state
 		for i := 1; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
-			stt, _ := replicas[0].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
 			if !custom.CompareSocialStates(st.(custom.SocialState), stt.(custom.SocialState)) {
 				for i := 0; i < numReplicas; i++ {
-					st, _ := replicas[i].Crdt.Query()
+					st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 					t.Error("Replica ", i, ": ", st)
 				}
 				return false
 			}
 		}
 		for i := 0; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 			t.Log("Replica ", i, ": ", st)
 		}
 
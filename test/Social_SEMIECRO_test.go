@@ -1,7 +1,10 @@
 package test
 
 import (
+	"library/packages/communication"
+	"library/packages/crdt"
 	datatypes "library/packages/datatypes/crdtECRO"
+	"library/packages/datatypes/persistent"
 	"library/packages/replica"
 	"math/rand"
 	"reflect"
@@ -47,7 +50,7 @@ func TestSocialSEMIECRO(t *testing.T) {
 						r.Prepare(OPType, OPValue)
 					case 1:
 						OPType = "breakup"
-						q, _ := r.Crdt.Query()
+						q, _ := r.Crdt.Read(replica.Optimistic)
 
 						//choose a random USER and a random friend of that user
 						user := rand.Intn(len(q.(datatypes.SocialState).Friends))
@@ -68,7 +71,7 @@ func TestSocialSEMIECRO(t *testing.T) {
 						r.Prepare(OPType, OPValue)
 					case 3:
 						OPType = "reject"
-						q, _ := r.Crdt.Query()
+						q, _ := r.Crdt.Read(replica.Optimistic)
 
 						//choose a random USER and a random request of that user
 						user := rand.Intn(len(q.(datatypes.SocialState).Requesters))
@@ -109,18 +112,18 @@ func TestSocialSEMIECRO(t *testing.T) {
 
 		//Check that all replicas have the same state
 		for i := 1; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
-			stt, _ := replicas[0].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
 			if !datatypes.CompareSocialStates(st.(datatypes.SocialState), stt.(datatypes.SocialState)) {
 				for i := 0; i < numReplicas; i++ {
-					st, _ := replicas[i].Crdt.Query()
+					st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 					t.Error("Replica ", i, ": ", st)
 				}
 				return false
 			}
 		}
 		for i := 0; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 			t.Log("Replica ", i, ": ", st)
 		}
 
@@ -151,3 +154,58 @@ func TestSocialSEMIECRO(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// a reject removes the request it has seen whether the request is stable or not when the reject is delivered
+func TestSocialSEMIECROStabilization(t *testing.T) {
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	newState := func() datatypes.SocialState {
+		return datatypes.SocialState{
+			Friends:    [5]*persistent.Set[any]{persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any]()},
+			Requesters: [5]*persistent.Set[any]{persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any](), persistent.NewSet[any]()},
+		}
+	}
+
+	// replica 2 rejects the request of replica 1, or replica 0 accepts it and breaks up after a second request
+	request := communication.Operation{Type: "request", Value: datatypes.SocialOpValue{From: 1, To: 2}, Version: version(map[string]uint64{"1": 1}), OriginID: "1"}
+	reject := communication.Operation{Type: "reject", Value: datatypes.SocialOpValue{From: 1, To: 2}, Version: version(map[string]uint64{"1": 1, "2": 1}), OriginID: "2"}
+	accept := communication.Operation{Type: "accept", Value: datatypes.SocialOpValue{From: 2, To: 1}, Version: version(map[string]uint64{"0": 1, "1": 1}), OriginID: "0"}
+	again := communication.Operation{Type: "request", Value: datatypes.SocialOpValue{From: 1, To: 2}, Version: version(map[string]uint64{"0": 1, "1": 2}), OriginID: "1"}
+	breakup := communication.Operation{Type: "breakup", Value: datatypes.SocialOpValue{From: 1, To: 2}, Version: version(map[string]uint64{"0": 2, "1": 2}), OriginID: "0"}
+
+	cases := []struct {
+		ops    []communication.Operation
+		stable []bool // stabilize the operation before the next one is delivered
+	}{
+		{[]communication.Operation{request, reject}, []bool{true, true}},
+		{[]communication.Operation{request, reject}, []bool{false, false}},
+		{[]communication.Operation{request, accept, again, breakup}, []bool{true, true, true, true}},
+		{[]communication.Operation{request, accept, again, breakup}, []bool{false, true, false, false}},
+	}
+	for _, c := range cases {
+		r := crdt.NewSemidirectECRO("0", newState(), datatypes.Social{})
+		unstable := []communication.Operation{}
+		for i, op := range c.ops {
+			r.Effect(op)
+			unstable = append(unstable, op)
+			if c.stable[i] {
+				for _, o := range unstable {
+					r.Stabilize(o)
+				}
+				unstable = nil
+			}
+		}
+		st, _ := r.Read(replica.Optimistic)
+		for _, o := range unstable {
+			r.Stabilize(o)
+		}
+		stable, _ := r.Read(replica.Optimistic)
+
+		for _, st := range []any{st, stable} {
+			if !datatypes.CompareSocialStates(st.(datatypes.SocialState), newState()) {
+				t.Error("Delivering ", c.ops, " stabilizing ", c.stable, " gives ", st, ", expected no friends and no requests")
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/middleware"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+type rowUpdate struct {
+	Row   int
+	Ticks []uint64
+}
+
+// minimum of each column of the observed matrix
+func naiveStableVersion(observed map[string]communication.VClock, ids []string) map[string]uint64 {
+	min := map[string]uint64{}
+	for _, column := range ids {
+		first := true
+		for _, vc := range observed {
+			if first || vc.FindTicks(column) < min[column] {
+				min[column] = vc.FindTicks(column)
+				first = false
+			}
+		}
+	}
+	return min
+}
+
+func TestStableVersion(t *testing.T) {
+
+	// Define property to test
+	property := func(updates []rowUpdate, numReplicas int) bool {
+		ids := make([]string, numReplicas)
+		for i := 0; i < numReplicas; i++ {
+			ids[i] = strconv.Itoa(i)
+		}
+
+		observed := middleware.InitVClocks(ids)
+
+		for _, u := range updates {
+			vc := communication.NewVClock()
+			for i, ticks := range u.Ticks {
+				vc.Set(ids[i], ticks)
+			}
+			observed.SetVClock(ids[u.Row], vc)
+
+			//compare incremental stable version with the minimum of each column
+			stable := observed.MinVClock()
+			expected := naiveStableVersion(observed.GetMap(), ids)
+			if !stable.Equal(communication.NewVClockFromMap(expected)) {
+				t.Error("Stable version ", stable.ReturnVCString(), " expected ", communication.NewVClockFromMap(expected).ReturnVCString())
+				return false
+			}
+		}
+		return true
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		numReplicas := 2 + rand.Intn(10)
+		updates := make([]rowUpdate, 50)
+
+		for i := range updates {
+			//rows are set to arbitrary versions, not only to greater ones
+			updates[i] = rowUpdate{Row: rand.Intn(numReplicas), Ticks: make([]uint64, numReplicas)}
+			for j := 0; j < numReplicas; j++ {
+				updates[i].Ticks[j] = uint64(rand.Intn(10))
+			}
+		}
+
+		vals[0] = reflect.ValueOf(updates)
+		vals[1] = reflect.ValueOf(numReplicas)
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 200,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+// observed matrix of a large group where every row is increased as messages are delivered
+func BenchmarkStableVersion(b *testing.B) {
+	for _, numReplicas := range []int{10, 100, 200} {
+		b.Run("replicas="+strconv.Itoa(numReplicas), func(b *testing.B) {
+			ids := make([]string, numReplicas)
+			rows := make([]communication.VClock, numReplicas)
+			for i := 0; i < numReplicas; i++ {
+				ids[i] = strconv.Itoa(i)
+			}
+			for i := 0; i < numReplicas; i++ {
+				rows[i] = communication.InitVClock(ids)
+			}
+
+			observed := middleware.InitVClocks(ids)
+			r := rand.New(rand.NewSource(1))
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				row := r.Intn(numReplicas)
+				rows[row].Tick(ids[row])
+				rows[row].Tick(ids[r.Intn(numReplicas)])
+				observed.SetVClock(ids[row], rows[row].Copy())
+				observed.MinVClock()
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes"
+	commutative "library/packages/datatypes/commutative"
+	crdtECRO "library/packages/datatypes/crdtECRO"
+	ecro "library/packages/datatypes/ecro"
+	semidirect "library/packages/datatypes/semidirect"
+	"library/packages/datatypes/text"
+	"library/packages/replica"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// replays the events of a text on a string
+type textObserver struct {
+	lock *sync.Mutex
+	text []rune
+}
+
+func (o *textObserver) observe(e text.Event) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	switch e.Type {
+	case text.Insert:
+		o.text = append(o.text[:e.Index], append([]rune(e.Text), o.text[e.Index:]...)...)
+	case text.Delete:
+		o.text = append(o.text[:e.Index], o.text[e.Index+len([]rune(e.Text)):]...)
+	}
+}
+
+func (o *textObserver) String() string {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	return string(o.text)
+}
+
+// replicas edit a text by positions, all replicas end with the same text and their observers replay it
+func checkText(t *testing.T, newEngine func(id string, root datatypes.Vertex) text.Engine) {
+
+	// Define property to test
+	property := func(operations int, numReplicas int) bool {
+
+		// Initialize channels
+		channels := map[string]chan interface{}{}
+		for i := 0; i < numReplicas; i++ {
+			channels[strconv.Itoa(i)] = make(chan interface{})
+		}
+
+		// Initialize replicas with an observer each
+		replicas := make([]*replica.Replica, numReplicas)
+		texts := make([]*text.Text, numReplicas)
+		observers := make([]*textObserver, numReplicas)
+		for i := 0; i < numReplicas; i++ {
+			id := strconv.Itoa(i)
+			root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: id}
+			texts[i] = text.NewText(newEngine(id, root))
+			observers[i] = &textObserver{lock: new(sync.Mutex)}
+			texts[i].Observe(observers[i].observe)
+			replicas[i] = text.NewTextReplica(id, texts[i], channels, 0)
+		}
+
+		// Start a goroutine for each replica
+		var numOps int64
+		var wg sync.WaitGroup
+		for i := range replicas {
+			wg.Add(1)
+			go func(txt *text.Text) {
+				defer wg.Done()
+				for j := 0; j < operations; j++ {
+					//remote deletes can shorten the text before the edit, it is then out of range and skipped
+					if n := txt.Len(); n > 0 && rand.Intn(3) == 0 {
+						index := rand.Intn(n)
+						length := 1 + rand.Intn(n-index)
+						if txt.DeleteRange(index, length) == nil {
+							atomic.AddInt64(&numOps, int64(length))
+						}
+					} else {
+						s := string(letters[rand.Intn(len(letters))]) + string(letters[rand.Intn(len(letters))])
+						if txt.InsertAt(rand.Intn(n+1), s) == nil {
+							atomic.AddInt64(&numOps, int64(len(s)))
+						}
+					}
+				}
+			}(texts[i])
+		}
+
+		// Wait for all goroutines to finish
+		wg.Wait()
+
+		// Wait for all replicas to receive all messages
+		for {
+			flag := 0
+			for i := 0; i < numReplicas; i++ {
+				if replicas[i].Crdt.NumOps() == uint64(atomic.LoadInt64(&numOps)) {
+					flag += 1
+				}
+			}
+			if flag == numReplicas {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		//Check that all replicas have the same text and that their observers replayed it
+		for i := 0; i < numReplicas; i++ {
+			if texts[i].String() != texts[0].String() || observers[i].String() != texts[i].String() {
+				t.Error("Replica ", i, " has text ", texts[i], " and observed ", observers[i], ", replica 0 has text ", texts[0])
+				return false
+			}
+		}
+		return true
+	}
+
+	// Define generator to limit input size
+	gen := func(vals []reflect.Value, rand *rand.Rand) {
+		vals[0] = reflect.ValueOf(5 + rand.Intn(15))
+		vals[1] = reflect.ValueOf(2 + rand.Intn(2))
+	}
+
+	// Define config for quick.Check
+	config := &quick.Config{
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCount: 10,
+		Values:   gen,
+	}
+
+	// Generate and test random inputs
+	if err := quick.Check(property, config); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTextECRO(t *testing.T) {
+	checkText(t, func(id string, root datatypes.Vertex) text.Engine {
+		return crdt.NewEcroCRDT(id, datatypes.NewSequence(root), ecro.RGA{Id: id})
+	})
+}
+
+func TestTextSemidirectECRO(t *testing.T) {
+	checkText(t, func(id string, root datatypes.Vertex) text.Engine {
+		return crdt.NewSemidirectECRO(id, datatypes.NewSequence(root), &crdtECRO.RGA{Id: id})
+	})
+}
+
+func TestTextSemidirect(t *testing.T) {
+	checkText(t, func(id string, root datatypes.Vertex) text.Engine {
+		return crdt.NewSemidirect2CRDT(id, datatypes.NewSequence(root), semidirect.RGA{Id: id})
+	})
+}
+
+func TestTextCommutative(t *testing.T) {
+	checkText(t, func(id string, root datatypes.Vertex) text.Engine {
+		return &crdt.CommutativeStableCRDT{Data: &commutative.RGA{Id: id}, Stable_st: datatypes.NewSequence(root)}
+	})
+}
+
+// positions are mapped to the vertices of the characters
+func TestTextPositions(t *testing.T) {
+	channels := map[string]chan interface{}{"0": make(chan interface{})}
+	root := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+	txt := text.NewText(crdt.NewEcroCRDT("0", datatypes.NewSequence(root), ecro.RGA{Id: "0"}))
+	events := []text.Event{}
+	txt.Observe(func(e text.Event) { events = append(events, e) })
+	text.NewTextReplica("0", txt, channels, 0)
+
+	txt.InsertAt(0, "hxello")
+	txt.DeleteRange(1, 1)
+	txt.InsertAt(5, " world")
+	txt.InsertAt(0, "¡")
+	if txt.String() != "¡hello world" {
+		t.Error("Text is ", txt, ", expected ¡hello world")
+	}
+	if e := events[6]; e.Type != text.Delete || e.Index != 1 || e.Text != "x" {
+		t.Error("Event of the delete is ", e, ", expected the delete of x at 1")
+	}
+	if e := events[len(events)-1]; e.Type != text.Insert || e.Index != 0 || e.Text != "¡" {
+		t.Error("Event of the last insert is ", e, ", expected the insert of ¡ at 0")
+	}
+
+	if err := txt.InsertAt(13, "!"); err == nil {
+		t.Error("Insert after the end of ", txt, " returned no error")
+	}
+	if err := txt.DeleteRange(10, 3); err == nil {
+		t.Error("Delete after the end of ", txt, " returned no error")
+	}
+	if txt.String() != "¡hello world" || len(events) != 14 {
+		t.Error("Text is ", txt, " after ", len(events), " events, expected ¡hello world after 14 events")
+	}
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/crdtcheck"
+	"library/packages/datatypes"
+	ecro "library/packages/datatypes/ecro"
+	"library/packages/replica"
+	"strconv"
+	"testing"
+)
+
+// random create, move or delete on the nodes under the root of a tree, moves may try to create cycles
+func treeOperation(state any, choice int) (string, any) {
+	st := state.(*datatypes.Tree)
+	nodes := st.Nodes()
+	parent := datatypes.RootNode
+	if n := choice / 3 % (len(nodes) + 1); n > 0 {
+		parent = nodes[n-1]
+	}
+	if len(nodes) == 0 || choice%3 == 0 {
+		return "Create", datatypes.TreeOpValue{Parent: parent, Value: strconv.Itoa(choice)}
+	}
+
+	node := nodes[choice/5%len(nodes)]
+	if choice%3 == 1 {
+		return "Move", datatypes.TreeOpValue{Node: node, Parent: parent}
+	}
+	return "Delete", datatypes.TreeOpValue{Node: node}
+}
+
+func treeEqual(st1 any, st2 any) bool {
+	return st1.(*datatypes.Tree).Equal(st2.(*datatypes.Tree))
+}
+
+// number of nodes under the root or the trash, nodes in a cycle are not reachable from either
+func reachableNodes(st *datatypes.Tree) int {
+	reachable := 0
+	var walk func(parent datatypes.NodeID)
+	walk = func(parent datatypes.NodeID) {
+		for _, child := range st.Children(parent) {
+			reachable++
+			walk(child)
+		}
+	}
+	walk(datatypes.RootNode)
+	walk(datatypes.TrashNode)
+	return reachable
+}
+
+func TestTree(t *testing.T) {
+
+	//Check that all replicas have the same tree and that every node is under the root or the trash
+	check := func(replicas []*replica.Replica, trace []communication.Operation) bool {
+		created := 0
+		for _, op := range trace {
+			if op.Type == "Create" {
+				created++
+			}
+		}
+		stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
+		for i := range replicas {
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			if !treeEqual(st, stt) {
+				t.Error("Replica ", i, ": ", st.(*datatypes.Tree).Nodes(), " differs from replica 0: ", stt.(*datatypes.Tree).Nodes())
+				return false
+			}
+			if n := reachableNodes(st.(*datatypes.Tree)); n != created {
+				t.Error("Replica ", i, " reaches ", n, " of ", created, " nodes from the root and the trash")
+				return false
+			}
+		}
+		return true
+	}
+	checkReplicas(t, ecro.NewTreeReplica, prepareFromRead(treeOperation), check)
+}
+
+func TestLawsTree(t *testing.T) {
+	gen := crdtcheck.Generator{State: datatypes.NewTree(), Operation: treeOperation, Equal: treeEqual}
+
+	if err := crdtcheck.CheckEcro(ecro.Tree{Id: "0"}, gen, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInterleavingsTree(t *testing.T) {
+	newEngine := func() replica.CrdtI {
+		return crdt.NewEcroCRDT("0", datatypes.NewTree(), ecro.Tree{Id: "0"})
+	}
+	checkInterleavings(t, newEngine, datatypes.NewTree(), ecro.Tree{Id: "0"}.Apply, treeOperation, treeEqual, true)
+
+	// n is created and moved under a while another replica moves one of its nodes, the move of n must stay after the create of n
+	// when the concurrent move sorts it again, the hash of the move of n orders it before the create
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	a := datatypes.NewNodeID(version(map[string]uint64{"0": 8}), "0")
+	n := datatypes.NewNodeID(version(map[string]uint64{"0": 9}), "0")
+	ops := []communication.Operation{
+		{Type: "Create", Value: datatypes.TreeOpValue{Parent: datatypes.RootNode, Value: "a"}, Version: version(map[string]uint64{"0": 8}), OriginID: "0"},
+		{Type: "Create", Value: datatypes.TreeOpValue{Parent: datatypes.RootNode, Value: "n"}, Version: version(map[string]uint64{"0": 9}), OriginID: "0"},
+		{Type: "Move", Value: datatypes.TreeOpValue{Node: n, Parent: a}, Version: version(map[string]uint64{"0": 10}), OriginID: "0"},
+		{Type: "Move", Value: datatypes.TreeOpValue{Node: datatypes.NewNodeID(version(map[string]uint64{"1": 10}), "1"), Parent: datatypes.RootNode}, Version: version(map[string]uint64{"1": 11}), OriginID: "1"},
+	}
+	if err := crdtcheck.CheckInterleavings(newEngine, ops, treeEqual, true); err != nil {
+		t.Error(err)
+	}
+}
+
+// a moves under b and b moves under a concurrently, the move ordered last would create a cycle and is undone
+func TestTreeConcurrentMoves(t *testing.T) {
+	version := func(m map[string]uint64) communication.VClock {
+		return communication.NewVClockFromMap(m)
+	}
+	a := datatypes.NewNodeID(version(map[string]uint64{"0": 1}), "0")
+	b := datatypes.NewNodeID(version(map[string]uint64{"0": 2}), "0")
+	ops := []communication.Operation{
+		{Type: "Create", Value: datatypes.TreeOpValue{Parent: datatypes.RootNode, Value: "a"}, Version: version(map[string]uint64{"0": 1}), OriginID: "0"},
+		{Type: "Create", Value: datatypes.TreeOpValue{Parent: datatypes.RootNode, Value: "b"}, Version: version(map[string]uint64{"0": 2}), OriginID: "0"},
+		{Type: "Move", Value: datatypes.TreeOpValue{Node: a, Parent: b}, Version: version(map[string]uint64{"0": 2, "1": 1}), OriginID: "1"},
+		{Type: "Move", Value: datatypes.TreeOpValue{Node: b, Parent: a}, Version: version(map[string]uint64{"0": 2, "2": 1}), OriginID: "2"},
+	}
+
+	for _, order := range [][]int{{0, 1, 2, 3}, {0, 1, 3, 2}} {
+		engine := crdt.NewEcroCRDT("0", datatypes.NewTree(), ecro.Tree{Id: "0"})
+		for _, i := range order {
+			engine.Effect(ops[i])
+		}
+		st, _ := engine.Read(replica.Optimistic)
+		tree := st.(*datatypes.Tree)
+		parentA, _ := tree.Parent(a)
+		parentB, _ := tree.Parent(b)
+		if parentA != b || parentB != datatypes.RootNode {
+			t.Error("Delivering ", order, " puts a under ", parentA, " and b under ", parentB, ", expected a under b and b under the root")
+		}
+	}
+}
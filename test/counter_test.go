@@ -67,18 +67,18 @@ func TestCounter(t *testing.T) {
 
 		//Check that all replicas have the same state
 		for i := 1; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
-			stt, _ := replicas[0].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
 			if !reflect.DeepEqual(st, stt) {
 				for i := 0; i < numReplicas; i++ {
-					st, _ := replicas[i].Crdt.Query()
+					st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 					t.Error("Replica ", i, ": ", st)
 				}
 				return false
 			}
 		}
 		for i := 0; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 			t.Log("Replica ", i, ": ", st)
 		}
 		return true
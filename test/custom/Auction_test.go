@@ -53,7 +53,7 @@ func TestAuction(t *testing.T) {
 					case 2:
 						OPType = "PlaceBid"
 
-						q, _ := r.Crdt.Query()
+						q, _ := r.Crdt.Read(replica.Optimistic)
 						users := q.(custom.AuctionState).Users.ToSlice()
 						if len(users) == 0 { //do not generate place bids when there are no users
 							j--
@@ -93,18 +93,18 @@ func TestAuction(t *testing.T) {
 
 		//Check that all replicas have the same state
 		for i := 1; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
-			stt, _ := replicas[0].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
 			if !custom.CompareAuctionStates(st.(custom.AuctionState), stt.(custom.AuctionState)) {
 				for i := 0; i < numReplicas; i++ {
-					st, _ := replicas[i].Crdt.Query()
+					st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 					t.Error("Replica ", i, ": ", st)
 				}
 				return false
 			}
 		}
 		for i := 0; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 			t.Log("Replica ", i, ": ", st)
 		}
 		return true
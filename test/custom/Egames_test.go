@@ -58,7 +58,7 @@ func TestEGames(t *testing.T) {
 						r.Prepare(OPType, OPValue)
 					case 4:
 						OPType = "Enroll"
-						q, _ := r.Crdt.Query()
+						q, _ := r.Crdt.Read(replica.Optimistic)
 						players := q.(custom.EgameState).Players.ToSlice()
 						tournaments := q.(custom.EgameState).Tournaments.ToSlice()
 						if len(players) == 0 || len(tournaments) == 0 { //do not generate place bids when there are no users
@@ -97,18 +97,18 @@ func TestEGames(t *testing.T) {
 
 		//Check that all replicas have the same state
 		for i := 1; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
-			stt, _ := replicas[0].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
 			if !custom.CompareEgameStates(st.(custom.EgameState), stt.(custom.EgameState)) {
 				for i := 0; i < numReplicas; i++ {
-					st, _ := replicas[i].Crdt.Query()
+					st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 					t.Error("Replica ", i, ": ", st)
 				}
 				return false
 			}
 		}
 		for i := 0; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 			t.Log("Replica ", i, ": ", st)
 		}
 		return true
@@ -0,0 +1,43 @@
+package test
+
+import (
+	"library/packages/communication"
+	"library/packages/crdt"
+	"library/packages/datatypes/ecro/custom"
+	"library/packages/datatypes/persistent"
+	"testing"
+)
+
+// an enrollment concurrent with the removal of its player is ordered after the removal,
+// so the enrollment references a removed player and the debug mode reports it on the unstable and then on the stable state
+func TestInvariantsEgames(t *testing.T) {
+	c := crdt.NewEcroCRDT("0", custom.EgameState{Tournaments: persistent.NewSet[any](), Players: persistent.NewSet[any](), Enrolled: persistent.NewSet[custom.Enroll]()}, custom.Egame{})
+	c.Debug = crdt.NewDebug()
+
+	enroll := communication.Operation{Type: "Enroll", Value: custom.Enroll{Player: 1, Tournament: 2}, Version: communication.NewVClockFromMap(map[string]uint64{"0": 2, "1": 1}), OriginID: "1"}
+	ops := []communication.Operation{
+		{Type: "AddPlayer", Value: 1, Version: communication.NewVClockFromMap(map[string]uint64{"0": 1}), OriginID: "0"},
+		{Type: "AddTournament", Value: 2, Version: communication.NewVClockFromMap(map[string]uint64{"0": 2}), OriginID: "0"},
+		{Type: "RemPlayer", Value: 1, Version: communication.NewVClockFromMap(map[string]uint64{"0": 3}), OriginID: "0"},
+		enroll,
+	}
+
+	for _, op := range ops {
+		c.Effect(op)
+	}
+	violations := c.Debug.Violations()
+	if len(violations) != 1 || violations[0].Stable || violations[0].Stabilize || !violations[0].Operation.Equals(enroll) {
+		t.Fatal("Expected a violation in the unstable state after the enrollment, got ", violations)
+	}
+
+	for _, op := range ops {
+		c.Stabilize(op)
+	}
+	stable := false
+	for _, v := range c.Debug.Violations() {
+		stable = stable || v.Stable && v.Stabilize && v.Operation.Equals(enroll)
+	}
+	if !stable {
+		t.Error("Expected a violation in the stable state after stabilizing the enrollment, got ", c.Debug.Violations())
+	}
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"library/packages/crdtcheck"
+	"library/packages/datatypes/ecro/custom"
+	"library/packages/datatypes/persistent"
+	"testing"
+)
+
+func TestLawsAuction(t *testing.T) {
+	gen := crdtcheck.Generator{
+		State: custom.AuctionState{Users: persistent.NewSet[any](), Bids: persistent.NewSet[custom.Bid](), MaxBid: 0},
+		Operation: func(state any, choice int) (string, any) {
+			switch choice % 4 {
+			case 0:
+				return "RemUser", choice / 4 % 5
+			case 1:
+				//do not generate bids when there are no users
+				users := state.(custom.AuctionState).Users.ToSlice()
+				if len(users) > 0 {
+					return "PlaceBid", custom.Bid{User: users[choice/4%len(users)].(int), Ammount: choice / 4 % 100}
+				}
+			case 2:
+				return "Close", nil
+			}
+			return "AddUser", choice / 4 % 5
+		},
+		Equal: func(st1 any, st2 any) bool {
+			return custom.CompareAuctionStates(st1.(custom.AuctionState), st2.(custom.AuctionState)) &&
+				st1.(custom.AuctionState).MaxBid == st2.(custom.AuctionState).MaxBid
+		},
+	}
+
+	if err := crdtcheck.CheckEcro(custom.Auction{}, gen, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLawsEgames(t *testing.T) {
+	gen := crdtcheck.Generator{
+		State: custom.EgameState{Tournaments: persistent.NewSet[any](), Players: persistent.NewSet[any](), Enrolled: persistent.NewSet[custom.Enroll]()},
+		Operation: func(state any, choice int) (string, any) {
+			switch choice % 5 {
+			case 0:
+				return "AddTournament", choice / 5 % 5
+			case 1:
+				return "RemPlayer", choice / 5 % 5
+			case 2:
+				return "RemTournament", choice / 5 % 5
+			case 3:
+				//do not generate enrollments when there are no players or tournaments
+				players := state.(custom.EgameState).Players.ToSlice()
+				tournaments := state.(custom.EgameState).Tournaments.ToSlice()
+				if len(players) > 0 && len(tournaments) > 0 {
+					return "Enroll", custom.Enroll{Player: players[choice/5%len(players)].(int), Tournament: tournaments[choice/25%len(tournaments)].(int)}
+				}
+			}
+			return "AddPlayer", choice / 5 % 5
+		},
+		Equal: func(st1 any, st2 any) bool {
+			return custom.CompareEgameStates(st1.(custom.EgameState), st2.(custom.EgameState))
+		},
+	}
+
+	if err := crdtcheck.CheckEcro(custom.Egame{}, gen, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"library/packages/datatypes/persistent"
 	datatypes "library/packages/datatypes/semidirect"
 	"library/packages/replica"
 	"log"
@@ -77,18 +78,18 @@ func TestAddWinsSEMI2(t *testing.T) {
 
 		//Check that all replicas have the same state
 		for i := 1; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
-			stt, _ := replicas[0].Crdt.Query()
-			if !reflect.DeepEqual(st, stt) {
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
+			if !st.(*persistent.Set[any]).Equal(stt.(*persistent.Set[any])) {
 				for i := 0; i < numReplicas; i++ {
-					st, _ := replicas[i].Crdt.Query()
+					st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 					t.Error("Replica ", i, ": ", st)
 				}
 				return false
 			}
 		}
 		for i := 0; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 			t.Log("Replica ", i, ": ", st)
 		}
 		return true
@@ -2,6 +2,7 @@ package test
 
 import (
 	datatypes "library/packages/datatypes/ecro"
+	"library/packages/datatypes/persistent"
 	"library/packages/replica"
 	"log"
 	"math/rand"
@@ -11,8 +12,6 @@ import (
 	"testing"
 	"testing/quick"
 	"time"
-
-	mapset "github.com/deckarep/golang-set/v2"
 )
 
 func TestAddWinsECRO(t *testing.T) {
@@ -82,18 +81,18 @@ func TestAddWinsECRO(t *testing.T) {
 
 		//Check that all replicas have the same state
 		for i := 1; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
-			stt, _ := replicas[0].Crdt.Query()
-			if st.(mapset.Set[any]).Equal(stt.(mapset.Set[any])) == false {
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
+			if st.(*persistent.Set[any]).Equal(stt.(*persistent.Set[any])) == false {
 				for i := 0; i < numReplicas; i++ {
-					st, _ := replicas[i].Crdt.Query()
+					st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 					t.Error("Replica ", i, ": ", st)
 				}
 				return false
 			}
 		}
 		for i := 0; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
 			t.Log("Replica ", i, ": ", st)
 		}
 		return true
@@ -0,0 +1,69 @@
+package test
+
+import (
+	datatypes "library/packages/datatypes/commutative"
+	"library/packages/replica"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// runs numOperations counter increments on each replica with the given delay and waits for all of them to be delivered
+func runDelayedCounter(numReplicas int, numOperations int, delay int) {
+	// Initialize channels
+	channels := map[string]chan interface{}{}
+	for i := 0; i < numReplicas; i++ {
+		channels[strconv.Itoa(i)] = make(chan interface{})
+	}
+
+	// Initialize replicas
+	replicas := make([]*replica.Replica, numReplicas)
+	for i := 0; i < numReplicas; i++ {
+		replicas[i] = datatypes.NewCounterReplica(strconv.Itoa(i), channels, delay)
+	}
+
+	// Start a goroutine for each replica
+	var wg sync.WaitGroup
+	for i := range replicas {
+		wg.Add(1)
+		go func(r *replica.Replica) {
+			defer wg.Done()
+			for j := 0; j < numOperations; j++ {
+				r.Prepare("Add", 1)
+			}
+		}(replicas[i])
+	}
+
+	// Wait for all goroutines to finish
+	wg.Wait()
+
+	// Wait for all replicas to receive all messages
+	for {
+		flag := 0
+		for i := 0; i < numReplicas; i++ {
+			if replicas[i].Crdt.NumOps() == uint64(numReplicas*numOperations) {
+				flag += 1
+			}
+		}
+		if flag == numReplicas {
+			break
+		}
+	}
+}
+
+// delivery queue without and with reordering from the delay simulator
+// a delay equal to the number of remote messages holds every message back and delivers them in random order,
+// a smaller delay can leave the last messages held, since held messages are only all handed over once that many are received
+func BenchmarkMiddlewareDQ(b *testing.B) {
+	numReplicas := 3
+	numOperations := 200
+	remoteMessages := (numReplicas - 1) * numOperations
+
+	for _, delay := range []int{0, remoteMessages} {
+		b.Run("delay="+strconv.Itoa(delay), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				runDelayedCounter(numReplicas, numOperations, delay)
+			}
+		})
+	}
+}
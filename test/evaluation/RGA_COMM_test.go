@@ -47,7 +47,7 @@ func TestRGACOMM(t *testing.T) {
 
 				for j := 0; j < operations; j++ {
 					//choose a predecessor or a vertex to remove randomly from query
-					rgaState, _ := r.Crdt.Query()
+					rgaState, _ := r.Crdt.Read(replica.Optimistic)
 					v := rgaState.([]datatypes.Vertex)[rand.Intn(len(rgaState.([]datatypes.Vertex)))]
 
 					//choose random leter to add
@@ -98,12 +98,12 @@ func TestRGACOMM(t *testing.T) {
 
 		//Check that all replicas have the same state
 		for i := 1; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
-			stt, _ := replicas[0].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
 			if !datatypes.RGAEqual(st.([]datatypes.Vertex), stt.([]datatypes.Vertex)) {
 				for i := 0; i < numReplicas; i++ {
 					t.Error("Replica ", i)
-					q, _ := replicas[i].Crdt.Query()
+					q, _ := replicas[i].Crdt.Read(replica.Optimistic)
 					for j := 0; j < len(q.([]datatypes.Vertex)); j++ {
 						log.Println(q.([]datatypes.Vertex)[j])
 					}
@@ -142,11 +142,12 @@ func TestRGACOMM(t *testing.T) {
 }
 
 func generateRandomVertexCOMM(r replica.Replica) datatypes.Vertex {
-	rgaState, rgaDeletedState := r.Crdt.Query()
+	rgaState, _ := r.Crdt.Read(replica.Optimistic)
+	rgaDeletedState := []communication.Operation{} //the engine has no pending operations
 
 	v := datatypes.Vertex{}
-	if len(rgaDeletedState.([]communication.Operation)) != 0 {
-		v = rgaDeletedState.([]communication.Operation)[rand.Intn(len(rgaDeletedState.([]communication.Operation)))].Value.(datatypes.RGAOpValue).V
+	if len(rgaDeletedState) != 0 {
+		v = rgaDeletedState[rand.Intn(len(rgaDeletedState))].Value.(datatypes.RGAOpValue).V
 	} else {
 		v = rgaState.([]datatypes.Vertex)[rand.Intn(len(rgaState.([]datatypes.Vertex)))]
 	}
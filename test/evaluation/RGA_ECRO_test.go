@@ -2,6 +2,7 @@ package test
 
 import (
 	"library/packages/communication"
+	"library/packages/crdt"
 	ecro "library/packages/datatypes/ecro"
 	"library/packages/datatypes"
 	"library/packages/replica"
@@ -47,7 +48,7 @@ func TestRGAECRO(t *testing.T) {
 
 				for j := 0; j < operations; j++ {
 					//choose a predecessor or a vertex to remove randomly from query
-					rgaState, _ := r.Crdt.Query()
+					rgaState, _ := r.Crdt.Read(replica.Optimistic)
 					v := rgaState.([]datatypes.Vertex)[rand.Intn(len(rgaState.([]datatypes.Vertex)))]
 
 					//choose random leter to add
@@ -100,12 +101,12 @@ func TestRGAECRO(t *testing.T) {
 
 		//Check that all replicas have the same state
 		for i := 1; i < numReplicas; i++ {
-			st, _ := replicas[i].Crdt.Query()
-			stt, _ := replicas[0].Crdt.Query()
+			st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+			stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
 			if !datatypes.RGAEqual(st.([]datatypes.Vertex), stt.([]datatypes.Vertex)) {
 				for i := 0; i < numReplicas; i++ {
 					t.Error("Replica ", i)
-					q, _ := replicas[i].Crdt.Query()
+					q, _ := replicas[i].Crdt.Read(replica.Optimistic)
 					for j := 0; j < len(q.([]datatypes.Vertex)); j++ {
 						log.Println(q.([]datatypes.Vertex)[j])
 					}
@@ -144,11 +145,12 @@ func TestRGAECRO(t *testing.T) {
 }
 
 func generateRandomVertexECRO(r replica.Replica) datatypes.Vertex {
-	rgaState, rgaDeletedState := r.Crdt.Query()
+	rgaState, _ := r.Crdt.Read(replica.Optimistic)
+	rgaDeletedState := []communication.Operation{} //the engine has no pending operations
 
 	v := datatypes.Vertex{}
-	if len(rgaDeletedState.([]communication.Operation)) != 0 {
-		v = rgaDeletedState.([]communication.Operation)[rand.Intn(len(rgaDeletedState.([]communication.Operation)))].Value.(datatypes.RGAOpValue).V
+	if len(rgaDeletedState) != 0 {
+		v = rgaDeletedState[rand.Intn(len(rgaDeletedState))].Value.(datatypes.RGAOpValue).V
 	} else {
 		v = rgaState.([]datatypes.Vertex)[rand.Intn(len(rgaState.([]datatypes.Vertex)))]
 	}
@@ -167,3 +169,92 @@ func generateRandomVertexECRO(r replica.Replica) datatypes.Vertex {
 
 	return result.Item.(datatypes.Vertex)
 }
+
+// runs the TestRGAECRO workload with numOperations random inserts and removes on each replica,
+// holding back every remote message so operations arrive out of arbitration order
+func runRGAECRO(numReplicas int, numOperations int, checkpointInterval int) {
+	// Initialize channels
+	channels := map[string]chan interface{}{}
+	for i := 0; i < numReplicas; i++ {
+		channels[strconv.Itoa(i)] = make(chan interface{})
+	}
+
+	// Initialize replicas
+	replicas := make([]*replica.Replica, numReplicas)
+	for i := 0; i < numReplicas; i++ {
+		replicas[i] = ecro.NewRGAReplica(strconv.Itoa(i), channels, (numReplicas-1)*numOperations)
+		replicas[i].Crdt.(*crdt.EcroCRDT).SetCheckpointInterval(checkpointInterval)
+	}
+
+	// Start a goroutine for each replica
+	var wg sync.WaitGroup
+	for i := range replicas {
+		wg.Add(1)
+		go func(r *replica.Replica) {
+			defer wg.Done()
+			for j := 0; j < numOperations; j++ {
+				rgaState, _ := r.Crdt.Read(replica.Optimistic)
+				v := rgaState.([]datatypes.Vertex)[rand.Intn(len(rgaState.([]datatypes.Vertex)))]
+
+				OPType := "Add"
+				if rand.Intn(2) == 0 && v.Value != "" {
+					OPType = "Rem"
+				}
+
+				r.Prepare(OPType, datatypes.RGAOpValue{Value: lettersECRO[rand.Intn(len(lettersECRO))], V: v})
+			}
+		}(replicas[i])
+	}
+
+	// Wait for all goroutines to finish
+	wg.Wait()
+
+	// Wait for all replicas to receive all messages
+	for {
+		flag := 0
+		for i := 0; i < numReplicas; i++ {
+			if replicas[i].Crdt.NumOps() == uint64(numReplicas*numOperations) {
+				flag += 1
+			}
+		}
+		if flag == numReplicas {
+			break
+		}
+	}
+}
+
+// replay cost of out of order operations with increasing checkpoint intervals, 0 replays always from the stable state
+func BenchmarkRGAECRO(b *testing.B) {
+	for _, interval := range []int{0, 8, 32, 128} {
+		b.Run("checkpoint="+strconv.Itoa(interval), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				runRGAECRO(3, 30, interval)
+			}
+		})
+	}
+}
+
+// replay of the RGA operations after an operation is inserted near the end of a long order
+func BenchmarkRGAECROReplay(b *testing.B) {
+	numOperations := 2000
+	ops := make([]communication.Operation, numOperations)
+	prev := datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"}
+	for i := 0; i < numOperations; i++ {
+		version := communication.NewVClockFromMap(map[string]uint64{"0": uint64(i + 1)})
+		ops[i] = communication.Operation{Type: "Add", Value: datatypes.RGAOpValue{Value: "a", V: prev}, Version: version, OriginID: "0"}
+		prev = datatypes.Vertex{Timestamp: version, Value: "a", OriginID: "0"}
+	}
+
+	initial := datatypes.NewSequence(datatypes.Vertex{Timestamp: communication.NewVClockFromMap(map[string]uint64{}), Value: "", OriginID: "0"})
+	for _, interval := range []int{0, 8, 32, 128} {
+		b.Run("checkpoint="+strconv.Itoa(interval), func(b *testing.B) {
+			checkpoints := crdt.NewCheckpoints(interval, initial)
+			checkpoints.Replay(ecro.RGA{}, ops, 0)
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				checkpoints.Replay(ecro.RGA{}, ops, numOperations-10)
+			}
+		})
+	}
+}
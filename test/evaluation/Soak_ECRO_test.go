@@ -0,0 +1,112 @@
+package test
+
+import (
+	"library/packages/crdt"
+	"library/packages/datatypes/ecro/custom"
+	"library/packages/replica"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// long running social network on ECRO replicas, its conflicting operations make cycles that are broken by removing edges
+// operations are generated in rounds, after a round is delivered the operations of the previous rounds become stable,
+// so the graph of unstable operations, its removed edges and the memory used stay flat
+func TestSoakECRO(t *testing.T) {
+	numReplicas := 3
+	numRounds := 200
+	roundOperations := 10 //operations of each replica in a round
+
+	// Initialize channels
+	channels := map[string]chan interface{}{}
+	for i := 0; i < numReplicas; i++ {
+		channels[strconv.Itoa(i)] = make(chan interface{})
+	}
+
+	// Initialize replicas
+	replicas := make([]*replica.Replica, numReplicas)
+	for i := 0; i < numReplicas; i++ {
+		replicas[i] = custom.NewSocialReplica(strconv.Itoa(i), channels, 0)
+	}
+
+	types := []string{"accept", "breakup", "request", "reject"}
+	var warmHeap uint64
+
+	for round := 1; round <= numRounds; round++ {
+		// Start a goroutine for each replica
+		var wg sync.WaitGroup
+		for i := range replicas {
+			wg.Add(1)
+			go func(r *replica.Replica) {
+				defer wg.Done()
+				for j := 0; j < roundOperations; j++ {
+					r.Prepare(types[rand.Intn(len(types))], custom.SocialOpValue{From: rand.Intn(5), To: rand.Intn(5)})
+				}
+			}(replicas[i])
+		}
+		wg.Wait()
+
+		// Wait for all replicas to receive all messages and stabilize the operations of the previous rounds
+		delivered := uint64(round * roundOperations * numReplicas)
+		stable := uint64((round - 1) * roundOperations * numReplicas)
+		deadline := time.Now().Add(30 * time.Second)
+		for {
+			flag := 0
+			for i := 0; i < numReplicas; i++ {
+				if replicas[i].Crdt.NumOps() == delivered && replicas[i].Crdt.NumSOps() >= stable {
+					flag += 1
+				}
+			}
+			if flag == numReplicas {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("Round ", round, " was not delivered and stabilized")
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		for i := 0; i < numReplicas; i++ {
+			c := replicas[i].Crdt.(*crdt.EcroCRDT)
+			c.StabilizeLock.Lock()
+			vertices, sorted, removed := c.Unstable_operations.Len(), len(c.Sorted_ops), c.Unstable_operations.RemovedEdges()
+
+			//removed edges are forgotten once both endpoints are stable
+			for _, edge := range removed {
+				_, ok1 := c.Unstable_operations.Vertex(edge.Source)
+				_, ok2 := c.Unstable_operations.Vertex(edge.Target)
+				if !ok1 && !ok2 {
+					t.Error("Replica ", i, " keeps removed edge ", edge, " between stable operations")
+				}
+			}
+			c.StabilizeLock.Unlock()
+
+			//only the operations of the last rounds are unstable
+			if vertices > 2*roundOperations*numReplicas || sorted != vertices {
+				t.Error("Round ", round, " replica ", i, " has ", vertices, " unstable operations and ", sorted, " sorted operations")
+			}
+		}
+
+		//memory after the first rounds is the reference
+		runtime.GC()
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		if round == numRounds/4 {
+			warmHeap = m.HeapAlloc
+		} else if round > numRounds/4 && m.HeapAlloc > 2*warmHeap {
+			t.Error("Round ", round, " uses ", m.HeapAlloc, " bytes of heap, after ", numRounds/4, " rounds it used ", warmHeap)
+		}
+	}
+
+	//Check that all replicas have the same state
+	for i := 1; i < numReplicas; i++ {
+		st, _ := replicas[i].Crdt.Read(replica.Optimistic)
+		stt, _ := replicas[0].Crdt.Read(replica.Optimistic)
+		if !custom.CompareSocialStates(st.(custom.SocialState), stt.(custom.SocialState)) {
+			t.Error("Replica ", i, " state ", st, " differs from replica 0 state ", stt)
+		}
+	}
+}
@@ -27,16 +27,6 @@ func InitMin(ids []string) map[string]string {
 	return vc
 }
 
-// MapValueExists returns true if the given value exists in the values of the map.
-func MapValueExists(m map[string]string, value string) bool {
-	for _, v := range m {
-		if v == value || v == "" {
-			return true
-		}
-	}
-	return false
-}
-
 // check if array contains operation
 func Contains(operations []communication.Operation, op communication.Operation) bool {
 	for _, o := range operations {